@@ -0,0 +1,82 @@
+package account
+
+import (
+	"encoding/json"
+	"github.com/cecobask/ocdtracker-api/internal/api"
+	"github.com/cecobask/ocdtracker-api/internal/api/middleware"
+	"github.com/cecobask/ocdtracker-api/pkg/entity"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/render"
+	"github.com/google/uuid"
+	"io"
+	"net/http"
+)
+
+func (h *handler) GetAllDevices(w http.ResponseWriter, r *http.Request) {
+	account, err := middleware.AccountFromContext(r.Context())
+	if err != nil {
+		api.InternalServerError(w, r, "invalid-account-ctx", err)
+		return
+	}
+	result, err := h.deviceRepo.GetAllDevices(r.Context(), account.ID)
+	if err != nil {
+		api.InternalServerError(w, r, "database-error", err)
+		return
+	}
+	render.JSON(w, r, result)
+}
+
+// RegisterDevice stores the push notification token of a device; registering a known token again updates it
+func (h *handler) RegisterDevice(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		api.BadRequestError(w, r, "invalid-request-body", err)
+		return
+	}
+	var requestBody entity.Device
+	err = json.Unmarshal(body, &requestBody)
+	if err != nil {
+		api.BadRequestError(w, r, "invalid-request-body", err)
+		return
+	}
+	if err = requestBody.Validate(); err != nil {
+		api.BadRequestError(w, r, "invalid-request-body", err)
+		return
+	}
+	account, err := middleware.AccountFromContext(r.Context())
+	if err != nil {
+		api.InternalServerError(w, r, "invalid-account-ctx", err)
+		return
+	}
+	err = h.deviceRepo.RegisterDevice(r.Context(), account.ID, &requestBody)
+	if err != nil {
+		api.InternalServerError(w, r, "database-error", err)
+		return
+	}
+	result, err := h.deviceRepo.GetDevice(r.Context(), account.ID, requestBody.ID)
+	if err != nil {
+		api.HandleRetrievalError(w, r, err)
+		return
+	}
+	render.Status(r, http.StatusCreated)
+	render.JSON(w, r, result)
+}
+
+func (h *handler) DeleteDevice(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		api.BadRequestError(w, r, "invalid-id", err)
+		return
+	}
+	account, err := middleware.AccountFromContext(r.Context())
+	if err != nil {
+		api.InternalServerError(w, r, "invalid-account-ctx", err)
+		return
+	}
+	err = h.deviceRepo.DeleteDevice(r.Context(), account.ID, id)
+	if err != nil {
+		api.InternalServerError(w, r, "database-error", err)
+		return
+	}
+	render.NoContent(w, r)
+}
@@ -0,0 +1,109 @@
+package account
+
+import (
+	"crypto/rand"
+	"encoding/base32"
+	"encoding/json"
+	"github.com/cecobask/ocdtracker-api/internal/api"
+	"github.com/cecobask/ocdtracker-api/internal/api/middleware"
+	"github.com/cecobask/ocdtracker-api/pkg/entity"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/render"
+	"github.com/google/uuid"
+	"io"
+	"net/http"
+)
+
+const (
+	invitationCodeBytes = 10 // encodes to 16 base32 characters
+)
+
+func (h *handler) GetAllGrants(w http.ResponseWriter, r *http.Request) {
+	account, err := middleware.AccountFromContext(r.Context())
+	if err != nil {
+		api.InternalServerError(w, r, "invalid-account-ctx", err)
+		return
+	}
+	result, err := h.grantRepo.GetAllGrants(r.Context(), account.ID)
+	if err != nil {
+		api.InternalServerError(w, r, "database-error", err)
+		return
+	}
+	render.JSON(w, r, result)
+}
+
+// CreateGrant invites a clinician to read the data of the account in the requested scopes. The response carries the
+// invitation code to pass on; an invitation with an email can only be accepted by the account with that email
+func (h *handler) CreateGrant(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		api.BadRequestError(w, r, "invalid-request-body", err)
+		return
+	}
+	var requestBody entity.Grant
+	err = json.Unmarshal(body, &requestBody)
+	if err != nil {
+		api.BadRequestError(w, r, "invalid-request-body", err)
+		return
+	}
+	if err = requestBody.Validate(); err != nil {
+		api.BadRequestError(w, r, "invalid-request-body", err)
+		return
+	}
+	account, err := middleware.AccountFromContext(r.Context())
+	if err != nil {
+		api.InternalServerError(w, r, "invalid-account-ctx", err)
+		return
+	}
+	code, err := newInvitationCode()
+	if err != nil {
+		api.InternalServerError(w, r, "invitation-code-error", err)
+		return
+	}
+	grant := entity.Grant{
+		Email:     requestBody.Email,
+		Code:      &code,
+		Scopes:    requestBody.Scopes.Normalized(),
+		ExpiresAt: requestBody.ExpiresAt,
+	}
+	err = h.grantRepo.CreateGrant(r.Context(), account.ID, &grant)
+	if err != nil {
+		api.InternalServerError(w, r, "database-error", err)
+		return
+	}
+	result, err := h.grantRepo.GetGrant(r.Context(), grant.ID)
+	if err != nil {
+		api.HandleRetrievalError(w, r, err)
+		return
+	}
+	render.Status(r, http.StatusCreated)
+	render.JSON(w, r, result)
+}
+
+// DeleteGrant revokes the access of a clinician, or withdraws a pending invitation
+func (h *handler) DeleteGrant(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		api.BadRequestError(w, r, "invalid-id", err)
+		return
+	}
+	account, err := middleware.AccountFromContext(r.Context())
+	if err != nil {
+		api.InternalServerError(w, r, "invalid-account-ctx", err)
+		return
+	}
+	err = h.grantRepo.DeleteGrant(r.Context(), account.ID, id)
+	if err != nil {
+		api.InternalServerError(w, r, "database-error", err)
+		return
+	}
+	render.NoContent(w, r)
+}
+
+func newInvitationCode() (string, error) {
+	code := make([]byte, invitationCodeBytes)
+	if _, err := rand.Read(code); err != nil {
+		return "", err
+	}
+	return base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString(code), nil
+}
@@ -3,27 +3,62 @@ package account
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	firebaseAuth "firebase.google.com/go/v4/auth"
+	"fmt"
 	"github.com/cecobask/ocdtracker-api/internal/api"
 	"github.com/cecobask/ocdtracker-api/internal/api/middleware"
-	"github.com/cecobask/ocdtracker-api/internal/db/postgres"
+	"github.com/cecobask/ocdtracker-api/internal/auth"
+	"github.com/cecobask/ocdtracker-api/internal/db"
+	"github.com/cecobask/ocdtracker-api/internal/export"
 	"github.com/cecobask/ocdtracker-api/pkg/entity"
+	"github.com/cecobask/ocdtracker-api/pkg/log"
 	"github.com/go-chi/render"
+	"go.uber.org/zap"
 	"io"
 	"net/http"
+	"strconv"
+)
+
+const (
+	exportFormatZip  = "zip"
+	exportFormatJSON = "json"
+)
+
+const (
+	maxRestoreBytes = 50 << 20
 )
 
 type handler struct {
 	ctx         context.Context
-	accountRepo *postgres.AccountRepository
-	authClient  *firebaseAuth.Client
+	accountRepo db.AccountRepository
+	deviceRepo  db.DeviceRepository
+	grantRepo   db.GrantRepository
+	exportRepos export.Repositories
+	store       db.Store
+	authClient  auth.Client
 }
 
-func NewHandler(ctx context.Context, accountRepo *postgres.AccountRepository, authClient *firebaseAuth.Client) *handler {
+func NewHandler(ctx context.Context, accountRepo db.AccountRepository, ocdLogRepo db.OCDLogRepository, tagRepo db.TagRepository,
+	exposureItemRepo db.ExposureItemRepository, exposureSessionRepo db.ExposureSessionRepository, questionnaireResponseRepo db.QuestionnaireResponseRepository,
+	deviceRepo db.DeviceRepository, grantRepo db.GrantRepository, store db.Store, authClient auth.Client) *handler {
 	return &handler{
 		ctx:         ctx,
 		accountRepo: accountRepo,
-		authClient:  authClient,
+		deviceRepo:  deviceRepo,
+		grantRepo:   grantRepo,
+		exportRepos: export.Repositories{
+			Accounts:               accountRepo,
+			OCDLogs:                ocdLogRepo,
+			Tags:                   tagRepo,
+			ExposureItems:          exposureItemRepo,
+			ExposureSessions:       exposureSessionRepo,
+			QuestionnaireResponses: questionnaireResponseRepo,
+			Devices:                deviceRepo,
+			Grants:                 grantRepo,
+		},
+		store:      store,
+		authClient: authClient,
 	}
 }
 
@@ -86,6 +121,79 @@ func (h *handler) GetAccount(w http.ResponseWriter, r *http.Request) {
 	render.JSON(w, r, result)
 }
 
+// ExportAccount returns every piece of data stored about the account as a zip archive or a single json document
+func (h *handler) ExportAccount(w http.ResponseWriter, r *http.Request) {
+	account, err := middleware.AccountFromContext(r.Context())
+	if err != nil {
+		api.InternalServerError(w, r, "invalid-account-ctx", err)
+		return
+	}
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		format = exportFormatZip
+	}
+	if format != exportFormatZip && format != exportFormatJSON {
+		api.BadRequestError(w, r, "invalid-format", fmt.Errorf("format must be one of %q, %q", exportFormatZip, exportFormatJSON))
+		return
+	}
+	accountExport, err := export.Build(r.Context(), h.exportRepos, account.ID)
+	if err != nil {
+		api.HandleRetrievalError(w, r, err)
+		return
+	}
+	filename := fmt.Sprintf("ocdtracker-export-%s.%s", accountExport.Manifest.GeneratedAt.Format("20060102T150405Z"), format)
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+	if format == exportFormatJSON {
+		render.JSON(w, r, accountExport)
+		return
+	}
+	w.Header().Set("Content-Type", "application/zip")
+	if err = export.WriteZip(w, accountExport); err != nil {
+		log.LoggerFromContext(r.Context()).Error("failed to write account export", zap.Error(err))
+	}
+}
+
+// RestoreAccount recreates the data and settings of a json export archive, optionally as a dry run
+func (h *handler) RestoreAccount(w http.ResponseWriter, r *http.Request) {
+	account, err := middleware.AccountFromContext(r.Context())
+	if err != nil {
+		api.InternalServerError(w, r, "invalid-account-ctx", err)
+		return
+	}
+	dryRun := false
+	if value := r.URL.Query().Get("dry_run"); value != "" {
+		dryRun, err = strconv.ParseBool(value)
+		if err != nil {
+			api.BadRequestError(w, r, "invalid-dry-run", err)
+			return
+		}
+	}
+	var accountExport entity.AccountExport
+	err = json.NewDecoder(http.MaxBytesReader(w, r.Body, maxRestoreBytes)).Decode(&accountExport)
+	if err != nil {
+		api.BadRequestError(w, r, "invalid-request-body", err)
+		return
+	}
+	data, err := export.Verify(&accountExport)
+	if err != nil {
+		api.BadRequestError(w, r, "invalid-export", err)
+		return
+	}
+	report, err := export.Restore(r.Context(), h.store, account.ID, data, dryRun)
+	switch {
+	case errors.Is(err, db.ErrorDuplicate):
+		api.ConflictError(w, r, "duplicate-record", err)
+		return
+	case err != nil:
+		api.InternalServerError(w, r, "database-error", err)
+		return
+	}
+	if !dryRun {
+		render.Status(r, http.StatusCreated)
+	}
+	render.JSON(w, r, report)
+}
+
 func (h *handler) DeleteAccount(w http.ResponseWriter, r *http.Request) {
 	account, err := middleware.AccountFromContext(r.Context())
 	if err != nil {
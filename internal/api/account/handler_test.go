@@ -0,0 +1,235 @@
+package account
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/json"
+	"github.com/cecobask/ocdtracker-api/internal/api/apitest"
+	"github.com/cecobask/ocdtracker-api/internal/api/middleware"
+	"github.com/cecobask/ocdtracker-api/internal/auth"
+	"github.com/cecobask/ocdtracker-api/internal/db/memory"
+	"github.com/cecobask/ocdtracker-api/pkg/entity"
+	"github.com/cecobask/ocdtracker-api/pkg/log"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newTestRouter(t *testing.T) (http.Handler, *entity.Account, *memory.OCDLogRepository) {
+	memoryDB := memory.NewDB()
+	account := apitest.CreateAccount(t, memory.NewAccountRepository(memoryDB), "patient")
+	return newTestRouterWithDB(memoryDB), account, memory.NewOCDLogRepository(memoryDB)
+}
+
+func newTestRouterWithDB(memoryDB *memory.DB) http.Handler {
+	return NewRouter(NewHandler(context.Background(), memory.NewAccountRepository(memoryDB), memory.NewOCDLogRepository(memoryDB), memory.NewTagRepository(memoryDB),
+		memory.NewExposureItemRepository(memoryDB), memory.NewExposureSessionRepository(memoryDB), memory.NewQuestionnaireResponseRepository(memoryDB),
+		memory.NewDeviceRepository(memoryDB), memory.NewGrantRepository(memoryDB), memory.NewStore(memoryDB), auth.NewStubClient()))
+}
+
+func TestUpdateGetAndDeleteAccount(t *testing.T) {
+	router, account, _ := newTestRouter(t)
+
+	apitest.ExpectStatus(t, apitest.Do(t, router, account, http.MethodPatch, "/me", `{"wake_time":"07:30","notification_interval":3}`), http.StatusNoContent)
+	apitest.ExpectStatus(t, apitest.Do(t, router, account, http.MethodPatch, "/me", `{"wake_time":"25:00"}`), http.StatusBadRequest)
+	stored := apitest.Decode[entity.Account](t, apitest.Do(t, router, account, http.MethodGet, "/me", ""), http.StatusOK)
+	if *stored.WakeTime != "07:30" || *stored.NotificationInterval != 3 || *stored.Email != *account.Email {
+		t.Fatalf("update was not applied: %+v", stored)
+	}
+
+	apitest.ExpectStatus(t, apitest.Do(t, router, account, http.MethodDelete, "/me", ""), http.StatusNoContent)
+	apitest.ExpectStatus(t, apitest.Do(t, router, account, http.MethodGet, "/me", ""), http.StatusNotFound)
+}
+
+func TestGetReminders(t *testing.T) {
+	router, account, _ := newTestRouter(t)
+	apitest.ExpectStatus(t, apitest.Do(t, router, account, http.MethodPatch, "/me", `{"wake_time":"22:00","sleep_time":"01:00","notification_interval":2}`), http.StatusNoContent)
+	schedule := apitest.Decode[entity.ReminderSchedule](t, apitest.Do(t, router, account, http.MethodGet, "/me/reminders?date=2024-03-10", ""), http.StatusOK)
+	midnight := time.Date(2024, time.March, 11, 0, 0, 0, 0, time.UTC)
+	if schedule.Date != "2024-03-10" || schedule.NotificationInterval != 2 || len(schedule.Reminders) != 1 || !schedule.Reminders[0].Equal(midnight) {
+		t.Fatalf("expected the reminders of the awake period starting on the date, got %+v", schedule)
+	}
+	apitest.ExpectStatus(t, apitest.Do(t, router, account, http.MethodGet, "/me/reminders?date=10-03-2024", ""), http.StatusBadRequest)
+
+	apitest.ExpectStatus(t, apitest.Do(t, router, account, http.MethodPatch, "/me", `{"time_zone":"Pacific/Kiritimati"}`), http.StatusNoContent)
+	schedule = apitest.Decode[entity.ReminderSchedule](t, apitest.Do(t, router, account, http.MethodGet, "/me/reminders", ""), http.StatusOK)
+	kiritimati, err := time.LoadLocation("Pacific/Kiritimati")
+	if err != nil {
+		t.Skipf("time zone database unavailable: %v", err)
+	}
+	if today := time.Now().In(kiritimati).Format("2006-01-02"); schedule.Date != today {
+		t.Fatalf("expected the reminders of today in the time zone of the account (%s), got %s", today, schedule.Date)
+	}
+	apitest.ExpectStatus(t, apitest.Do(t, router, account, http.MethodPatch, "/me", `{"time_zone":"Mars/Olympus"}`), http.StatusBadRequest)
+}
+
+func TestExportAccount(t *testing.T) {
+	router, account, ocdLogRepo := newTestRouter(t)
+	anxietyLevel := 4
+	if err := ocdLogRepo.CreateLog(context.Background(), account.ID, &entity.OCDLog{AnxietyLevel: &anxietyLevel}); err != nil {
+		t.Fatalf("failed to create log: %v", err)
+	}
+	apitest.ExpectStatus(t, apitest.Do(t, router, account, http.MethodPost, "/me/devices", `{"token":"secret-token","platform":"ios"}`), http.StatusCreated)
+	apitest.ExpectStatus(t, apitest.Do(t, router, account, http.MethodPost, "/me/grants", `{"scopes":["logs"]}`), http.StatusCreated)
+	accountExport := apitest.Decode[entity.AccountExport](t, apitest.Do(t, router, account, http.MethodGet, "/me/export?format=json", ""), http.StatusOK)
+	if accountExport.Manifest.SchemaVersion != entity.ExportSchemaVersion || accountExport.Manifest.AccountID != account.ID || accountExport.Manifest.Counts["ocdlogs"] != 1 {
+		t.Fatalf("unexpected manifest %+v", accountExport.Manifest)
+	}
+	checksum, err := entity.ExportChecksum(accountExport.Data)
+	if err != nil || checksum != accountExport.Manifest.Checksum {
+		t.Fatalf("expected the checksum to match the data, got %s (%v)", checksum, err)
+	}
+	var data entity.AccountExportData
+	if err = json.Unmarshal(accountExport.Data, &data); err != nil {
+		t.Fatalf("failed to decode export data: %v", err)
+	}
+	if data.Account.ID != account.ID || len(data.OCDLogs) != 1 || *data.OCDLogs[0].AnxietyLevel != anxietyLevel {
+		t.Fatalf("unexpected export data %+v", data)
+	}
+	if len(data.Devices) != 1 || data.Devices[0].Token != nil || *data.Devices[0].Platform != entity.DevicePlatformIOS {
+		t.Fatalf("expected the device to be exported without its token, got %+v", data.Devices)
+	}
+	if len(data.Grants) != 1 || data.Grants[0].Code != nil || accountExport.Manifest.Counts["grants"] != 1 {
+		t.Fatalf("expected the grant to be exported without its invitation code, got %+v", data.Grants)
+	}
+
+	recorder := apitest.Do(t, router, account, http.MethodGet, "/me/export", "")
+	apitest.ExpectStatus(t, recorder, http.StatusOK)
+	if recorder.Header().Get("Content-Type") != "application/zip" || !strings.Contains(recorder.Header().Get("Content-Disposition"), ".zip") {
+		t.Fatalf("expected a zip attachment, got %v", recorder.Header())
+	}
+	archive, err := zip.NewReader(bytes.NewReader(recorder.Body.Bytes()), int64(recorder.Body.Len()))
+	if err != nil {
+		t.Fatalf("failed to open zip: %v", err)
+	}
+	if len(archive.File) != 2 || archive.File[0].Name != "manifest.json" || archive.File[1].Name != "data.json" {
+		t.Fatalf("unexpected zip entries %+v", archive.File)
+	}
+	apitest.ExpectStatus(t, apitest.Do(t, router, account, http.MethodGet, "/me/export?format=xml", ""), http.StatusBadRequest)
+}
+
+func TestRestoreAccount(t *testing.T) {
+	router, account, ocdLogRepo := newTestRouter(t)
+	apitest.ExpectStatus(t, apitest.Do(t, router, account, http.MethodPatch, "/me", `{"sleep_time":"22:15"}`), http.StatusNoContent)
+	anxietyLevel := 6
+	if err := ocdLogRepo.CreateLog(context.Background(), account.ID, &entity.OCDLog{AnxietyLevel: &anxietyLevel}); err != nil {
+		t.Fatalf("failed to create log: %v", err)
+	}
+	archive := apitest.Do(t, router, account, http.MethodGet, "/me/export?format=json", "").Body.String()
+
+	apitest.ExpectStatus(t, apitest.Do(t, router, account, http.MethodPost, "/me/restore", archive), http.StatusConflict)
+	if err := ocdLogRepo.DeleteAllLogs(context.Background(), account.ID); err != nil {
+		t.Fatalf("failed to delete logs: %v", err)
+	}
+	if _, err := ocdLogRepo.PurgeTrashedLogs(context.Background(), time.Now().Add(time.Hour)); err != nil {
+		t.Fatalf("failed to purge logs: %v", err)
+	}
+	apitest.ExpectStatus(t, apitest.Do(t, router, account, http.MethodPatch, "/me", `{"sleep_time":"23:30"}`), http.StatusNoContent)
+
+	report := apitest.Decode[entity.AccountRestoreReport](t, apitest.Do(t, router, account, http.MethodPost, "/me/restore?dry_run=true", archive), http.StatusOK)
+	if !report.DryRun || report.Counts["ocdlogs"] != 1 || *report.Settings.SleepTime != "22:15" {
+		t.Fatalf("unexpected dry run report %+v", report)
+	}
+	stored := apitest.Decode[entity.Account](t, apitest.Do(t, router, account, http.MethodGet, "/me", ""), http.StatusOK)
+	if *stored.SleepTime != "23:30" {
+		t.Fatalf("expected the dry run to leave the settings as they were, got %s", *stored.SleepTime)
+	}
+
+	apitest.Decode[entity.AccountRestoreReport](t, apitest.Do(t, router, account, http.MethodPost, "/me/restore", archive), http.StatusCreated)
+	stored = apitest.Decode[entity.Account](t, apitest.Do(t, router, account, http.MethodGet, "/me", ""), http.StatusOK)
+	ocdLogList, err := ocdLogRepo.GetAllLogs(context.Background(), account.ID, entity.OCDLogFilter{}, entity.DefaultOCDLogSort, 10, 0)
+	if err != nil || len(ocdLogList.Logs) != 1 || *stored.SleepTime != "22:15" {
+		t.Fatalf("expected the logs and settings to be restored, got %+v %s (%v)", ocdLogList.Logs, *stored.SleepTime, err)
+	}
+
+	apitest.ExpectStatus(t, apitest.Do(t, router, account, http.MethodPost, "/me/restore?dry_run=maybe", archive), http.StatusBadRequest)
+	tampered := strings.Replace(archive, `"anxiety_level":6`, `"anxiety_level":7`, 1)
+	apitest.ExpectStatus(t, apitest.Do(t, router, account, http.MethodPost, "/me/restore", tampered), http.StatusBadRequest)
+}
+
+func TestDevices(t *testing.T) {
+	memoryDB := memory.NewDB()
+	accountRepo := memory.NewAccountRepository(memoryDB)
+	patient, partner := apitest.CreateAccount(t, accountRepo, "patient"), apitest.CreateAccount(t, accountRepo, "partner")
+	router := newTestRouterWithDB(memoryDB)
+
+	phone := apitest.Decode[entity.Device](t, apitest.Do(t, router, patient, http.MethodPost, "/me/devices", `{"token":"phone-token","platform":"android"}`), http.StatusCreated)
+	if phone.AccountID != patient.ID || *phone.Token != "phone-token" || phone.CreatedAt == nil {
+		t.Fatalf("expected the registered device, got %+v", phone)
+	}
+	apitest.Decode[entity.Device](t, apitest.Do(t, router, patient, http.MethodPost, "/me/devices", `{"token":"laptop-token","platform":"web"}`), http.StatusCreated)
+	apitest.ExpectStatus(t, apitest.Do(t, router, patient, http.MethodPost, "/me/devices", `{"platform":"android"}`), http.StatusBadRequest)
+	apitest.ExpectStatus(t, apitest.Do(t, router, patient, http.MethodPost, "/me/devices", `{"token":"tv-token","platform":"tv"}`), http.StatusBadRequest)
+	registered := apitest.Decode[entity.DeviceList](t, apitest.Do(t, router, patient, http.MethodGet, "/me/devices", ""), http.StatusOK)
+	if len(registered.Devices) != 2 {
+		t.Fatalf("expected both devices, got %+v", registered.Devices)
+	}
+
+	// another user logging in on the phone takes its token over, so the patient no longer gets notifications there
+	core, logs := observer.New(zap.WarnLevel)
+	request := httptest.NewRequest(http.MethodPost, "/me/devices", strings.NewReader(`{"token":"phone-token","platform":"android"}`))
+	request = request.WithContext(log.ContextWithLogger(middleware.ContextWithAccount(request.Context(), partner), zap.New(core)))
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, request)
+	moved := apitest.Decode[entity.Device](t, recorder, http.StatusCreated)
+	if moved.ID != phone.ID || moved.AccountID != partner.ID || moved.UpdatedAt == nil {
+		t.Fatalf("expected the device to move to the other account, got %+v", moved)
+	}
+	entries := logs.FilterMessage("device moved to another account").All()
+	if len(entries) != 1 || entries[0].ContextMap()["from_account_id"] != patient.ID || entries[0].ContextMap()["to_account_id"] != partner.ID {
+		t.Fatalf("expected the move to be logged, got %+v", logs.All())
+	}
+	registered = apitest.Decode[entity.DeviceList](t, apitest.Do(t, router, patient, http.MethodGet, "/me/devices", ""), http.StatusOK)
+	if len(registered.Devices) != 1 || *registered.Devices[0].Token != "laptop-token" {
+		t.Fatalf("expected the moved device to be gone from the previous account, got %+v", registered.Devices)
+	}
+
+	apitest.ExpectStatus(t, apitest.Do(t, router, patient, http.MethodDelete, "/me/devices/"+phone.ID.String(), ""), http.StatusNoContent)
+	registered = apitest.Decode[entity.DeviceList](t, apitest.Do(t, router, partner, http.MethodGet, "/me/devices", ""), http.StatusOK)
+	if len(registered.Devices) != 1 {
+		t.Fatalf("expected a device of another account not to be deleted, got %+v", registered.Devices)
+	}
+	apitest.ExpectStatus(t, apitest.Do(t, router, partner, http.MethodDelete, "/me/devices/"+phone.ID.String(), ""), http.StatusNoContent)
+	registered = apitest.Decode[entity.DeviceList](t, apitest.Do(t, router, partner, http.MethodGet, "/me/devices", ""), http.StatusOK)
+	if len(registered.Devices) != 0 {
+		t.Fatalf("expected the device to be unregistered, got %+v", registered.Devices)
+	}
+	apitest.ExpectStatus(t, apitest.Do(t, router, partner, http.MethodDelete, "/me/devices/nope", ""), http.StatusBadRequest)
+}
+
+func TestGrants(t *testing.T) {
+	memoryDB := memory.NewDB()
+	accountRepo := memory.NewAccountRepository(memoryDB)
+	patient, other := apitest.CreateAccount(t, accountRepo, "patient"), apitest.CreateAccount(t, accountRepo, "other")
+	router := newTestRouterWithDB(memoryDB)
+
+	grant := apitest.Decode[entity.Grant](t, apitest.Do(t, router, patient, http.MethodPost, "/me/grants", `{"email":"clinician@example.com","scopes":["stats","logs","stats"]}`), http.StatusCreated)
+	if grant.AccountID != patient.ID || grant.Code == nil || len(*grant.Code) != 16 || !grant.Pending() || len(grant.Scopes) != 2 || grant.Scopes[0] != entity.GrantScopeLogs {
+		t.Fatalf("expected a pending invitation with a code and normalised scopes, got %+v", grant)
+	}
+	for _, body := range []string{`{"scopes":[]}`, `{"scopes":["everything"]}`, `{"email":"nope","scopes":["logs"]}`, `{"scopes":["logs"],"expires_at":"2020-01-01T00:00:00Z"}`} {
+		apitest.ExpectStatus(t, apitest.Do(t, router, patient, http.MethodPost, "/me/grants", body), http.StatusBadRequest)
+	}
+	grants := apitest.Decode[entity.GrantList](t, apitest.Do(t, router, patient, http.MethodGet, "/me/grants", ""), http.StatusOK)
+	if len(grants.Grants) != 1 || grants.Grants[0].ID != grant.ID {
+		t.Fatalf("expected the invitation to be listed, got %+v", grants.Grants)
+	}
+
+	// only the account that gave a grant can revoke it
+	apitest.ExpectStatus(t, apitest.Do(t, router, other, http.MethodDelete, "/me/grants/"+grant.ID.String(), ""), http.StatusNoContent)
+	grants = apitest.Decode[entity.GrantList](t, apitest.Do(t, router, patient, http.MethodGet, "/me/grants", ""), http.StatusOK)
+	if len(grants.Grants) != 1 {
+		t.Fatalf("expected a grant of another account not to be deleted, got %+v", grants.Grants)
+	}
+	apitest.ExpectStatus(t, apitest.Do(t, router, patient, http.MethodDelete, "/me/grants/"+grant.ID.String(), ""), http.StatusNoContent)
+	grants = apitest.Decode[entity.GrantList](t, apitest.Do(t, router, patient, http.MethodGet, "/me/grants", ""), http.StatusOK)
+	if len(grants.Grants) != 0 {
+		t.Fatalf("expected the invitation to be withdrawn, got %+v", grants.Grants)
+	}
+	apitest.ExpectStatus(t, apitest.Do(t, router, patient, http.MethodDelete, "/me/grants/nope", ""), http.StatusBadRequest)
+}
@@ -0,0 +1,40 @@
+package account
+
+import (
+	"fmt"
+	"github.com/cecobask/ocdtracker-api/internal/api"
+	"github.com/cecobask/ocdtracker-api/internal/api/middleware"
+	"github.com/cecobask/ocdtracker-api/internal/reminder"
+	"github.com/go-chi/render"
+	"net/http"
+	"time"
+)
+
+// GetReminders returns the check-in reminder times of the account for the date query param, today in the time zone of
+// the account by default
+func (h *handler) GetReminders(w http.ResponseWriter, r *http.Request) {
+	account, err := middleware.AccountFromContext(r.Context())
+	if err != nil {
+		api.InternalServerError(w, r, "invalid-account-ctx", err)
+		return
+	}
+	result, err := h.accountRepo.GetAccount(r.Context(), account.ID)
+	if err != nil {
+		api.HandleRetrievalError(w, r, err)
+		return
+	}
+	date := time.Now().In(result.Location())
+	if value := r.URL.Query().Get("date"); value != "" {
+		date, err = time.ParseInLocation("2006-01-02", value, result.Location())
+		if err != nil {
+			api.BadRequestError(w, r, "invalid-date", fmt.Errorf("date must be formatted as 2006-01-02: %w", err))
+			return
+		}
+	}
+	schedule, err := reminder.Schedule(*result, date)
+	if err != nil {
+		api.InternalServerError(w, r, "invalid-account-settings", err)
+		return
+	}
+	render.JSON(w, r, schedule)
+}
@@ -12,6 +12,19 @@ func NewRouter(h *handler) http.Handler {
 		r.Patch("/", h.UpdateAccount)
 		r.Get("/", h.GetAccount)
 		r.Delete("/", h.DeleteAccount)
+		r.Get("/export", h.ExportAccount)
+		r.Post("/restore", h.RestoreAccount)
+		r.Get("/reminders", h.GetReminders)
+		r.Route("/devices", func(r chi.Router) {
+			r.Post("/", h.RegisterDevice)
+			r.Get("/", h.GetAllDevices)
+			r.Delete("/{id}", h.DeleteDevice)
+		})
+		r.Route("/grants", func(r chi.Router) {
+			r.Post("/", h.CreateGrant)
+			r.Get("/", h.GetAllGrants)
+			r.Delete("/{id}", h.DeleteGrant)
+		})
 	})
 	return r
 }
@@ -0,0 +1,68 @@
+// Package apitest serves the http handlers to tests the way main.go does, backed by the in-memory repositories
+package apitest
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/cecobask/ocdtracker-api/internal/api/middleware"
+	"github.com/cecobask/ocdtracker-api/internal/db"
+	"github.com/cecobask/ocdtracker-api/pkg/entity"
+	"github.com/cecobask/ocdtracker-api/pkg/log"
+	"go.uber.org/zap"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// CreateAccount stores an account with the given id and an email derived from it
+func CreateAccount(t *testing.T, accountRepo db.AccountRepository, id string) *entity.Account {
+	t.Helper()
+	email := id + "@example.com"
+	account := &entity.Account{
+		ID:    id,
+		Email: &email,
+	}
+	if err := accountRepo.CreateAccount(context.Background(), account); err != nil {
+		t.Fatalf("failed to create account %s: %v", id, err)
+	}
+	stored, err := accountRepo.GetAccount(context.Background(), id)
+	if err != nil {
+		t.Fatalf("failed to get account %s: %v", id, err)
+	}
+	return stored
+}
+
+// Do serves a request made by the account, with the pagination middleware applied like on the authenticated routes
+func Do(t *testing.T, handler http.Handler, account *entity.Account, method, target, body string) *httptest.ResponseRecorder {
+	t.Helper()
+	return DoRequest(t, handler, account, httptest.NewRequest(method, target, strings.NewReader(body)))
+}
+
+// DoRequest is Do for requests that need headers
+func DoRequest(t *testing.T, handler http.Handler, account *entity.Account, r *http.Request) *httptest.ResponseRecorder {
+	t.Helper()
+	ctx := log.ContextWithLogger(r.Context(), zap.NewNop())
+	ctx = middleware.ContextWithAccount(ctx, account)
+	recorder := httptest.NewRecorder()
+	middleware.NewPaginationMiddleware(ctx).Handle(handler).ServeHTTP(recorder, r.WithContext(ctx))
+	return recorder
+}
+
+// Decode decodes the json body of a response, failing the test unless the status is the expected one
+func Decode[T any](t *testing.T, recorder *httptest.ResponseRecorder, status int) T {
+	t.Helper()
+	ExpectStatus(t, recorder, status)
+	var value T
+	if err := json.Unmarshal(recorder.Body.Bytes(), &value); err != nil {
+		t.Fatalf("failed to decode response body %q: %v", recorder.Body.String(), err)
+	}
+	return value
+}
+
+func ExpectStatus(t *testing.T, recorder *httptest.ResponseRecorder, status int) {
+	t.Helper()
+	if recorder.Code != status {
+		t.Fatalf("expected status %d, got %d: %s", status, recorder.Code, recorder.Body.String())
+	}
+}
@@ -0,0 +1,321 @@
+package erp
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/cecobask/ocdtracker-api/internal/api"
+	"github.com/cecobask/ocdtracker-api/internal/api/middleware"
+	"github.com/cecobask/ocdtracker-api/internal/db"
+	"github.com/cecobask/ocdtracker-api/pkg/entity"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/render"
+	"github.com/google/uuid"
+	"io"
+	"net/http"
+)
+
+type handler struct {
+	ctx         context.Context
+	itemRepo    db.ExposureItemRepository
+	sessionRepo db.ExposureSessionRepository
+}
+
+func NewHandler(ctx context.Context, itemRepo db.ExposureItemRepository, sessionRepo db.ExposureSessionRepository) *handler {
+	return &handler{
+		ctx:         ctx,
+		itemRepo:    itemRepo,
+		sessionRepo: sessionRepo,
+	}
+}
+
+func (h *handler) GetAllItems(w http.ResponseWriter, r *http.Request) {
+	account, err := middleware.AccountFromContext(r.Context())
+	if err != nil {
+		api.InternalServerError(w, r, "invalid-account-ctx", err)
+		return
+	}
+	result, err := h.itemRepo.GetAllItems(r.Context(), account.ID)
+	if err != nil {
+		api.InternalServerError(w, r, "database-error", err)
+		return
+	}
+	render.JSON(w, r, result)
+}
+
+func (h *handler) CreateItem(w http.ResponseWriter, r *http.Request) {
+	var requestBody entity.ExposureItem
+	if !processRequestBody(w, r, &requestBody) {
+		return
+	}
+	if err := requestBody.ValidateCreate(); err != nil {
+		api.BadRequestError(w, r, "invalid-request-body", err)
+		return
+	}
+	account, err := middleware.AccountFromContext(r.Context())
+	if err != nil {
+		api.InternalServerError(w, r, "invalid-account-ctx", err)
+		return
+	}
+	err = h.itemRepo.CreateItem(r.Context(), account.ID, &requestBody)
+	if err != nil {
+		api.InternalServerError(w, r, "database-error", err)
+		return
+	}
+	result, err := h.itemRepo.GetItem(r.Context(), account.ID, requestBody.ID)
+	if err != nil {
+		api.HandleRetrievalError(w, r, err)
+		return
+	}
+	render.Status(r, http.StatusCreated)
+	render.JSON(w, r, result)
+}
+
+func (h *handler) GetItem(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		api.BadRequestError(w, r, "invalid-id", err)
+		return
+	}
+	account, err := middleware.AccountFromContext(r.Context())
+	if err != nil {
+		api.InternalServerError(w, r, "invalid-account-ctx", err)
+		return
+	}
+	result, err := h.itemRepo.GetItem(r.Context(), account.ID, id)
+	if err != nil {
+		api.HandleRetrievalError(w, r, err)
+		return
+	}
+	render.JSON(w, r, result)
+}
+
+func (h *handler) UpdateItem(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		api.BadRequestError(w, r, "invalid-id", err)
+		return
+	}
+	var requestBody entity.ExposureItem
+	if !processRequestBody(w, r, &requestBody) {
+		return
+	}
+	account, err := middleware.AccountFromContext(r.Context())
+	if err != nil {
+		api.InternalServerError(w, r, "invalid-account-ctx", err)
+		return
+	}
+	_, err = h.itemRepo.GetItem(r.Context(), account.ID, id)
+	if err != nil {
+		api.HandleRetrievalError(w, r, err)
+		return
+	}
+	err = h.itemRepo.UpdateItem(r.Context(), account.ID, id, &requestBody)
+	if err != nil {
+		api.InternalServerError(w, r, "database-error", err)
+		return
+	}
+	render.NoContent(w, r)
+}
+
+// DeleteItem removes an item from the hierarchy together with its sessions
+func (h *handler) DeleteItem(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		api.BadRequestError(w, r, "invalid-id", err)
+		return
+	}
+	account, err := middleware.AccountFromContext(r.Context())
+	if err != nil {
+		api.InternalServerError(w, r, "invalid-account-ctx", err)
+		return
+	}
+	err = h.itemRepo.DeleteItem(r.Context(), account.ID, id)
+	if err != nil {
+		api.InternalServerError(w, r, "database-error", err)
+		return
+	}
+	render.NoContent(w, r)
+}
+
+func (h *handler) GetAllSessions(w http.ResponseWriter, r *http.Request) {
+	account, err := middleware.AccountFromContext(r.Context())
+	if err != nil {
+		api.InternalServerError(w, r, "invalid-account-ctx", err)
+		return
+	}
+	pagination := middleware.PaginationFromContext(r.Context())
+	if pagination.CursorMode {
+		api.BadRequestError(w, r, "invalid-pagination", fmt.Errorf("exposure sessions only support offset pagination"))
+		return
+	}
+	var filter entity.ExposureSessionFilter
+	if value := r.URL.Query().Get("item_id"); value != "" {
+		itemID, err := uuid.Parse(value)
+		if err != nil {
+			api.BadRequestError(w, r, "invalid-filter", fmt.Errorf("item_id: %w", err))
+			return
+		}
+		filter.ItemID = &itemID
+	}
+	result, err := h.sessionRepo.GetAllSessions(r.Context(), account.ID, filter, pagination.Limit, *pagination.Offset)
+	if err != nil {
+		api.InternalServerError(w, r, "database-error", err)
+		return
+	}
+	render.JSON(w, r, result)
+}
+
+// CreateSession logs an exposure to an item of the hierarchy of the account
+func (h *handler) CreateSession(w http.ResponseWriter, r *http.Request) {
+	var requestBody entity.ExposureSession
+	if !processRequestBody(w, r, &requestBody) {
+		return
+	}
+	if err := requestBody.ValidateCreate(); err != nil {
+		api.BadRequestError(w, r, "invalid-request-body", err)
+		return
+	}
+	account, err := middleware.AccountFromContext(r.Context())
+	if err != nil {
+		api.InternalServerError(w, r, "invalid-account-ctx", err)
+		return
+	}
+	_, err = h.itemRepo.GetItem(r.Context(), account.ID, *requestBody.ItemID)
+	if errors.Is(err, sql.ErrNoRows) {
+		api.BadRequestError(w, r, "invalid-item", fmt.Errorf("exposure item %s does not exist", *requestBody.ItemID))
+		return
+	}
+	if err != nil {
+		api.InternalServerError(w, r, "database-error", err)
+		return
+	}
+	err = h.sessionRepo.CreateSession(r.Context(), account.ID, &requestBody)
+	if err != nil {
+		api.InternalServerError(w, r, "database-error", err)
+		return
+	}
+	result, err := h.sessionRepo.GetSession(r.Context(), account.ID, requestBody.ID)
+	if err != nil {
+		api.HandleRetrievalError(w, r, err)
+		return
+	}
+	render.Status(r, http.StatusCreated)
+	render.JSON(w, r, result)
+}
+
+func (h *handler) GetSession(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		api.BadRequestError(w, r, "invalid-id", err)
+		return
+	}
+	account, err := middleware.AccountFromContext(r.Context())
+	if err != nil {
+		api.InternalServerError(w, r, "invalid-account-ctx", err)
+		return
+	}
+	result, err := h.sessionRepo.GetSession(r.Context(), account.ID, id)
+	if err != nil {
+		api.HandleRetrievalError(w, r, err)
+		return
+	}
+	render.JSON(w, r, result)
+}
+
+// UpdateSession validates the patch against the stored session, so that the peak still bounds the start and end ratings
+func (h *handler) UpdateSession(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		api.BadRequestError(w, r, "invalid-id", err)
+		return
+	}
+	var requestBody entity.ExposureSession
+	if !processRequestBody(w, r, &requestBody) {
+		return
+	}
+	account, err := middleware.AccountFromContext(r.Context())
+	if err != nil {
+		api.InternalServerError(w, r, "invalid-account-ctx", err)
+		return
+	}
+	existing, err := h.sessionRepo.GetSession(r.Context(), account.ID, id)
+	if err != nil {
+		api.HandleRetrievalError(w, r, err)
+		return
+	}
+	if err = mergeSession(*existing, requestBody).Validate(); err != nil {
+		api.BadRequestError(w, r, "invalid-request-body", err)
+		return
+	}
+	err = h.sessionRepo.UpdateSession(r.Context(), account.ID, id, &requestBody)
+	if err != nil {
+		api.InternalServerError(w, r, "database-error", err)
+		return
+	}
+	render.NoContent(w, r)
+}
+
+func (h *handler) DeleteSession(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		api.BadRequestError(w, r, "invalid-id", err)
+		return
+	}
+	account, err := middleware.AccountFromContext(r.Context())
+	if err != nil {
+		api.InternalServerError(w, r, "invalid-account-ctx", err)
+		return
+	}
+	err = h.sessionRepo.DeleteSession(r.Context(), account.ID, id)
+	if err != nil {
+		api.InternalServerError(w, r, "database-error", err)
+		return
+	}
+	render.NoContent(w, r)
+}
+
+// mergeSession applies the fields set in a patch to a stored session
+func mergeSession(session, patch entity.ExposureSession) entity.ExposureSession {
+	if patch.StartedAt != nil {
+		session.StartedAt = patch.StartedAt
+	}
+	if patch.DurationMinutes != nil {
+		session.DurationMinutes = patch.DurationMinutes
+	}
+	if patch.SUDSStart != nil {
+		session.SUDSStart = patch.SUDSStart
+	}
+	if patch.SUDSPeak != nil {
+		session.SUDSPeak = patch.SUDSPeak
+	}
+	if patch.SUDSEnd != nil {
+		session.SUDSEnd = patch.SUDSEnd
+	}
+	if patch.Notes != nil {
+		session.Notes = patch.Notes
+	}
+	return session
+}
+
+// processRequestBody decodes the body into value and validates it; it writes the error response and returns false if
+// the body is invalid
+func processRequestBody(w http.ResponseWriter, r *http.Request, value interface{ Validate() error }) bool {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		api.BadRequestError(w, r, "invalid-request-body", err)
+		return false
+	}
+	err = json.Unmarshal(body, value)
+	if err != nil {
+		api.BadRequestError(w, r, "invalid-request-body", err)
+		return false
+	}
+	if err := value.Validate(); err != nil {
+		api.BadRequestError(w, r, "invalid-request-body", err)
+		return false
+	}
+	return true
+}
@@ -0,0 +1,87 @@
+package erp
+
+import (
+	"context"
+	"fmt"
+	"github.com/cecobask/ocdtracker-api/internal/api/apitest"
+	"github.com/cecobask/ocdtracker-api/internal/db/memory"
+	"github.com/cecobask/ocdtracker-api/pkg/entity"
+	"github.com/google/uuid"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func newTestRouter(t *testing.T) (http.Handler, *entity.Account) {
+	memoryDB := memory.NewDB()
+	account := apitest.CreateAccount(t, memory.NewAccountRepository(memoryDB), "patient")
+	return NewRouter(NewHandler(context.Background(), memory.NewExposureItemRepository(memoryDB), memory.NewExposureSessionRepository(memoryDB))), account
+}
+
+func createTestItem(t *testing.T, router http.Handler, account *entity.Account, description string, predictedSUDS int) entity.ExposureItem {
+	t.Helper()
+	body := fmt.Sprintf(`{"description":%q,"predicted_suds":%d}`, description, predictedSUDS)
+	return apitest.Decode[entity.ExposureItem](t, apitest.Do(t, router, account, http.MethodPost, "/hierarchy", body), http.StatusCreated)
+}
+
+func TestHierarchy(t *testing.T) {
+	router, account := newTestRouter(t)
+	doorknob := createTestItem(t, router, account, "touch a doorknob", 60)
+	createTestItem(t, router, account, "leave the stove unchecked", 90)
+	createTestItem(t, router, account, "touch a clean towel", 20)
+	apitest.ExpectStatus(t, apitest.Do(t, router, account, http.MethodPost, "/hierarchy", `{"description":"too much","predicted_suds":101}`), http.StatusBadRequest)
+	apitest.ExpectStatus(t, apitest.Do(t, router, account, http.MethodPost, "/hierarchy", `{"predicted_suds":50}`), http.StatusBadRequest)
+
+	hierarchy := apitest.Decode[entity.ExposureItemList](t, apitest.Do(t, router, account, http.MethodGet, "/hierarchy", ""), http.StatusOK)
+	if len(hierarchy.Items) != 3 || *hierarchy.Items[0].PredictedSUDS != 20 || *hierarchy.Items[2].PredictedSUDS != 90 {
+		t.Fatalf("expected the hierarchy from the least to the most distressing item, got %+v", hierarchy.Items)
+	}
+
+	target := "/hierarchy/" + doorknob.ID.String()
+	apitest.ExpectStatus(t, apitest.Do(t, router, account, http.MethodPatch, target, `{"predicted_suds":40}`), http.StatusNoContent)
+	item := apitest.Decode[entity.ExposureItem](t, apitest.Do(t, router, account, http.MethodGet, target, ""), http.StatusOK)
+	if *item.PredictedSUDS != 40 || *item.Description != "touch a doorknob" || item.UpdatedAt == nil {
+		t.Fatalf("update was not applied: %+v", item)
+	}
+	apitest.ExpectStatus(t, apitest.Do(t, router, account, http.MethodPatch, target, `{"description":""}`), http.StatusBadRequest)
+	apitest.ExpectStatus(t, apitest.Do(t, router, account, http.MethodGet, "/hierarchy/"+uuid.New().String(), ""), http.StatusNotFound)
+	apitest.ExpectStatus(t, apitest.Do(t, router, &entity.Account{ID: "other"}, http.MethodGet, target, ""), http.StatusNotFound)
+}
+
+func TestSessions(t *testing.T) {
+	router, account := newTestRouter(t)
+	doorknob := createTestItem(t, router, account, "touch a doorknob", 60)
+	towel := createTestItem(t, router, account, "touch a clean towel", 20)
+	startedAt := time.Now().Add(-time.Hour).UTC().Format(time.RFC3339)
+	body := func(itemID uuid.UUID, start, peak, end int) string {
+		return fmt.Sprintf(`{"item_id":%q,"started_at":%q,"duration_minutes":30,"suds_start":%d,"suds_peak":%d,"suds_end":%d}`, itemID, startedAt, start, peak, end)
+	}
+	first := apitest.Decode[entity.ExposureSession](t, apitest.Do(t, router, account, http.MethodPost, "/session", body(doorknob.ID, 50, 70, 30)), http.StatusCreated)
+	if first.ID == uuid.Nil || *first.ItemID != doorknob.ID || first.StartedAt.Format(time.RFC3339) != startedAt {
+		t.Fatalf("expected the created session in the response, got %+v", first)
+	}
+	apitest.Decode[entity.ExposureSession](t, apitest.Do(t, router, account, http.MethodPost, "/session", `{"item_id":"`+towel.ID.String()+`","duration_minutes":10,"suds_start":20,"suds_peak":25,"suds_end":5}`), http.StatusCreated)
+	apitest.ExpectStatus(t, apitest.Do(t, router, account, http.MethodPost, "/session", body(doorknob.ID, 50, 40, 30)), http.StatusBadRequest)
+	apitest.ExpectStatus(t, apitest.Do(t, router, account, http.MethodPost, "/session", body(uuid.New(), 50, 70, 30)), http.StatusBadRequest)
+
+	sessions := apitest.Decode[entity.ExposureSessionList](t, apitest.Do(t, router, account, http.MethodGet, "/session", ""), http.StatusOK)
+	if len(sessions.Sessions) != 2 || *sessions.Sessions[0].ItemID != towel.ID || *sessions.Pagination.Total != 2 {
+		t.Fatalf("expected the sessions with the most recent first, got %+v", sessions)
+	}
+	sessions = apitest.Decode[entity.ExposureSessionList](t, apitest.Do(t, router, account, http.MethodGet, "/session?item_id="+doorknob.ID.String(), ""), http.StatusOK)
+	if len(sessions.Sessions) != 1 || sessions.Sessions[0].ID != first.ID {
+		t.Fatalf("expected the sessions of the item, got %+v", sessions)
+	}
+	apitest.ExpectStatus(t, apitest.Do(t, router, account, http.MethodGet, "/session?item_id=nope", ""), http.StatusBadRequest)
+
+	target := "/session/" + first.ID.String()
+	apitest.ExpectStatus(t, apitest.Do(t, router, account, http.MethodPatch, target, `{"suds_end":80}`), http.StatusBadRequest)
+	apitest.ExpectStatus(t, apitest.Do(t, router, account, http.MethodPatch, target, `{"suds_end":10,"notes":"easier than expected"}`), http.StatusNoContent)
+	session := apitest.Decode[entity.ExposureSession](t, apitest.Do(t, router, account, http.MethodGet, target, ""), http.StatusOK)
+	if *session.SUDSEnd != 10 || *session.Notes != "easier than expected" || *session.SUDSPeak != 70 {
+		t.Fatalf("update was not applied: %+v", session)
+	}
+
+	apitest.ExpectStatus(t, apitest.Do(t, router, account, http.MethodDelete, "/hierarchy/"+doorknob.ID.String(), ""), http.StatusNoContent)
+	apitest.ExpectStatus(t, apitest.Do(t, router, account, http.MethodGet, target, ""), http.StatusNotFound)
+}
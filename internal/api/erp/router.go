@@ -0,0 +1,30 @@
+package erp
+
+import (
+	"github.com/go-chi/chi/v5"
+	"net/http"
+)
+
+// NewRouter creates all routes associated with exposure and response prevention
+func NewRouter(h *handler) http.Handler {
+	r := chi.NewRouter()
+	r.Route("/hierarchy", func(r chi.Router) {
+		r.Post("/", h.CreateItem)
+		r.Get("/", h.GetAllItems)
+		r.Route("/{id}", func(r chi.Router) {
+			r.Patch("/", h.UpdateItem)
+			r.Get("/", h.GetItem)
+			r.Delete("/", h.DeleteItem)
+		})
+	})
+	r.Route("/session", func(r chi.Router) {
+		r.Post("/", h.CreateSession)
+		r.Get("/", h.GetAllSessions)
+		r.Route("/{id}", func(r chi.Router) {
+			r.Patch("/", h.UpdateSession)
+			r.Get("/", h.GetSession)
+			r.Delete("/", h.DeleteSession)
+		})
+	})
+	return r
+}
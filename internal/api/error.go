@@ -13,6 +13,10 @@ func UnauthorisedError(w http.ResponseWriter, r *http.Request, message string, e
 	httpRespondWithError(w, r, "unauthorised", err, message, http.StatusUnauthorized)
 }
 
+func ForbiddenError(w http.ResponseWriter, r *http.Request, message string, err error) {
+	httpRespondWithError(w, r, "forbidden", err, message, http.StatusForbidden)
+}
+
 func NotFoundError(w http.ResponseWriter, r *http.Request, message string, err error) {
 	httpRespondWithError(w, r, "not-found", err, message, http.StatusNotFound)
 }
@@ -25,6 +29,10 @@ func BadRequestError(w http.ResponseWriter, r *http.Request, message string, err
 	httpRespondWithError(w, r, "bad-request", err, message, http.StatusBadRequest)
 }
 
+func ConflictError(w http.ResponseWriter, r *http.Request, message string, err error) {
+	httpRespondWithError(w, r, "conflict", err, message, http.StatusConflict)
+}
+
 func httpRespondWithError(w http.ResponseWriter, r *http.Request, slug string, err error, message string, status int) {
 	logger := log.LoggerFromContext(r.Context())
 	logger.Warn(message, zap.String("error-slug", slug), zap.Int("status", status), zap.Error(err))
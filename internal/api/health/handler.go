@@ -0,0 +1,96 @@
+package health
+
+import (
+	"context"
+	"github.com/go-chi/render"
+	"net/http"
+	"sync"
+	"time"
+)
+
+const (
+	statusOK   = "ok"
+	statusFail = "fail"
+)
+
+// Check probes a single dependency and returns an error when it is unavailable
+type Check struct {
+	Name  string
+	Probe func(ctx context.Context) error
+}
+
+type handler struct {
+	ctx          context.Context
+	checks       []Check
+	checkTimeout time.Duration
+}
+
+type Response struct {
+	Status string                 `json:"status"`
+	Checks map[string]CheckResult `json:"checks,omitempty"`
+}
+
+type CheckResult struct {
+	Status    string  `json:"status"`
+	LatencyMS float64 `json:"latency_ms"`
+	Error     string  `json:"error,omitempty"`
+}
+
+func NewHandler(ctx context.Context, checkTimeout time.Duration, checks ...Check) *handler {
+	return &handler{
+		ctx:          ctx,
+		checks:       checks,
+		checkTimeout: checkTimeout,
+	}
+}
+
+// Liveness reports that the process is up and able to serve requests
+func (h *handler) Liveness(w http.ResponseWriter, r *http.Request) {
+	render.JSON(w, r, Response{Status: statusOK})
+}
+
+// Readiness runs all dependency checks concurrently and fails if any of them fails
+func (h *handler) Readiness(w http.ResponseWriter, r *http.Request) {
+	resp := Response{
+		Status: statusOK,
+		Checks: make(map[string]CheckResult, len(h.checks)),
+	}
+	var (
+		mu sync.Mutex
+		wg sync.WaitGroup
+	)
+	for _, check := range h.checks {
+		wg.Add(1)
+		go func(check Check) {
+			defer wg.Done()
+			result := h.runCheck(r.Context(), check)
+			mu.Lock()
+			defer mu.Unlock()
+			resp.Checks[check.Name] = result
+			if result.Status != statusOK {
+				resp.Status = statusFail
+			}
+		}(check)
+	}
+	wg.Wait()
+	if resp.Status != statusOK {
+		render.Status(r, http.StatusServiceUnavailable)
+	}
+	render.JSON(w, r, resp)
+}
+
+func (h *handler) runCheck(ctx context.Context, check Check) CheckResult {
+	ctx, cancel := context.WithTimeout(ctx, h.checkTimeout)
+	defer cancel()
+	start := time.Now()
+	err := check.Probe(ctx)
+	result := CheckResult{
+		Status:    statusOK,
+		LatencyMS: float64(time.Since(start).Microseconds()) / 1000,
+	}
+	if err != nil {
+		result.Status = statusFail
+		result.Error = err.Error()
+	}
+	return result
+}
@@ -0,0 +1,77 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/go-chi/chi/v5"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func newTestRouter(checks ...Check) http.Handler {
+	router := chi.NewRouter()
+	RegisterRoutes(router, NewHandler(context.Background(), 50*time.Millisecond, checks...))
+	return router
+}
+
+func get(t *testing.T, router http.Handler, path string) (int, Response) {
+	t.Helper()
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, path, nil))
+	var resp Response
+	if err := json.NewDecoder(recorder.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	return recorder.Code, resp
+}
+
+func TestLivenessIgnoresChecks(t *testing.T) {
+	router := newTestRouter(Check{Name: "broken", Probe: func(context.Context) error {
+		return errors.New("down")
+	}})
+	code, resp := get(t, router, "/healthz")
+	if code != http.StatusOK || resp.Status != statusOK || len(resp.Checks) != 0 {
+		t.Fatalf("expected a plain ok, got %d %+v", code, resp)
+	}
+}
+
+func TestReadinessReportsEveryCheck(t *testing.T) {
+	healthy := Check{Name: "postgres", Probe: func(context.Context) error {
+		return nil
+	}}
+	code, resp := get(t, newTestRouter(healthy), "/readyz")
+	if code != http.StatusOK || resp.Status != statusOK || resp.Checks["postgres"].Status != statusOK {
+		t.Fatalf("expected the check to pass, got %d %+v", code, resp)
+	}
+
+	failing := Check{Name: "firebase", Probe: func(context.Context) error {
+		return errors.New("unreachable")
+	}}
+	slow := Check{Name: "migration", Probe: func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	}}
+	code, resp = get(t, newTestRouter(healthy, failing, slow), "/readyz")
+	if code != http.StatusServiceUnavailable || resp.Status != statusFail {
+		t.Fatalf("expected the readiness check to fail, got %d %+v", code, resp)
+	}
+	if resp.Checks["postgres"].Status != statusOK {
+		t.Fatalf("expected the healthy check to pass, got %+v", resp.Checks["postgres"])
+	}
+	if result := resp.Checks["firebase"]; result.Status != statusFail || result.Error != "unreachable" {
+		t.Fatalf("expected the failing check to be reported, got %+v", result)
+	}
+	if result := resp.Checks["migration"]; result.Status != statusFail || result.Error != context.DeadlineExceeded.Error() {
+		t.Fatalf("expected the slow check to time out, got %+v", result)
+	}
+}
+
+func TestReadinessWithoutChecks(t *testing.T) {
+	code, resp := get(t, newTestRouter(), "/readyz")
+	if code != http.StatusOK || resp.Status != statusOK {
+		t.Fatalf("expected readiness without dependencies, got %d %+v", code, resp)
+	}
+}
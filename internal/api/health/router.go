@@ -0,0 +1,11 @@
+package health
+
+import (
+	"github.com/go-chi/chi/v5"
+)
+
+// RegisterRoutes adds the unauthenticated probe routes to r
+func RegisterRoutes(r chi.Router, h *handler) {
+	r.Get("/healthz", h.Liveness)
+	r.Get("/readyz", h.Readiness)
+}
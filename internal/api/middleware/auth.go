@@ -4,9 +4,9 @@ import (
 	"context"
 	"database/sql"
 	"errors"
-	firebaseAuth "firebase.google.com/go/v4/auth"
 	"github.com/cecobask/ocdtracker-api/internal/api"
-	"github.com/cecobask/ocdtracker-api/internal/db/postgres"
+	"github.com/cecobask/ocdtracker-api/internal/auth"
+	"github.com/cecobask/ocdtracker-api/internal/db"
 	"github.com/cecobask/ocdtracker-api/pkg/entity"
 	"github.com/cecobask/ocdtracker-api/pkg/log"
 	"go.uber.org/zap"
@@ -24,11 +24,11 @@ var (
 
 type authMiddleware struct {
 	ctx         context.Context
-	authClient  *firebaseAuth.Client
-	accountRepo *postgres.AccountRepository
+	authClient  auth.Client
+	accountRepo db.AccountRepository
 }
 
-func NewAuthMiddleware(ctx context.Context, authClient *firebaseAuth.Client, accountRepo *postgres.AccountRepository) *authMiddleware {
+func NewAuthMiddleware(ctx context.Context, authClient auth.Client, accountRepo db.AccountRepository) *authMiddleware {
 	return &authMiddleware{
 		ctx:         ctx,
 		authClient:  authClient,
@@ -44,12 +44,12 @@ func (a *authMiddleware) Handle(next http.Handler) http.Handler {
 			api.UnauthorisedError(w, r, "invalid-jwt", nil)
 			return
 		}
-		token, err := a.authClient.VerifyIDToken(a.ctx, bearerToken)
+		token, err := a.authClient.VerifyIDToken(r.Context(), bearerToken)
 		if err != nil {
 			api.UnauthorisedError(w, r, "invalid-jwt", err)
 			return
 		}
-		user, err := a.authClient.GetUser(a.ctx, token.UID)
+		user, err := a.authClient.GetUser(r.Context(), token.UID)
 		if err != nil {
 			api.NotFoundError(w, r, "firebase-account-not-found", err)
 			return
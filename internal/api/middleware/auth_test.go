@@ -0,0 +1,50 @@
+package middleware
+
+import (
+	"context"
+	"github.com/cecobask/ocdtracker-api/internal/auth"
+	"github.com/cecobask/ocdtracker-api/internal/db/memory"
+	"github.com/cecobask/ocdtracker-api/pkg/entity"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestAuthMiddlewareCreatesAccountOnFirstRequest(t *testing.T) {
+	accountRepo := memory.NewAccountRepository(memory.NewDB())
+	var seen *entity.Account
+	handler := NewAuthMiddleware(context.Background(), auth.NewStubClient(), accountRepo).Handle(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		account, err := AccountFromContext(r.Context())
+		if err != nil {
+			t.Fatalf("expected an account in the context: %v", err)
+		}
+		seen = account
+		w.WriteHeader(http.StatusNoContent)
+	}))
+
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/", nil))
+	if recorder.Code != http.StatusUnauthorized {
+		t.Fatalf("expected status %d without a token, got %d", http.StatusUnauthorized, recorder.Code)
+	}
+
+	for i := 0; i < 2; i++ {
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		r.Header.Set("Authorization", "Bearer alice")
+		recorder = httptest.NewRecorder()
+		handler.ServeHTTP(recorder, r)
+		if recorder.Code != http.StatusNoContent {
+			t.Fatalf("expected status %d, got %d: %s", http.StatusNoContent, recorder.Code, recorder.Body.String())
+		}
+		if seen == nil || seen.ID != "alice" || *seen.Email != "alice@localhost" {
+			t.Fatalf("expected the account of the token, got %+v", seen)
+		}
+	}
+	stored, err := accountRepo.GetAccount(context.Background(), "alice")
+	if err != nil {
+		t.Fatalf("expected the account to be stored: %v", err)
+	}
+	if stored.CreatedAt == nil {
+		t.Fatalf("expected the stored account to have a creation time, got %+v", stored)
+	}
+}
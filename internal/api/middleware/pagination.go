@@ -2,13 +2,18 @@ package middleware
 
 import (
 	"context"
+	"fmt"
+	"github.com/cecobask/ocdtracker-api/internal/api"
 	"github.com/cecobask/ocdtracker-api/pkg/entity"
 	"net/http"
+	"net/url"
 	"strconv"
 )
 
 const (
 	ctxKeyPagination string = "ctxKeyPagination"
+	defaultLimit            = 50
+	maxLimit                = 500
 )
 
 type paginationMiddleware struct {
@@ -21,28 +26,66 @@ func NewPaginationMiddleware(ctx context.Context) *paginationMiddleware {
 	}
 }
 
+// Handle parses limit and either offset or cursor query params; the presence of cursor (empty for the first page)
+// switches to keyset pagination
 func (p paginationMiddleware) Handle(next http.Handler) http.Handler {
 	fn := func(w http.ResponseWriter, r *http.Request) {
-		limitParam := r.URL.Query().Get("limit")
-		offsetParam := r.URL.Query().Get("offset")
-		limit, err := strconv.Atoi(limitParam)
+		paginationDetails, err := parsePagination(r.URL.Query())
 		if err != nil {
-			limit = 50
+			api.BadRequestError(w, r, "invalid-pagination", err)
+			return
 		}
-		offset, err := strconv.Atoi(offsetParam)
-		if err != nil {
-			offset = 0
-		}
-		paginationDetails := entity.PaginationDetails{
-			Limit:  limit,
-			Offset: offset,
-		}
-		ctx := context.WithValue(r.Context(), ctxKeyPagination, &paginationDetails)
+		ctx := context.WithValue(r.Context(), ctxKeyPagination, paginationDetails)
 		next.ServeHTTP(w, r.WithContext(ctx))
 	}
 	return http.HandlerFunc(fn)
 }
 
+func parsePagination(query url.Values) (*entity.PaginationDetails, error) {
+	limit, err := parseNonNegativeInt(query, "limit", defaultLimit)
+	if err != nil {
+		return nil, err
+	}
+	// an empty page never advances a cursor, so clients following next_cursor would loop forever
+	if limit < 1 || limit > maxLimit {
+		return nil, fmt.Errorf("limit: must be between 1 and %d", maxLimit)
+	}
+	paginationDetails := entity.PaginationDetails{
+		Limit: limit,
+	}
+	if query.Has("cursor") {
+		if query.Has("offset") {
+			return nil, fmt.Errorf("offset: must not be combined with cursor")
+		}
+		paginationDetails.CursorMode = true
+		if token := query.Get("cursor"); token != "" {
+			paginationDetails.Cursor, err = entity.DecodeCursor(token)
+			if err != nil {
+				return nil, fmt.Errorf("cursor: %w", err)
+			}
+		}
+		return &paginationDetails, nil
+	}
+	offset, err := parseNonNegativeInt(query, "offset", 0)
+	if err != nil {
+		return nil, err
+	}
+	paginationDetails.Offset = &offset
+	return &paginationDetails, nil
+}
+
+func parseNonNegativeInt(query url.Values, key string, defaultValue int) (int, error) {
+	value := query.Get(key)
+	if value == "" {
+		return defaultValue, nil
+	}
+	parsed, err := strconv.Atoi(value)
+	if err != nil || parsed < 0 {
+		return 0, fmt.Errorf("%s: must be a non-negative integer", key)
+	}
+	return parsed, nil
+}
+
 func PaginationFromContext(ctx context.Context) *entity.PaginationDetails {
 	if paginationDetails, ok := ctx.Value(ctxKeyPagination).(*entity.PaginationDetails); ok {
 		return paginationDetails
@@ -0,0 +1,163 @@
+package ocdlog
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/cecobask/ocdtracker-api/internal/api"
+	"github.com/cecobask/ocdtracker-api/internal/api/middleware"
+	"github.com/cecobask/ocdtracker-api/internal/db"
+	"github.com/cecobask/ocdtracker-api/pkg/entity"
+	"github.com/cecobask/ocdtracker-api/pkg/log"
+	"github.com/go-chi/render"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"net/http"
+)
+
+const (
+	maxBatchBytes = 5 << 20
+)
+
+// batchOperationError marks the operation that made a batch fail
+type batchOperationError struct {
+	index  int
+	status int
+	err    error
+}
+
+func (e *batchOperationError) Error() string {
+	return fmt.Sprintf("operation %d: %s", e.index, e.err)
+}
+
+// ApplyBatch applies a list of create, update and delete operations atomically, reporting on every operation
+func (h *handler) ApplyBatch(w http.ResponseWriter, r *http.Request) {
+	account, err := middleware.AccountFromContext(r.Context())
+	if err != nil {
+		api.InternalServerError(w, r, "invalid-account-ctx", err)
+		return
+	}
+	var batch entity.OCDLogBatch
+	err = json.NewDecoder(http.MaxBytesReader(w, r.Body, maxBatchBytes)).Decode(&batch)
+	if err != nil {
+		api.BadRequestError(w, r, "invalid-request-body", err)
+		return
+	}
+	if len(batch.Operations) == 0 || len(batch.Operations) > entity.MaxBatchOperations {
+		api.BadRequestError(w, r, "invalid-request-body", fmt.Errorf("batch must contain between 1 and %d operations", entity.MaxBatchOperations))
+		return
+	}
+	resp := entity.OCDLogBatchResponse{
+		Results: make([]entity.OCDLogBatchResult, len(batch.Operations)),
+	}
+	for i, operation := range batch.Operations {
+		resp.Results[i] = entity.OCDLogBatchResult{Index: i, Op: operation.Op, ID: operation.ID, Status: entity.BatchResultSkipped}
+		if operation.Op == entity.BatchOpCreate && operation.ID == nil {
+			id := uuid.New()
+			batch.Operations[i].ID, resp.Results[i].ID = &id, &id
+		}
+	}
+	for i, operation := range batch.Operations {
+		err = validateBatchOperation(operation)
+		if err != nil {
+			failBatch(w, r, &resp, &batchOperationError{index: i, status: http.StatusBadRequest, err: err})
+			return
+		}
+	}
+	err = h.ocdLogRepo.WithTx(r.Context(), func(txRepo db.OCDLogRepository) error {
+		for i, operation := range batch.Operations {
+			if err := applyBatchOperation(r, txRepo, account.ID, operation); err != nil {
+				if status := batchErrorStatus(err); status != 0 {
+					return &batchOperationError{index: i, status: status, err: err}
+				}
+				return err
+			}
+			resp.Results[i].Status = entity.BatchResultApplied
+		}
+		return nil
+	})
+	var operationErr *batchOperationError
+	switch {
+	case errors.As(err, &operationErr):
+		failBatch(w, r, &resp, operationErr)
+		return
+	case err != nil:
+		api.InternalServerError(w, r, "database-error", err)
+		return
+	}
+	resp.Applied = true
+	render.JSON(w, r, resp)
+}
+
+func validateBatchOperation(operation entity.OCDLogBatchOperation) error {
+	if err := operation.Validate(); err != nil {
+		return err
+	}
+	if operation.Log != nil {
+		return operation.Log.Validate()
+	}
+	return nil
+}
+
+func applyBatchOperation(r *http.Request, txRepo db.OCDLogRepository, accountID string, operation entity.OCDLogBatchOperation) error {
+	switch operation.Op {
+	case entity.BatchOpCreate:
+		// keep the client generated id but let the server own the timestamps, like a regular create
+		ocdLog := *operation.Log
+		ocdLog.ID = *operation.ID
+		ocdLog.AccountID = ""
+		ocdLog.CreatedAt, ocdLog.UpdatedAt, ocdLog.DeletedAt = nil, nil, nil
+		return txRepo.ImportLogs(r.Context(), accountID, []entity.OCDLog{ocdLog})
+	case entity.BatchOpUpdate:
+		if err := requireBatchLog(r, txRepo, accountID, *operation.ID); err != nil {
+			return err
+		}
+		return txRepo.UpdateLog(r.Context(), accountID, *operation.ID, operation.Log)
+	case entity.BatchOpDelete:
+		if err := requireBatchLog(r, txRepo, accountID, *operation.ID); err != nil {
+			return err
+		}
+		return txRepo.DeleteLog(r.Context(), accountID, *operation.ID)
+	default:
+		return fmt.Errorf("unknown operation %s", operation.Op)
+	}
+}
+
+// requireBatchLog fails the batch unless the log exists and belongs to the account, since updates and deletes of
+// other logs would otherwise silently do nothing
+func requireBatchLog(r *http.Request, txRepo db.OCDLogRepository, accountID string, id uuid.UUID) error {
+	_, err := txRepo.GetLog(r.Context(), accountID, id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return fmt.Errorf("log %s not found: %w", id, err)
+	}
+	return err
+}
+
+// batchErrorStatus returns the status of errors caused by the request; zero means the error is internal
+func batchErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		return http.StatusNotFound
+	case errors.Is(err, db.ErrorDuplicate):
+		return http.StatusConflict
+	default:
+		return 0
+	}
+}
+
+// failBatch marks the failed operation, rolls back the ones applied before it and responds with the results
+func failBatch(w http.ResponseWriter, r *http.Request, resp *entity.OCDLogBatchResponse, operationErr *batchOperationError) {
+	log.LoggerFromContext(r.Context()).Warn("batch-failed", zap.Int("status", operationErr.status), zap.Error(operationErr))
+	for i := range resp.Results {
+		switch {
+		case i == operationErr.index:
+			resp.Results[i].Status = entity.BatchResultFailed
+			resp.Results[i].Error = operationErr.err.Error()
+		case resp.Results[i].Status == entity.BatchResultApplied:
+			resp.Results[i].Status = entity.BatchResultRolledBack
+		}
+	}
+	render.Status(r, operationErr.status)
+	render.JSON(w, r, resp)
+}
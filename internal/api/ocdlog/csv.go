@@ -0,0 +1,142 @@
+package ocdlog
+
+import (
+	"encoding/csv"
+	"github.com/cecobask/ocdtracker-api/pkg/entity"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	contentTypeCSV = "text/csv"
+	csvFlushEvery  = 100
+)
+
+var (
+	csvHeader = []string{"id", "created_at", "updated_at", "ruminate_minutes", "anxiety_level", "notes", "tags", "compulsion_type", "urge_intensity", "resisted", "resisted_minutes"}
+)
+
+// csvLogWriter writes logs as csv rows, flushing every few rows so that large exports are streamed
+type csvLogWriter struct {
+	writer  *csv.Writer
+	out     *countingWriter
+	flusher http.Flusher
+	written int
+}
+
+// countingWriter counts the bytes that reached the underlying writer
+type countingWriter struct {
+	io.Writer
+	count int64
+}
+
+func newCSVLogWriter(w io.Writer) (*csvLogWriter, error) {
+	out := &countingWriter{Writer: w}
+	logWriter := &csvLogWriter{writer: csv.NewWriter(out), out: out}
+	logWriter.flusher, _ = w.(http.Flusher)
+	if err := logWriter.writer.Write(csvHeader); err != nil {
+		return nil, err
+	}
+	return logWriter, nil
+}
+
+func (lw *csvLogWriter) Write(ocdLog entity.OCDLog) error {
+	err := lw.writer.Write([]string{
+		ocdLog.ID.String(),
+		formatCSVTime(ocdLog.CreatedAt),
+		formatCSVTime(ocdLog.UpdatedAt),
+		formatCSVInt(ocdLog.RuminateMinutes),
+		formatCSVInt(ocdLog.AnxietyLevel),
+		formatCSVString(ocdLog.Notes),
+		escapeCSVFormula(strings.Join(ocdLog.Tags, entity.TagNameSeparator)),
+		formatCSVString(ocdLog.CompulsionType),
+		formatCSVInt(ocdLog.UrgeIntensity),
+		formatCSVBool(ocdLog.Resisted),
+		formatCSVInt(ocdLog.ResistedMinutes),
+	})
+	if err != nil {
+		return err
+	}
+	lw.written++
+	if lw.written%csvFlushEvery == 0 {
+		return lw.Flush()
+	}
+	return nil
+}
+
+func (lw *csvLogWriter) Flush() error {
+	lw.writer.Flush()
+	if lw.flusher != nil {
+		lw.flusher.Flush()
+	}
+	return lw.writer.Error()
+}
+
+// Started reports whether any output reached the underlying writer, after which the response can no longer change
+func (lw *csvLogWriter) Started() bool {
+	return lw.out.count > 0
+}
+
+func (cw *countingWriter) Write(p []byte) (int, error) {
+	n, err := cw.Writer.Write(p)
+	cw.count += int64(n)
+	return n, err
+}
+
+func acceptsCSV(r *http.Request) bool {
+	for _, mediaRange := range strings.Split(r.Header.Get("Accept"), ",") {
+		mediaType := strings.TrimSpace(strings.Split(mediaRange, ";")[0])
+		if strings.EqualFold(mediaType, contentTypeCSV) {
+			return true
+		}
+	}
+	return false
+}
+
+func formatCSVTime(value *time.Time) string {
+	if value == nil {
+		return ""
+	}
+	return value.UTC().Format(time.RFC3339Nano)
+}
+
+func formatCSVInt(value *int) string {
+	if value == nil {
+		return ""
+	}
+	return strconv.Itoa(*value)
+}
+
+func formatCSVString(value *string) string {
+	if value == nil {
+		return ""
+	}
+	return escapeCSVFormula(*value)
+}
+
+// escapeCSVFormula prefixes free text that spreadsheet applications would evaluate as a formula with a single quote,
+// since exports are opened by clinicians as well as by the users who wrote the text
+func escapeCSVFormula(value string) string {
+	if value != "" && strings.ContainsRune("=+-@\t\r", rune(value[0])) {
+		return "'" + value
+	}
+	return value
+}
+
+// unescapeCSVFormula reverts escapeCSVFormula so that exports can be imported again unchanged
+func unescapeCSVFormula(value string) string {
+	if len(value) > 1 && value[0] == '\'' && escapeCSVFormula(value[1:]) == value {
+		return value[1:]
+	}
+	return value
+}
+
+func formatCSVBool(value *bool) string {
+	if value == nil {
+		return ""
+	}
+	return strconv.FormatBool(*value)
+}
@@ -0,0 +1,32 @@
+package ocdlog
+
+import (
+	"testing"
+)
+
+func TestEscapeCSVFormula(t *testing.T) {
+	tests := map[string]string{
+		"":                  "",
+		"felt better":       "felt better",
+		"=HYPERLINK(\"x\")": "'=HYPERLINK(\"x\")",
+		"+1 hour":           "'+1 hour",
+		"-10 minutes":       "'-10 minutes",
+		"@SUM(A1:A2)":       "'@SUM(A1:A2)",
+		"\tindented":        "'\tindented",
+		"a=b":               "a=b",
+		"'already quoted":   "'already quoted",
+	}
+	for value, want := range tests {
+		if got := escapeCSVFormula(value); got != want {
+			t.Errorf("expected %q to be escaped as %q, got %q", value, want, got)
+		}
+	}
+}
+
+func TestUnescapeCSVFormulaRevertsEscape(t *testing.T) {
+	for _, value := range []string{"", "'", "felt better", "=1+1", "-10 minutes", "'quoted", "''=nested"} {
+		if got := unescapeCSVFormula(escapeCSVFormula(value)); got != value {
+			t.Errorf("expected %q to survive escaping, got %q", value, got)
+		}
+	}
+}
@@ -3,23 +3,28 @@ package ocdlog
 import (
 	"context"
 	"encoding/json"
+	"fmt"
 	"github.com/cecobask/ocdtracker-api/internal/api"
 	"github.com/cecobask/ocdtracker-api/internal/api/middleware"
-	"github.com/cecobask/ocdtracker-api/internal/db/postgres"
+	"github.com/cecobask/ocdtracker-api/internal/db"
 	"github.com/cecobask/ocdtracker-api/pkg/entity"
+	"github.com/cecobask/ocdtracker-api/pkg/log"
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/render"
 	"github.com/google/uuid"
+	"go.uber.org/zap"
 	"io"
 	"net/http"
+	"strings"
+	"time"
 )
 
 type handler struct {
 	ctx        context.Context
-	ocdLogRepo *postgres.OCDLogRepository
+	ocdLogRepo db.OCDLogRepository
 }
 
-func NewHandler(ctx context.Context, ocdLogRepo *postgres.OCDLogRepository) *handler {
+func NewHandler(ctx context.Context, ocdLogRepo db.OCDLogRepository) *handler {
 	return &handler{
 		ctx:        ctx,
 		ocdLogRepo: ocdLogRepo,
@@ -32,8 +37,133 @@ func (h *handler) GetAllLogs(w http.ResponseWriter, r *http.Request) {
 		api.InternalServerError(w, r, "invalid-account-ctx", err)
 		return
 	}
+	filter, err := parseFilter(r.URL.Query())
+	if err != nil {
+		api.BadRequestError(w, r, "invalid-filter", err)
+		return
+	}
+	sort, err := parseSort(r.URL.Query())
+	if err != nil {
+		api.BadRequestError(w, r, "invalid-sort", err)
+		return
+	}
+	if acceptsCSV(r) {
+		h.exportLogs(w, r, account.ID, *filter, sort)
+		return
+	}
 	pagination := middleware.PaginationFromContext(r.Context())
-	result, err := h.ocdLogRepo.GetAllLogs(r.Context(), account.ID, pagination.Limit, pagination.Offset)
+	if pagination.CursorMode {
+		h.getLogsByCursor(w, r, account.ID, *filter, sort, pagination)
+		return
+	}
+	result, err := h.ocdLogRepo.GetAllLogs(r.Context(), account.ID, *filter, sort, pagination.Limit, *pagination.Offset)
+	if err != nil {
+		api.InternalServerError(w, r, "database-error", err)
+		return
+	}
+	render.JSON(w, r, result)
+}
+
+func (h *handler) getLogsByCursor(w http.ResponseWriter, r *http.Request, accountID string, filter entity.OCDLogFilter, sort entity.OCDLogSort, pagination *entity.PaginationDetails) {
+	if !sort.CursorCompatible() {
+		api.BadRequestError(w, r, "invalid-sort", fmt.Errorf("cursor pagination only supports sorting by created_at"))
+		return
+	}
+	if pagination.Cursor != nil && pagination.Cursor.Descending != sort.Descending() {
+		api.BadRequestError(w, r, "invalid-pagination", fmt.Errorf("cursor was issued for a different sort order"))
+		return
+	}
+	result, err := h.ocdLogRepo.GetLogsByCursor(r.Context(), accountID, filter, pagination.Cursor, sort.Descending(), pagination.Limit)
+	if err != nil {
+		api.InternalServerError(w, r, "database-error", err)
+		return
+	}
+	api.SetCursorLinkHeader(w, r, result.Pagination)
+	render.JSON(w, r, result)
+}
+
+func (h *handler) ExportLogs(w http.ResponseWriter, r *http.Request) {
+	account, err := middleware.AccountFromContext(r.Context())
+	if err != nil {
+		api.InternalServerError(w, r, "invalid-account-ctx", err)
+		return
+	}
+	filter, err := parseFilter(r.URL.Query())
+	if err != nil {
+		api.BadRequestError(w, r, "invalid-filter", err)
+		return
+	}
+	sort, err := parseSort(r.URL.Query())
+	if err != nil {
+		api.BadRequestError(w, r, "invalid-sort", err)
+		return
+	}
+	h.exportLogs(w, r, account.ID, *filter, sort)
+}
+
+// exportLogs streams every log matching the filter as csv; errors after the first flush can only be logged
+func (h *handler) exportLogs(w http.ResponseWriter, r *http.Request, accountID string, filter entity.OCDLogFilter, sort entity.OCDLogSort) {
+	w.Header().Set("Content-Type", contentTypeCSV+"; charset=utf-8")
+	w.Header().Set("Content-Disposition", `attachment; filename="ocdlogs.csv"`)
+	csvWriter, err := newCSVLogWriter(w)
+	if err != nil {
+		api.InternalServerError(w, r, "csv-error", err)
+		return
+	}
+	err = h.ocdLogRepo.StreamLogs(r.Context(), accountID, filter, sort, csvWriter.Write)
+	if err != nil && !csvWriter.Started() {
+		w.Header().Del("Content-Disposition")
+		api.InternalServerError(w, r, "database-error", err)
+		return
+	}
+	if err == nil {
+		err = csvWriter.Flush()
+	}
+	if err != nil {
+		log.LoggerFromContext(r.Context()).Error("failed to export logs", zap.Error(err))
+	}
+}
+
+func (h *handler) GetLogStats(w http.ResponseWriter, r *http.Request) {
+	account, err := middleware.AccountFromContext(r.Context())
+	if err != nil {
+		api.InternalServerError(w, r, "invalid-account-ctx", err)
+		return
+	}
+	filter, err := parseFilter(r.URL.Query())
+	if err != nil {
+		api.BadRequestError(w, r, "invalid-filter", err)
+		return
+	}
+	result, err := h.ocdLogRepo.GetLogStats(r.Context(), account.ID, *filter)
+	if err != nil {
+		api.InternalServerError(w, r, "database-error", err)
+		return
+	}
+	render.JSON(w, r, result)
+}
+
+func (h *handler) GetLogSeries(w http.ResponseWriter, r *http.Request) {
+	account, err := middleware.AccountFromContext(r.Context())
+	if err != nil {
+		api.InternalServerError(w, r, "invalid-account-ctx", err)
+		return
+	}
+	filter, err := parseFilter(r.URL.Query())
+	if err != nil {
+		api.BadRequestError(w, r, "invalid-filter", err)
+		return
+	}
+	query, err := parseSeriesQuery(r.URL.Query(), filter, account)
+	if err != nil {
+		api.BadRequestError(w, r, "invalid-series-query", err)
+		return
+	}
+	// widen the filter to whole buckets so that the first and last points are complete
+	from := query.Bucket.Truncate(query.From)
+	to := query.Bucket.Next(query.Bucket.Truncate(query.To)).Add(-time.Microsecond)
+	filter.From, filter.To = &from, &to
+	result, err := h.ocdLogRepo.GetLogSeries(r.Context(), account.ID, *filter, *query)
 	if err != nil {
 		api.InternalServerError(w, r, "database-error", err)
 		return
@@ -117,7 +247,45 @@ func (h *handler) CreateLog(w http.ResponseWriter, r *http.Request) {
 		api.InternalServerError(w, r, "database-error", err)
 		return
 	}
+	result, err := h.ocdLogRepo.GetLog(r.Context(), account.ID, requestBody.ID)
+	if err != nil {
+		api.HandleRetrievalError(w, r, err)
+		return
+	}
 	render.Status(r, http.StatusCreated)
+	render.JSON(w, r, result)
+}
+
+// ImportLogs inserts the valid rows of a csv or json array body in one transaction and reports on every row
+func (h *handler) ImportLogs(w http.ResponseWriter, r *http.Request) {
+	account, err := middleware.AccountFromContext(r.Context())
+	if err != nil {
+		api.InternalServerError(w, r, "invalid-account-ctx", err)
+		return
+	}
+	body := http.MaxBytesReader(w, r.Body, maxImportBytes)
+	var rows []importRow
+	if strings.HasPrefix(r.Header.Get("Content-Type"), contentTypeCSV) {
+		rows, err = parseImportCSV(body)
+	} else {
+		rows, err = parseImportJSON(body)
+	}
+	if err != nil {
+		api.BadRequestError(w, r, "invalid-request-body", err)
+		return
+	}
+	ocdLogs, report := buildImport(rows)
+	if len(ocdLogs) > 0 {
+		err = h.ocdLogRepo.ImportLogs(r.Context(), account.ID, ocdLogs)
+		if err != nil {
+			api.InternalServerError(w, r, "database-error", err)
+			return
+		}
+	}
+	if report.Accepted > 0 {
+		render.Status(r, http.StatusCreated)
+	}
+	render.JSON(w, r, report)
 }
 
 func (h *handler) DeleteLog(w http.ResponseWriter, r *http.Request) {
@@ -139,6 +307,46 @@ func (h *handler) DeleteLog(w http.ResponseWriter, r *http.Request) {
 	render.NoContent(w, r)
 }
 
+// GetTrashedLogs lists the deleted logs that have not been purged yet, most recently deleted first
+func (h *handler) GetTrashedLogs(w http.ResponseWriter, r *http.Request) {
+	account, err := middleware.AccountFromContext(r.Context())
+	if err != nil {
+		api.InternalServerError(w, r, "invalid-account-ctx", err)
+		return
+	}
+	pagination := middleware.PaginationFromContext(r.Context())
+	if pagination.CursorMode {
+		api.BadRequestError(w, r, "invalid-pagination", fmt.Errorf("the trash only supports offset pagination"))
+		return
+	}
+	result, err := h.ocdLogRepo.GetTrashedLogs(r.Context(), account.ID, pagination.Limit, *pagination.Offset)
+	if err != nil {
+		api.InternalServerError(w, r, "database-error", err)
+		return
+	}
+	render.JSON(w, r, result)
+}
+
+// RestoreLog takes a deleted log out of the trash
+func (h *handler) RestoreLog(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		api.BadRequestError(w, r, "invalid-id", err)
+		return
+	}
+	account, err := middleware.AccountFromContext(r.Context())
+	if err != nil {
+		api.InternalServerError(w, r, "invalid-account-ctx", err)
+		return
+	}
+	err = h.ocdLogRepo.RestoreLog(r.Context(), account.ID, id)
+	if err != nil {
+		api.HandleRetrievalError(w, r, err)
+		return
+	}
+	render.NoContent(w, r)
+}
+
 func processRequestBody(w http.ResponseWriter, r *http.Request) *entity.OCDLog {
 	body, err := io.ReadAll(r.Body)
 	if err != nil {
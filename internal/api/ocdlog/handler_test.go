@@ -0,0 +1,652 @@
+package ocdlog
+
+import (
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"github.com/cecobask/ocdtracker-api/internal/api/apitest"
+	"github.com/cecobask/ocdtracker-api/internal/db/memory"
+	"github.com/cecobask/ocdtracker-api/pkg/entity"
+	"github.com/google/uuid"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newTestRouter(t *testing.T) (http.Handler, *entity.Account) {
+	memoryDB := memory.NewDB()
+	account := apitest.CreateAccount(t, memory.NewAccountRepository(memoryDB), "patient")
+	return NewRouter(NewHandler(context.Background(), memory.NewOCDLogRepository(memoryDB))), account
+}
+
+// createTestLogs creates one log per body, a millisecond apart so that their created_at timestamps are distinct
+func createTestLogs(t *testing.T, router http.Handler, account *entity.Account, bodies ...string) []entity.OCDLog {
+	t.Helper()
+	ocdLogs := make([]entity.OCDLog, 0, len(bodies))
+	for _, body := range bodies {
+		ocdLogs = append(ocdLogs, apitest.Decode[entity.OCDLog](t, apitest.Do(t, router, account, http.MethodPost, "/", body), http.StatusCreated))
+		time.Sleep(time.Millisecond)
+	}
+	return ocdLogs
+}
+
+// anxietyLevels lists the anxiety levels of the logs returned for target, in order
+func anxietyLevels(t *testing.T, router http.Handler, account *entity.Account, target string) []int {
+	t.Helper()
+	list := apitest.Decode[entity.OCDLogList](t, apitest.Do(t, router, account, http.MethodGet, target, ""), http.StatusOK)
+	levels := make([]int, 0, len(list.Logs))
+	for _, ocdLog := range list.Logs {
+		levels = append(levels, *ocdLog.AnxietyLevel)
+	}
+	return levels
+}
+
+func TestCreateGetUpdateAndDeleteLog(t *testing.T) {
+	router, account := newTestRouter(t)
+	created := apitest.Decode[entity.OCDLog](t, apitest.Do(t, router, account, http.MethodPost, "/", `{"ruminate_minutes":10,"anxiety_level":4}`), http.StatusCreated)
+	if created.ID == uuid.Nil || created.AccountID != account.ID || *created.AnxietyLevel != 4 || created.CreatedAt == nil {
+		t.Fatalf("expected the created log in the response, got %+v", created)
+	}
+	apitest.ExpectStatus(t, apitest.Do(t, router, account, http.MethodPost, "/", `{"anxiety_level":11}`), http.StatusBadRequest)
+
+	apitest.ExpectStatus(t, apitest.Do(t, router, account, http.MethodPatch, "/"+created.ID.String(), `{"anxiety_level":7,"notes":"better"}`), http.StatusNoContent)
+	ocdLog := apitest.Decode[entity.OCDLog](t, apitest.Do(t, router, account, http.MethodGet, "/"+created.ID.String(), ""), http.StatusOK)
+	if *ocdLog.AnxietyLevel != 7 || *ocdLog.RuminateMinutes != 10 || *ocdLog.Notes != "better" || ocdLog.UpdatedAt == nil {
+		t.Fatalf("update was not applied: %+v", ocdLog)
+	}
+
+	apitest.ExpectStatus(t, apitest.Do(t, router, account, http.MethodDelete, "/"+created.ID.String(), ""), http.StatusNoContent)
+	apitest.ExpectStatus(t, apitest.Do(t, router, account, http.MethodGet, "/"+created.ID.String(), ""), http.StatusNotFound)
+	apitest.ExpectStatus(t, apitest.Do(t, router, account, http.MethodPatch, "/"+created.ID.String(), `{"anxiety_level":1}`), http.StatusNotFound)
+	apitest.ExpectStatus(t, apitest.Do(t, router, account, http.MethodGet, "/not-a-uuid", ""), http.StatusBadRequest)
+}
+
+func TestGetAllLogsPagination(t *testing.T) {
+	router, account := newTestRouter(t)
+	ids := make([]uuid.UUID, 0, 5)
+	for i := 0; i < 5; i++ {
+		created := apitest.Decode[entity.OCDLog](t, apitest.Do(t, router, account, http.MethodPost, "/", `{"anxiety_level":1}`), http.StatusCreated)
+		ids = append(ids, created.ID)
+	}
+	list := apitest.Decode[entity.OCDLogList](t, apitest.Do(t, router, account, http.MethodGet, "/?limit=2&offset=3", ""), http.StatusOK)
+	if *list.Pagination.Total != 5 || list.Pagination.Count != 2 || list.Pagination.Limit != 2 {
+		t.Fatalf("unexpected pagination %+v", list.Pagination)
+	}
+	if len(list.Logs) != 2 || list.Logs[0].ID != ids[3] || list.Logs[1].ID != ids[4] {
+		t.Fatalf("expected the last two logs in creation order, got %+v", list.Logs)
+	}
+
+	apitest.ExpectStatus(t, apitest.Do(t, router, account, http.MethodDelete, "/", ""), http.StatusNoContent)
+	list = apitest.Decode[entity.OCDLogList](t, apitest.Do(t, router, account, http.MethodGet, "/", ""), http.StatusOK)
+	if len(list.Logs) != 0 || *list.Pagination.Total != 0 {
+		t.Fatalf("expected every log to be removed, got %+v", list)
+	}
+}
+
+func TestLogsAreScopedToAccount(t *testing.T) {
+	memoryDB := memory.NewDB()
+	accountRepo := memory.NewAccountRepository(memoryDB)
+	owner := apitest.CreateAccount(t, accountRepo, "owner")
+	other := apitest.CreateAccount(t, accountRepo, "other")
+	router := NewRouter(NewHandler(context.Background(), memory.NewOCDLogRepository(memoryDB)))
+
+	created := apitest.Decode[entity.OCDLog](t, apitest.Do(t, router, owner, http.MethodPost, "/", `{"anxiety_level":3}`), http.StatusCreated)
+	apitest.ExpectStatus(t, apitest.Do(t, router, other, http.MethodGet, "/"+created.ID.String(), ""), http.StatusNotFound)
+	apitest.ExpectStatus(t, apitest.Do(t, router, other, http.MethodDelete, "/"+created.ID.String(), ""), http.StatusNoContent)
+	apitest.ExpectStatus(t, apitest.Do(t, router, owner, http.MethodGet, "/"+created.ID.String(), ""), http.StatusOK)
+	list := apitest.Decode[entity.OCDLogList](t, apitest.Do(t, router, other, http.MethodGet, "/", ""), http.StatusOK)
+	if len(list.Logs) != 0 {
+		t.Fatalf("expected no logs for the other account, got %+v", list.Logs)
+	}
+}
+
+func TestGetAllLogsFilters(t *testing.T) {
+	router, account := newTestRouter(t)
+	created := createTestLogs(t, router, account,
+		`{"anxiety_level":2,"ruminate_minutes":0}`,
+		`{"anxiety_level":5,"ruminate_minutes":10,"notes":"work"}`,
+		`{"anxiety_level":8,"ruminate_minutes":20,"notes":""}`,
+		`{"anxiety_level":9,"ruminate_minutes":30,"notes":"home"}`,
+	)
+	between := url.Values{
+		"from": {created[1].CreatedAt.Format(time.RFC3339Nano)},
+		"to":   {created[2].CreatedAt.Format(time.RFC3339Nano)},
+	}
+	tests := []struct {
+		query string
+		want  []int
+	}{
+		{query: "", want: []int{2, 5, 8, 9}},
+		{query: "anxiety_min=5", want: []int{5, 8, 9}},
+		{query: "anxiety_min=5&anxiety_max=8", want: []int{5, 8}},
+		{query: "ruminate_min=10&ruminate_max=20", want: []int{5, 8}},
+		{query: "has_notes=true", want: []int{5, 9}},
+		{query: "has_notes=false", want: []int{2, 8}},
+		{query: between.Encode(), want: []int{5, 8}},
+	}
+	for _, test := range tests {
+		t.Run(test.query, func(t *testing.T) {
+			if got := anxietyLevels(t, router, account, "/?"+test.query); fmt.Sprint(got) != fmt.Sprint(test.want) {
+				t.Fatalf("expected anxiety levels %v, got %v", test.want, got)
+			}
+		})
+	}
+	list := apitest.Decode[entity.OCDLogList](t, apitest.Do(t, router, account, http.MethodGet, "/?anxiety_min=5&limit=1", ""), http.StatusOK)
+	if *list.Pagination.Total != 3 || len(list.Logs) != 1 {
+		t.Fatalf("expected the total to count the filtered logs, got %+v", list.Pagination)
+	}
+}
+
+func TestGetAllLogsRejectsInvalidFilters(t *testing.T) {
+	router, account := newTestRouter(t)
+	for _, query := range []string{
+		"anxiety_min=11",
+		"anxiety_min=6&anxiety_max=5",
+		"ruminate_min=-1",
+		"ruminate_min=abc",
+		"has_notes=maybe",
+		"from=yesterday",
+		"from=2022-01-02T00:00:00Z&to=2022-01-01T00:00:00Z",
+	} {
+		t.Run(query, func(t *testing.T) {
+			apitest.ExpectStatus(t, apitest.Do(t, router, account, http.MethodGet, "/?"+query, ""), http.StatusBadRequest)
+		})
+	}
+}
+
+func TestGetAllLogsSort(t *testing.T) {
+	router, account := newTestRouter(t)
+	created := createTestLogs(t, router, account,
+		`{"anxiety_level":5,"ruminate_minutes":20}`,
+		`{"anxiety_level":2,"ruminate_minutes":10}`,
+		`{"anxiety_level":9,"ruminate_minutes":10}`,
+		`{"anxiety_level":7,"ruminate_minutes":20}`,
+	)
+	apitest.ExpectStatus(t, apitest.Do(t, router, account, http.MethodPatch, "/"+created[1].ID.String(), `{"notes":"edited"}`), http.StatusNoContent)
+	tests := []struct {
+		query string
+		want  []int
+	}{
+		{query: "", want: []int{5, 2, 9, 7}},
+		{query: "sort=-created_at", want: []int{7, 9, 2, 5}},
+		{query: "sort=-anxiety_level", want: []int{9, 7, 5, 2}},
+		{query: "sort=ruminate_minutes,-anxiety_level", want: []int{9, 2, 7, 5}},
+		{query: "sort=-ruminate_minutes,anxiety_level", want: []int{5, 7, 2, 9}},
+		{query: "sort=updated_at,anxiety_level", want: []int{2, 5, 7, 9}},
+	}
+	for _, test := range tests {
+		t.Run(test.query, func(t *testing.T) {
+			if got := anxietyLevels(t, router, account, "/?"+test.query); fmt.Sprint(got) != fmt.Sprint(test.want) {
+				t.Fatalf("expected anxiety levels %v, got %v", test.want, got)
+			}
+		})
+	}
+	for _, query := range []string{"sort=notes", "sort=anxiety_level,-anxiety_level", "sort=anxiety_level,,created_at", "sort=-"} {
+		t.Run(query, func(t *testing.T) {
+			apitest.ExpectStatus(t, apitest.Do(t, router, account, http.MethodGet, "/?"+query, ""), http.StatusBadRequest)
+		})
+	}
+}
+
+// followCursor pages through target until next_cursor runs out, checking the Link header on the way
+func followCursor(t *testing.T, router http.Handler, account *entity.Account, target string) []uuid.UUID {
+	t.Helper()
+	ids := make([]uuid.UUID, 0)
+	for page := 0; page < 10; page++ {
+		recorder := apitest.Do(t, router, account, http.MethodGet, target, "")
+		list := apitest.Decode[entity.OCDLogList](t, recorder, http.StatusOK)
+		if list.Pagination.Offset != nil || list.Pagination.Total != nil {
+			t.Fatalf("expected a cursor page, got %+v", list.Pagination)
+		}
+		for _, ocdLog := range list.Logs {
+			ids = append(ids, ocdLog.ID)
+		}
+		if list.Pagination.NextCursor == "" {
+			return ids
+		}
+		if link := recorder.Header().Get("Link"); !strings.Contains(link, `rel="next"`) {
+			t.Fatalf("expected a next link, got %q", link)
+		}
+		query := url.Values{"limit": {"2"}, "cursor": {list.Pagination.NextCursor}}
+		if strings.Contains(target, "sort=-created_at") {
+			query.Set("sort", "-created_at")
+		}
+		target = "/?" + query.Encode()
+	}
+	t.Fatalf("cursor pagination did not terminate")
+	return nil
+}
+
+func TestGetAllLogsCursorPagination(t *testing.T) {
+	router, account := newTestRouter(t)
+	created := createTestLogs(t, router, account, `{"anxiety_level":1}`, `{"anxiety_level":2}`, `{"anxiety_level":3}`, `{"anxiety_level":4}`, `{"anxiety_level":5}`)
+	ids := make([]uuid.UUID, 0, len(created))
+	reversed := make([]uuid.UUID, 0, len(created))
+	for i := range created {
+		ids = append(ids, created[i].ID)
+		reversed = append(reversed, created[len(created)-1-i].ID)
+	}
+	if got := followCursor(t, router, account, "/?limit=2&cursor="); fmt.Sprint(got) != fmt.Sprint(ids) {
+		t.Fatalf("expected the logs in creation order %v, got %v", ids, got)
+	}
+	if got := followCursor(t, router, account, "/?limit=2&cursor=&sort=-created_at"); fmt.Sprint(got) != fmt.Sprint(reversed) {
+		t.Fatalf("expected the logs in reverse creation order %v, got %v", reversed, got)
+	}
+
+	first := apitest.Decode[entity.OCDLogList](t, apitest.Do(t, router, account, http.MethodGet, "/?limit=2&cursor=", ""), http.StatusOK)
+	second := apitest.Decode[entity.OCDLogList](t, apitest.Do(t, router, account, http.MethodGet, "/?limit=2&cursor="+first.Pagination.NextCursor, ""), http.StatusOK)
+	back := apitest.Decode[entity.OCDLogList](t, apitest.Do(t, router, account, http.MethodGet, "/?limit=2&cursor="+second.Pagination.PrevCursor, ""), http.StatusOK)
+	if len(back.Logs) != 2 || back.Logs[0].ID != ids[0] || back.Logs[1].ID != ids[1] {
+		t.Fatalf("expected prev_cursor to return the first page, got %+v", back.Logs)
+	}
+
+	for _, query := range []string{
+		"limit=0&cursor=",
+		"limit=501",
+		"limit=-1",
+		"cursor=&offset=2",
+		"cursor=not-a-cursor",
+		"cursor=&sort=anxiety_level",
+		"sort=-created_at&cursor=" + first.Pagination.NextCursor,
+	} {
+		t.Run(query, func(t *testing.T) {
+			apitest.ExpectStatus(t, apitest.Do(t, router, account, http.MethodGet, "/?"+query, ""), http.StatusBadRequest)
+		})
+	}
+}
+
+func TestGetLogStats(t *testing.T) {
+	router, account := newTestRouter(t)
+	empty := apitest.Decode[entity.OCDLogStats](t, apitest.Do(t, router, account, http.MethodGet, "/stats", ""), http.StatusOK)
+	if empty.Count != 0 || len(empty.AnxietyHistogram) != entity.MaxAnxietyLevel+1 {
+		t.Fatalf("expected empty stats with a full histogram, got %+v", empty)
+	}
+
+	created := createTestLogs(t, router, account,
+		`{"anxiety_level":2,"ruminate_minutes":5}`,
+		`{"anxiety_level":4,"ruminate_minutes":10}`,
+		`{"anxiety_level":9,"ruminate_minutes":15}`,
+		`{"anxiety_level":5,"ruminate_minutes":0}`,
+	)
+	stats := apitest.Decode[entity.OCDLogStats](t, apitest.Do(t, router, account, http.MethodGet, "/stats", ""), http.StatusOK)
+	if stats.Count != 4 || stats.AvgAnxietyLevel != 5 || stats.MedianAnxietyLevel != 4.5 || stats.MaxAnxietyLevel != 9 {
+		t.Fatalf("unexpected anxiety stats %+v", stats)
+	}
+	if stats.TotalRuminateMinutes != 30 || stats.AvgRuminateMinutes != 7.5 {
+		t.Fatalf("unexpected rumination stats %+v", stats)
+	}
+	if stats.AnxietyHistogram[4].Count != 1 || stats.AnxietyHistogram[9].Count != 1 || stats.AnxietyHistogram[0].Count != 0 {
+		t.Fatalf("unexpected histogram %+v", stats.AnxietyHistogram)
+	}
+
+	from := url.Values{"from": {created[1].CreatedAt.Format(time.RFC3339Nano)}, "anxiety_max": {"8"}}
+	stats = apitest.Decode[entity.OCDLogStats](t, apitest.Do(t, router, account, http.MethodGet, "/stats?"+from.Encode(), ""), http.StatusOK)
+	if stats.Count != 2 || stats.MedianAnxietyLevel != 4.5 || stats.TotalRuminateMinutes != 10 {
+		t.Fatalf("expected the filters to apply, got %+v", stats)
+	}
+	apitest.ExpectStatus(t, apitest.Do(t, router, account, http.MethodGet, "/stats?anxiety_min=11", ""), http.StatusBadRequest)
+}
+
+func TestGetLogSeries(t *testing.T) {
+	router, account := newTestRouter(t)
+	createTestLogs(t, router, account, `{"anxiety_level":3,"ruminate_minutes":5}`, `{"anxiety_level":6,"ruminate_minutes":10}`)
+	now := time.Now().UTC()
+	query := url.Values{"from": {now.AddDate(0, 0, -2).Format(time.RFC3339)}, "to": {now.Format(time.RFC3339)}}
+	series := apitest.Decode[entity.OCDLogSeries](t, apitest.Do(t, router, account, http.MethodGet, "/series?"+query.Encode(), ""), http.StatusOK)
+	if series.Bucket != entity.SeriesBucketDay || len(series.Points) != 3 {
+		t.Fatalf("expected three daily points, got %+v", series)
+	}
+	if series.Points[0].Count != 0 || series.Points[0].AvgAnxietyLevel != nil {
+		t.Fatalf("expected the first day to be empty, got %+v", series.Points[0])
+	}
+	today := series.Points[2]
+	if today.Count != 2 || *today.AvgAnxietyLevel != 4.5 || today.TotalRuminateMinutes != 15 {
+		t.Fatalf("expected today's logs to be aggregated, got %+v", today)
+	}
+	series = apitest.Decode[entity.OCDLogSeries](t, apitest.Do(t, router, account, http.MethodGet, "/series?bucket=month&anxiety_min=5", ""), http.StatusOK)
+	if last := series.Points[len(series.Points)-1]; last.Count != 1 || *last.AvgAnxietyLevel != 6 {
+		t.Fatalf("expected the filters to apply to the monthly series, got %+v", last)
+	}
+	apitest.ExpectStatus(t, apitest.Do(t, router, account, http.MethodGet, "/series?bucket=year", ""), http.StatusBadRequest)
+	apitest.ExpectStatus(t, apitest.Do(t, router, account, http.MethodGet, "/series?bucket=hour&from=2000-01-01T00:00:00Z", ""), http.StatusBadRequest)
+}
+
+// failingStreamRepository fails every export before any log is written
+type failingStreamRepository struct {
+	*memory.OCDLogRepository
+}
+
+func (repo failingStreamRepository) StreamLogs(context.Context, string, entity.OCDLogFilter, entity.OCDLogSort, func(entity.OCDLog) error) error {
+	return errors.New("connection reset")
+}
+
+func TestExportLogsCSV(t *testing.T) {
+	router, account := newTestRouter(t)
+	created := createTestLogs(t, router, account,
+		`{"anxiety_level":2,"ruminate_minutes":5,"notes":"=HYPERLINK(\"http://example.com\")"}`,
+		`{"anxiety_level":5,"notes":"fine, mostly"}`,
+	)
+	recorder := apitest.Do(t, router, account, http.MethodGet, "/export.csv?sort=-anxiety_level", "")
+	apitest.ExpectStatus(t, recorder, http.StatusOK)
+	if contentType := recorder.Header().Get("Content-Type"); !strings.HasPrefix(contentType, contentTypeCSV) {
+		t.Fatalf("expected a csv response, got %q", contentType)
+	}
+	records, err := csv.NewReader(recorder.Body).ReadAll()
+	if err != nil {
+		t.Fatalf("failed to parse the export: %v", err)
+	}
+	if len(records) != 3 || strings.Join(records[0], ",") != strings.Join(csvHeader, ",") {
+		t.Fatalf("expected a header and two rows, got %q", records)
+	}
+	if records[1][0] != created[1].ID.String() || records[1][4] != "5" || records[1][5] != "fine, mostly" {
+		t.Fatalf("unexpected first row %q", records[1])
+	}
+	if records[2][3] != "5" || records[2][5] != `'=HYPERLINK("http://example.com")` {
+		t.Fatalf("expected the formula to be escaped, got %q", records[2])
+	}
+
+	r := httptest.NewRequest(http.MethodGet, "/?anxiety_min=5", nil)
+	r.Header.Set("Accept", "text/csv, application/json;q=0.5")
+	recorder = apitest.DoRequest(t, router, account, r)
+	apitest.ExpectStatus(t, recorder, http.StatusOK)
+	if lines := strings.Split(strings.TrimSpace(recorder.Body.String()), "\n"); len(lines) != 2 || !strings.HasPrefix(lines[1], created[1].ID.String()) {
+		t.Fatalf("expected the listing to be negotiated as csv, got %q", recorder.Body.String())
+	}
+}
+
+func TestExportLogsReportsErrorsBeforeFirstFlush(t *testing.T) {
+	memoryDB := memory.NewDB()
+	account := apitest.CreateAccount(t, memory.NewAccountRepository(memoryDB), "patient")
+	router := NewRouter(NewHandler(context.Background(), failingStreamRepository{memory.NewOCDLogRepository(memoryDB)}))
+	recorder := apitest.Do(t, router, account, http.MethodGet, "/export.csv", "")
+	apitest.ExpectStatus(t, recorder, http.StatusInternalServerError)
+	if recorder.Header().Get("Content-Disposition") != "" {
+		t.Fatalf("expected no attachment for a failed export")
+	}
+}
+
+func TestImportLogsJSON(t *testing.T) {
+	router, account := newTestRouter(t)
+	body := `[
+		{"created_at":"2022-01-01T08:00:00Z","anxiety_level":3,"ruminate_minutes":5,"notes":"paper diary"},
+		{"created_at":"2022-01-02T08:00:00Z","anxiety_level":11},
+		{"created_at":"2999-01-01T08:00:00Z","anxiety_level":1},
+		{"created_at":"2022-01-03T08:00:00Z","updated_at":"2022-01-02T08:00:00Z","anxiety_level":1},
+		"not a log"
+	]`
+	report := apitest.Decode[entity.OCDLogImportReport](t, apitest.Do(t, router, account, http.MethodPost, "/import", body), http.StatusCreated)
+	if report.Accepted != 1 || report.Rejected != 4 || len(report.Rows) != 5 {
+		t.Fatalf("expected 1 accepted and 4 rejected rows, got %+v", report)
+	}
+	for _, row := range report.Rows[1:] {
+		if row.Status != entity.ImportRowStatusRejected || row.Error == "" || row.ID != nil {
+			t.Fatalf("expected row %d to be rejected with a reason, got %+v", row.Row, row)
+		}
+	}
+	ocdLog := apitest.Decode[entity.OCDLog](t, apitest.Do(t, router, account, http.MethodGet, "/"+report.Rows[0].ID.String(), ""), http.StatusOK)
+	if !ocdLog.CreatedAt.Equal(time.Date(2022, 1, 1, 8, 0, 0, 0, time.UTC)) || *ocdLog.Notes != "paper diary" {
+		t.Fatalf("expected the historical log to be imported, got %+v", ocdLog)
+	}
+
+	report = apitest.Decode[entity.OCDLogImportReport](t, apitest.Do(t, router, account, http.MethodPost, "/import", `[{"anxiety_level":-1}]`), http.StatusOK)
+	if report.Accepted != 0 || report.Rejected != 1 {
+		t.Fatalf("expected nothing to be imported, got %+v", report)
+	}
+	apitest.ExpectStatus(t, apitest.Do(t, router, account, http.MethodPost, "/import", `{"anxiety_level":1}`), http.StatusBadRequest)
+}
+
+func TestImportLogsCSV(t *testing.T) {
+	router, account := newTestRouter(t)
+	body := "Anxiety_Level,created_at,ruminate_minutes,unknown\n" +
+		"3,2022-01-01T08:00:00Z,5,ignored\n" +
+		"11,2022-01-02T08:00:00Z,5,\n" +
+		"3,yesterday,5,\n" +
+		"four,2022-01-03T08:00:00Z,,\n"
+	r := httptest.NewRequest(http.MethodPost, "/import", strings.NewReader(body))
+	r.Header.Set("Content-Type", contentTypeCSV+"; charset=utf-8")
+	report := apitest.Decode[entity.OCDLogImportReport](t, apitest.DoRequest(t, router, account, r), http.StatusCreated)
+	if report.Accepted != 1 || report.Rejected != 3 {
+		t.Fatalf("expected 1 accepted and 3 rejected rows, got %+v", report)
+	}
+	if !strings.HasPrefix(report.Rows[2].Error, "created_at") || !strings.HasPrefix(report.Rows[3].Error, "anxiety_level") {
+		t.Fatalf("expected the rejected columns to be named, got %+v", report.Rows)
+	}
+}
+
+func TestImportLogsRoundTripsExport(t *testing.T) {
+	router, account := newTestRouter(t)
+	createTestLogs(t, router, account, `{"anxiety_level":2,"ruminate_minutes":5,"notes":"=1+1"}`, `{"anxiety_level":5,"notes":"line\nbreak"}`)
+	export := apitest.Do(t, router, account, http.MethodGet, "/export.csv", "")
+	apitest.ExpectStatus(t, export, http.StatusOK)
+
+	other, otherAccount := newTestRouter(t)
+	r := httptest.NewRequest(http.MethodPost, "/import", strings.NewReader(export.Body.String()))
+	r.Header.Set("Content-Type", contentTypeCSV)
+	report := apitest.Decode[entity.OCDLogImportReport](t, apitest.DoRequest(t, other, otherAccount, r), http.StatusCreated)
+	if report.Accepted != 2 {
+		t.Fatalf("expected the export to be imported, got %+v", report)
+	}
+	list := apitest.Decode[entity.OCDLogList](t, apitest.Do(t, other, otherAccount, http.MethodGet, "/", ""), http.StatusOK)
+	if len(list.Logs) != 2 || *list.Logs[0].Notes != "=1+1" || *list.Logs[1].Notes != "line\nbreak" {
+		t.Fatalf("expected the notes to survive the round trip, got %+v", list.Logs)
+	}
+}
+
+func TestLogRevisionsAndRevert(t *testing.T) {
+	router, account := newTestRouter(t)
+	created := createTestLogs(t, router, account, `{"anxiety_level":4,"ruminate_minutes":10,"notes":"before"}`)
+	target := "/" + created[0].ID.String()
+
+	apitest.ExpectStatus(t, apitest.Do(t, router, account, http.MethodPatch, target, `{"anxiety_level":7}`), http.StatusNoContent)
+	apitest.ExpectStatus(t, apitest.Do(t, router, account, http.MethodPatch, target, `{"anxiety_level":7}`), http.StatusNoContent)
+	apitest.ExpectStatus(t, apitest.Do(t, router, account, http.MethodPatch, target, `{"notes":"after","ruminate_minutes":20}`), http.StatusNoContent)
+	revisions := apitest.Decode[entity.OCDLogRevisionList](t, apitest.Do(t, router, account, http.MethodGet, target+"/revisions", ""), http.StatusOK)
+	if len(revisions.Revisions) != 2 {
+		t.Fatalf("expected an update without changes to record no revision, got %+v", revisions.Revisions)
+	}
+	first, second := revisions.Revisions[0], revisions.Revisions[1]
+	if first.Revision != 1 || first.Old.AnxietyLevel != 4 || first.New.AnxietyLevel != 7 || first.ChangedBy != account.ID {
+		t.Fatalf("unexpected first revision %+v", first)
+	}
+	if second.Revision != 2 || *second.Old.Notes != "before" || *second.New.Notes != "after" || second.New.RuminateMinutes != 20 {
+		t.Fatalf("unexpected second revision %+v", second)
+	}
+
+	apitest.ExpectStatus(t, apitest.Do(t, router, account, http.MethodPost, target+"/revisions/1/revert", ""), http.StatusNoContent)
+	reverted := apitest.Decode[entity.OCDLog](t, apitest.Do(t, router, account, http.MethodGet, target, ""), http.StatusOK)
+	if *reverted.AnxietyLevel != 4 || *reverted.Notes != "after" || *reverted.RuminateMinutes != 20 {
+		t.Fatalf("expected only the fields of the revision to be reverted, got %+v", reverted)
+	}
+	revisions = apitest.Decode[entity.OCDLogRevisionList](t, apitest.Do(t, router, account, http.MethodGet, target+"/revisions", ""), http.StatusOK)
+	if len(revisions.Revisions) != 3 || revisions.Revisions[2].Old.AnxietyLevel != 7 || revisions.Revisions[2].New.AnxietyLevel != 4 {
+		t.Fatalf("expected the revert to be recorded as a revision, got %+v", revisions.Revisions)
+	}
+
+	apitest.ExpectStatus(t, apitest.Do(t, router, account, http.MethodPost, target+"/revisions/9/revert", ""), http.StatusNotFound)
+	apitest.ExpectStatus(t, apitest.Do(t, router, account, http.MethodPost, target+"/revisions/0/revert", ""), http.StatusBadRequest)
+	apitest.ExpectStatus(t, apitest.Do(t, router, account, http.MethodGet, "/"+uuid.New().String()+"/revisions", ""), http.StatusNotFound)
+}
+
+func TestLogTags(t *testing.T) {
+	router, account := newTestRouter(t)
+	created := createTestLogs(t, router, account,
+		`{"anxiety_level":2,"ruminate_minutes":5,"tags":["Work"," commute","work"]}`,
+		`{"anxiety_level":6,"ruminate_minutes":15,"tags":["work"]}`,
+		`{"anxiety_level":9}`,
+	)
+	if tags := created[0].Tags; len(tags) != 2 || tags[0] != "commute" || tags[1] != "work" {
+		t.Fatalf("expected normalized tags, got %v", tags)
+	}
+	apitest.ExpectStatus(t, apitest.Do(t, router, account, http.MethodPost, "/", `{"tags":["a;b"]}`), http.StatusBadRequest)
+
+	if levels := anxietyLevels(t, router, account, "/?tag=WORK"); fmt.Sprint(levels) != "[2 6]" {
+		t.Fatalf("expected the logs tagged work, got %v", levels)
+	}
+	if levels := anxietyLevels(t, router, account, "/?tag=work&tag=commute"); fmt.Sprint(levels) != "[2]" {
+		t.Fatalf("expected the logs carrying both tags, got %v", levels)
+	}
+	stats := apitest.Decode[entity.OCDLogStats](t, apitest.Do(t, router, account, http.MethodGet, "/stats", ""), http.StatusOK)
+	if len(stats.Tags) != 2 || stats.Tags[0].Tag != "work" || stats.Tags[0].Count != 2 || stats.Tags[0].AvgAnxietyLevel != 4 || stats.Tags[0].TotalRuminateMinutes != 20 {
+		t.Fatalf("unexpected tag stats %+v", stats.Tags)
+	}
+
+	target := "/" + created[2].ID.String()
+	apitest.ExpectStatus(t, apitest.Do(t, router, account, http.MethodPatch, target, `{"tags":["home"]}`), http.StatusNoContent)
+	revisions := apitest.Decode[entity.OCDLogRevisionList](t, apitest.Do(t, router, account, http.MethodGet, target+"/revisions", ""), http.StatusOK)
+	if len(revisions.Revisions) != 1 || len(revisions.Revisions[0].Old.Tags) != 0 || fmt.Sprint(revisions.Revisions[0].New.Tags) != "[home]" {
+		t.Fatalf("expected a tags-only update to record a revision, got %+v", revisions.Revisions)
+	}
+	apitest.ExpectStatus(t, apitest.Do(t, router, account, http.MethodPost, target+"/revisions/1/revert", ""), http.StatusNoContent)
+	reverted := apitest.Decode[entity.OCDLog](t, apitest.Do(t, router, account, http.MethodGet, target, ""), http.StatusOK)
+	if len(reverted.Tags) != 0 {
+		t.Fatalf("expected the revert to remove the tags, got %v", reverted.Tags)
+	}
+}
+
+func TestLogCompulsions(t *testing.T) {
+	router, account := newTestRouter(t)
+	created := createTestLogs(t, router, account,
+		`{"anxiety_level":5,"compulsion_type":"checking","urge_intensity":8,"resisted":true,"resisted_minutes":20}`,
+		`{"anxiety_level":6,"compulsion_type":"checking","urge_intensity":4,"resisted":false,"resisted_minutes":5}`,
+		`{"anxiety_level":3,"compulsion_type":"washing","urge_intensity":6,"resisted":true}`,
+		`{"anxiety_level":2}`,
+	)
+	apitest.ExpectStatus(t, apitest.Do(t, router, account, http.MethodPost, "/", `{"compulsion_type":"juggling"}`), http.StatusBadRequest)
+	apitest.ExpectStatus(t, apitest.Do(t, router, account, http.MethodPost, "/", `{"urge_intensity":11}`), http.StatusBadRequest)
+
+	if levels := anxietyLevels(t, router, account, "/?compulsion_type=checking&resisted=true"); fmt.Sprint(levels) != "[5]" {
+		t.Fatalf("expected the resisted checking log, got %v", levels)
+	}
+	stats := apitest.Decode[entity.OCDLogStats](t, apitest.Do(t, router, account, http.MethodGet, "/stats", ""), http.StatusOK)
+	if *stats.AvgUrgeIntensity != 6 || stats.ResistedCount != 2 || *stats.ResistanceRate != 2.0/3 || stats.TotalResistedMinutes != 25 {
+		t.Fatalf("unexpected compulsion stats %+v", stats)
+	}
+	if len(stats.Compulsions) != 2 || stats.Compulsions[0].CompulsionType != "checking" || stats.Compulsions[0].Count != 2 || *stats.Compulsions[0].ResistanceRate != 0.5 {
+		t.Fatalf("unexpected per compulsion type stats %+v", stats.Compulsions)
+	}
+
+	target := "/" + created[3].ID.String()
+	apitest.ExpectStatus(t, apitest.Do(t, router, account, http.MethodPatch, target, `{"compulsion_type":"counting","resisted":true}`), http.StatusNoContent)
+	revisions := apitest.Decode[entity.OCDLogRevisionList](t, apitest.Do(t, router, account, http.MethodGet, target+"/revisions", ""), http.StatusOK)
+	if len(revisions.Revisions) != 1 || revisions.Revisions[0].Old.CompulsionType != nil || *revisions.Revisions[0].New.CompulsionType != "counting" {
+		t.Fatalf("expected the compulsion to be recorded in a revision, got %+v", revisions.Revisions)
+	}
+	apitest.ExpectStatus(t, apitest.Do(t, router, account, http.MethodPost, target+"/revisions/1/revert", ""), http.StatusNoContent)
+	reverted := apitest.Decode[entity.OCDLog](t, apitest.Do(t, router, account, http.MethodGet, target, ""), http.StatusOK)
+	if reverted.CompulsionType != nil || reverted.Resisted != nil {
+		t.Fatalf("expected the revert to clear the compulsion fields, got %+v", reverted)
+	}
+}
+
+func TestTrash(t *testing.T) {
+	router, account := newTestRouter(t)
+	created := createTestLogs(t, router, account, `{"anxiety_level":1}`, `{"anxiety_level":2}`, `{"anxiety_level":3}`)
+	target := func(ocdLog entity.OCDLog, suffix string) string {
+		return "/" + ocdLog.ID.String() + suffix
+	}
+
+	apitest.ExpectStatus(t, apitest.Do(t, router, account, http.MethodDelete, target(created[0], ""), ""), http.StatusNoContent)
+	apitest.ExpectStatus(t, apitest.Do(t, router, account, http.MethodGet, target(created[0], ""), ""), http.StatusNotFound)
+	if got := anxietyLevels(t, router, account, "/"); fmt.Sprint(got) != "[2 3]" {
+		t.Fatalf("expected the deleted log to be hidden, got %v", got)
+	}
+	apitest.ExpectStatus(t, apitest.Do(t, router, account, http.MethodDelete, "/", ""), http.StatusNoContent)
+	trash := apitest.Decode[entity.OCDLogList](t, apitest.Do(t, router, account, http.MethodGet, "/trash", ""), http.StatusOK)
+	if len(trash.Logs) != 3 || *trash.Pagination.Total != 3 || trash.Logs[2].ID != created[0].ID || trash.Logs[2].DeletedAt == nil {
+		t.Fatalf("expected every log in the trash, most recently deleted first, got %+v", trash)
+	}
+
+	apitest.ExpectStatus(t, apitest.Do(t, router, account, http.MethodPost, target(created[0], "/restore"), ""), http.StatusNoContent)
+	apitest.ExpectStatus(t, apitest.Do(t, router, account, http.MethodPost, target(created[0], "/restore"), ""), http.StatusNotFound)
+	restored := apitest.Decode[entity.OCDLog](t, apitest.Do(t, router, account, http.MethodGet, target(created[0], ""), ""), http.StatusOK)
+	if restored.DeletedAt != nil || *restored.AnxietyLevel != 1 {
+		t.Fatalf("expected the log to be restored as it was, got %+v", restored)
+	}
+	apitest.ExpectStatus(t, apitest.Do(t, router, account, http.MethodGet, "/trash?cursor=", ""), http.StatusBadRequest)
+}
+
+func TestApplyBatch(t *testing.T) {
+	router, account := newTestRouter(t)
+	created := createTestLogs(t, router, account, `{"anxiety_level":2}`, `{"anxiety_level":5}`)
+	clientID := uuid.New()
+	body := fmt.Sprintf(`{"operations":[
+		{"op":"create","id":%q,"log":{"anxiety_level":1,"created_at":"2020-01-01T00:00:00Z","updated_at":"2020-01-02T00:00:00Z"}},
+		{"op":"update","id":%q,"log":{"anxiety_level":6}},
+		{"op":"delete","id":%q}
+	]}`, clientID, created[0].ID, created[1].ID)
+	resp := apitest.Decode[entity.OCDLogBatchResponse](t, apitest.Do(t, router, account, http.MethodPost, "/batch", body), http.StatusOK)
+	if !resp.Applied || len(resp.Results) != 3 || *resp.Results[0].ID != clientID {
+		t.Fatalf("expected the batch to be applied, got %+v", resp)
+	}
+	for _, result := range resp.Results {
+		if result.Status != entity.BatchResultApplied {
+			t.Fatalf("expected every operation to be applied, got %+v", result)
+		}
+	}
+	ocdLog := apitest.Decode[entity.OCDLog](t, apitest.Do(t, router, account, http.MethodGet, "/"+clientID.String(), ""), http.StatusOK)
+	if ocdLog.CreatedAt.Year() == 2020 || ocdLog.UpdatedAt != nil {
+		t.Fatalf("expected the server to set the timestamps of the created log, got %+v", ocdLog)
+	}
+	if got := anxietyLevels(t, router, account, "/?sort=anxiety_level"); fmt.Sprint(got) != "[1 6]" {
+		t.Fatalf("unexpected logs after the batch %v", got)
+	}
+
+	body = fmt.Sprintf(`{"operations":[{"op":"create","id":%q,"log":{"anxiety_level":1}}]}`, clientID)
+	apitest.ExpectStatus(t, apitest.Do(t, router, account, http.MethodPost, "/batch", body), http.StatusConflict)
+}
+
+func TestApplyBatchFailsOnMissingLogs(t *testing.T) {
+	memoryDB := memory.NewDB()
+	accountRepo := memory.NewAccountRepository(memoryDB)
+	account := apitest.CreateAccount(t, accountRepo, "patient")
+	other := apitest.CreateAccount(t, accountRepo, "other")
+	router := NewRouter(NewHandler(context.Background(), memory.NewOCDLogRepository(memoryDB)))
+	created := createTestLogs(t, router, account, `{"anxiety_level":6}`)
+	othersLog := createTestLogs(t, router, other, `{"anxiety_level":3}`)
+	trashed := createTestLogs(t, router, account, `{"anxiety_level":1}`)
+	apitest.ExpectStatus(t, apitest.Do(t, router, account, http.MethodDelete, "/"+trashed[0].ID.String(), ""), http.StatusNoContent)
+	for name, operation := range map[string]string{
+		"update missing":        fmt.Sprintf(`{"op":"update","id":%q,"log":{"anxiety_level":0}}`, uuid.New()),
+		"delete missing":        fmt.Sprintf(`{"op":"delete","id":%q}`, uuid.New()),
+		"delete other accounts": fmt.Sprintf(`{"op":"delete","id":%q}`, othersLog[0].ID),
+		"delete trashed":        fmt.Sprintf(`{"op":"delete","id":%q}`, trashed[0].ID),
+	} {
+		t.Run(name, func(t *testing.T) {
+			body := fmt.Sprintf(`{"operations":[{"op":"update","id":%q,"log":{"anxiety_level":0}},%s]}`, created[0].ID, operation)
+			resp := apitest.Decode[entity.OCDLogBatchResponse](t, apitest.Do(t, router, account, http.MethodPost, "/batch", body), http.StatusNotFound)
+			if resp.Applied || resp.Results[0].Status != entity.BatchResultRolledBack || resp.Results[1].Status != entity.BatchResultFailed {
+				t.Fatalf("expected the second operation to fail the batch, got %+v", resp)
+			}
+			if got := anxietyLevels(t, router, account, "/"); fmt.Sprint(got) != "[6]" {
+				t.Fatalf("expected the failed batch to be rolled back, got %v", got)
+			}
+		})
+	}
+	apitest.ExpectStatus(t, apitest.Do(t, router, other, http.MethodGet, "/"+othersLog[0].ID.String(), ""), http.StatusOK)
+}
+
+func TestApplyBatchRejectsInvalidBatches(t *testing.T) {
+	router, account := newTestRouter(t)
+	tooMany := `{"operations":[` + strings.Repeat(`{"op":"create","log":{}},`, entity.MaxBatchOperations) + `{"op":"create","log":{}}]}`
+	tooLarge := `{"operations":[{"op":"create","log":{"notes":"` + strings.Repeat("x", maxBatchBytes) + `"}}]}`
+	for name, body := range map[string]string{
+		"empty":       `{"operations":[]}`,
+		"too many":    tooMany,
+		"too large":   tooLarge,
+		"unknown op":  `{"operations":[{"op":"upsert","log":{}}]}`,
+		"missing id":  `{"operations":[{"op":"delete"}]}`,
+		"invalid log": `{"operations":[{"op":"create","log":{"anxiety_level":11}}]}`,
+		"not json":    `operations`,
+	} {
+		t.Run(name, func(t *testing.T) {
+			apitest.ExpectStatus(t, apitest.Do(t, router, account, http.MethodPost, "/batch", body), http.StatusBadRequest)
+		})
+	}
+	if got := anxietyLevels(t, router, account, "/"); len(got) != 0 {
+		t.Fatalf("expected nothing to be created, got %v", got)
+	}
+}
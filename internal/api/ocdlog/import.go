@@ -0,0 +1,193 @@
+package ocdlog
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/cecobask/ocdtracker-api/pkg/entity"
+	"github.com/google/uuid"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	maxImportRows  = 10000
+	maxImportBytes = 10 << 20
+)
+
+var (
+	errorTooManyImportRows = fmt.Errorf("import must not contain more than %d rows", maxImportRows)
+)
+
+// importRow is a parsed row of an import; err is set when the row could not be parsed
+type importRow struct {
+	ocdLog entity.OCDLog
+	err    error
+}
+
+// parseImportJSON reads a json array of logs, parsing every element on its own so that one bad row does not fail
+// the others
+func parseImportJSON(r io.Reader) ([]importRow, error) {
+	var elements []json.RawMessage
+	if err := json.NewDecoder(r).Decode(&elements); err != nil {
+		return nil, fmt.Errorf("body must be a json array of logs: %w", err)
+	}
+	if len(elements) > maxImportRows {
+		return nil, errorTooManyImportRows
+	}
+	rows := make([]importRow, len(elements))
+	for i, element := range elements {
+		rows[i].err = json.Unmarshal(element, &rows[i].ocdLog)
+	}
+	return rows, nil
+}
+
+// parseImportCSV reads logs from csv with a header row; columns are matched by name and unknown ones are ignored
+func parseImportCSV(r io.Reader) ([]importRow, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read csv header: %w", err)
+	}
+	columns := make(map[string]int, len(header))
+	for i, name := range header {
+		columns[strings.TrimSpace(strings.ToLower(name))] = i
+	}
+	var rows []importRow
+	for {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			return rows, nil
+		}
+		if len(rows) == maxImportRows {
+			return nil, errorTooManyImportRows
+		}
+		if err != nil {
+			var parseErr *csv.ParseError
+			if !errors.As(err, &parseErr) {
+				return nil, fmt.Errorf("failed to read csv: %w", err)
+			}
+			rows = append(rows, importRow{err: err})
+			continue
+		}
+		rows = append(rows, parseCSVRecord(record, columns))
+	}
+}
+
+func parseCSVRecord(record []string, columns map[string]int) importRow {
+	var (
+		row importRow
+		err error
+	)
+	cell := func(name string) string {
+		if i, ok := columns[name]; ok && i < len(record) {
+			return record[i]
+		}
+		return ""
+	}
+	if row.ocdLog.CreatedAt, err = parseCSVTime(cell("created_at")); err != nil {
+		return importRow{err: fmt.Errorf("created_at: %w", err)}
+	}
+	if row.ocdLog.UpdatedAt, err = parseCSVTime(cell("updated_at")); err != nil {
+		return importRow{err: fmt.Errorf("updated_at: %w", err)}
+	}
+	if row.ocdLog.RuminateMinutes, err = parseCSVInt(cell("ruminate_minutes")); err != nil {
+		return importRow{err: fmt.Errorf("ruminate_minutes: %w", err)}
+	}
+	if row.ocdLog.AnxietyLevel, err = parseCSVInt(cell("anxiety_level")); err != nil {
+		return importRow{err: fmt.Errorf("anxiety_level: %w", err)}
+	}
+	if notes := unescapeCSVFormula(cell("notes")); notes != "" {
+		row.ocdLog.Notes = &notes
+	}
+	row.ocdLog.Tags = parseCSVTags(unescapeCSVFormula(cell("tags")))
+	if compulsionType := strings.TrimSpace(cell("compulsion_type")); compulsionType != "" {
+		row.ocdLog.CompulsionType = &compulsionType
+	}
+	if row.ocdLog.UrgeIntensity, err = parseCSVInt(cell("urge_intensity")); err != nil {
+		return importRow{err: fmt.Errorf("urge_intensity: %w", err)}
+	}
+	if row.ocdLog.Resisted, err = parseCSVBool(cell("resisted")); err != nil {
+		return importRow{err: fmt.Errorf("resisted: %w", err)}
+	}
+	if row.ocdLog.ResistedMinutes, err = parseCSVInt(cell("resisted_minutes")); err != nil {
+		return importRow{err: fmt.Errorf("resisted_minutes: %w", err)}
+	}
+	return row
+}
+
+// buildImport validates the parsed rows, assigning new ids to the accepted ones
+func buildImport(rows []importRow) ([]entity.OCDLog, *entity.OCDLogImportReport) {
+	report := entity.OCDLogImportReport{
+		Rows: make([]entity.OCDLogImportRow, len(rows)),
+	}
+	ocdLogs := make([]entity.OCDLog, 0, len(rows))
+	for i, row := range rows {
+		report.Rows[i].Row = i + 1
+		err := row.err
+		if err == nil {
+			err = row.ocdLog.Validate()
+		}
+		if err != nil {
+			report.Rows[i].Status = entity.ImportRowStatusRejected
+			report.Rows[i].Error = err.Error()
+			report.Rejected++
+			continue
+		}
+		ocdLog := row.ocdLog
+		ocdLog.ID = uuid.New()
+		ocdLog.DeletedAt = nil // imports never go straight to the trash
+		ocdLogs = append(ocdLogs, ocdLog)
+		report.Rows[i].Status = entity.ImportRowStatusAccepted
+		report.Rows[i].ID = &ocdLog.ID
+		report.Accepted++
+	}
+	return ocdLogs, &report
+}
+
+func parseCSVTime(value string) (*time.Time, error) {
+	if value = strings.TrimSpace(value); value == "" {
+		return nil, nil
+	}
+	parsed, err := time.Parse(time.RFC3339Nano, value)
+	if err != nil {
+		return nil, fmt.Errorf("must be an RFC3339 timestamp")
+	}
+	return &parsed, nil
+}
+
+func parseCSVInt(value string) (*int, error) {
+	if value = strings.TrimSpace(value); value == "" {
+		return nil, nil
+	}
+	parsed, err := strconv.Atoi(value)
+	if err != nil {
+		return nil, fmt.Errorf("must be an integer")
+	}
+	return &parsed, nil
+}
+
+func parseCSVTags(value string) entity.TagNames {
+	var tags entity.TagNames
+	for _, name := range strings.Split(value, entity.TagNameSeparator) {
+		if name = strings.TrimSpace(name); name != "" {
+			tags = append(tags, name)
+		}
+	}
+	return tags
+}
+
+func parseCSVBool(value string) (*bool, error) {
+	if value = strings.TrimSpace(value); value == "" {
+		return nil, nil
+	}
+	parsed, err := strconv.ParseBool(value)
+	if err != nil {
+		return nil, fmt.Errorf("must be a boolean")
+	}
+	return &parsed, nil
+}
@@ -0,0 +1,138 @@
+package ocdlog
+
+import (
+	"fmt"
+	"github.com/cecobask/ocdtracker-api/pkg/entity"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// parseFilter builds a log filter from the query parameters of a listing request
+func parseFilter(query url.Values) (*entity.OCDLogFilter, error) {
+	var (
+		filter entity.OCDLogFilter
+		err    error
+	)
+	if filter.From, err = parseTimeParam(query, "from"); err != nil {
+		return nil, err
+	}
+	if filter.To, err = parseTimeParam(query, "to"); err != nil {
+		return nil, err
+	}
+	if filter.AnxietyMin, err = parseIntParam(query, "anxiety_min"); err != nil {
+		return nil, err
+	}
+	if filter.AnxietyMax, err = parseIntParam(query, "anxiety_max"); err != nil {
+		return nil, err
+	}
+	if filter.RuminateMin, err = parseIntParam(query, "ruminate_min"); err != nil {
+		return nil, err
+	}
+	if filter.RuminateMax, err = parseIntParam(query, "ruminate_max"); err != nil {
+		return nil, err
+	}
+	if filter.HasNotes, err = parseBoolParam(query, "has_notes"); err != nil {
+		return nil, err
+	}
+	if value := query.Get("compulsion_type"); value != "" {
+		filter.CompulsionType = &value
+	}
+	if filter.Resisted, err = parseBoolParam(query, "resisted"); err != nil {
+		return nil, err
+	}
+	filter.Tags = entity.TagNames(query["tag"])
+	if err = filter.Validate(); err != nil {
+		return nil, err
+	}
+	filter.Tags = filter.Tags.Normalized()
+	return &filter, nil
+}
+
+// parseSort builds a log sort from a comma separated list of fields, each optionally prefixed with - for descending order
+func parseSort(query url.Values) (entity.OCDLogSort, error) {
+	value := query.Get("sort")
+	if value == "" {
+		return entity.DefaultOCDLogSort, nil
+	}
+	var sort entity.OCDLogSort
+	for _, field := range strings.Split(value, ",") {
+		field = strings.TrimSpace(field)
+		sortField := entity.OCDLogSortField{Field: strings.TrimPrefix(field, "-")}
+		sortField.Descending = sortField.Field != field
+		sort = append(sort, sortField)
+	}
+	if err := sort.Validate(); err != nil {
+		return nil, fmt.Errorf("sort: %w", err)
+	}
+	return sort, nil
+}
+
+// parseSeriesQuery builds the bucket range of a series from the filter's from/to, defaulting to a span ending now; the
+// buckets follow the time zone of the account
+func parseSeriesQuery(query url.Values, filter *entity.OCDLogFilter, account *entity.Account) (*entity.OCDLogSeriesQuery, error) {
+	location := account.Location()
+	seriesQuery := entity.OCDLogSeriesQuery{
+		Bucket:   entity.SeriesBucket(query.Get("bucket")),
+		To:       time.Now().In(location),
+		Location: location,
+	}
+	if seriesQuery.Bucket == "" {
+		seriesQuery.Bucket = entity.SeriesBucketDay
+	}
+	if filter.To != nil {
+		seriesQuery.To = filter.To.In(location)
+	}
+	seriesQuery.From = seriesQuery.Bucket.DefaultSpan(seriesQuery.To)
+	if filter.From != nil {
+		seriesQuery.From = filter.From.In(location)
+	}
+	awakeOnly, err := parseBoolParam(query, "awake_only")
+	if err != nil {
+		return nil, err
+	}
+	if awakeOnly != nil && *awakeOnly && account.WakeTime != nil && account.SleepTime != nil {
+		seriesQuery.AwakeWindow = &entity.DailyWindow{Start: *account.WakeTime, End: *account.SleepTime}
+	}
+	if err = seriesQuery.Validate(); err != nil {
+		return nil, err
+	}
+	return &seriesQuery, nil
+}
+
+func parseTimeParam(query url.Values, key string) (*time.Time, error) {
+	value := query.Get(key)
+	if value == "" {
+		return nil, nil
+	}
+	parsed, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return nil, fmt.Errorf("%s: must be an RFC3339 timestamp", key)
+	}
+	return &parsed, nil
+}
+
+func parseIntParam(query url.Values, key string) (*int, error) {
+	value := query.Get(key)
+	if value == "" {
+		return nil, nil
+	}
+	parsed, err := strconv.Atoi(value)
+	if err != nil {
+		return nil, fmt.Errorf("%s: must be an integer", key)
+	}
+	return &parsed, nil
+}
+
+func parseBoolParam(query url.Values, key string) (*bool, error) {
+	value := query.Get(key)
+	if value == "" {
+		return nil, nil
+	}
+	parsed, err := strconv.ParseBool(value)
+	if err != nil {
+		return nil, fmt.Errorf("%s: must be a boolean", key)
+	}
+	return &parsed, nil
+}
@@ -0,0 +1,75 @@
+package ocdlog
+
+import (
+	"fmt"
+	"github.com/cecobask/ocdtracker-api/internal/api"
+	"github.com/cecobask/ocdtracker-api/internal/api/middleware"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/render"
+	"github.com/google/uuid"
+	"net/http"
+	"strconv"
+)
+
+// GetLogRevisions lists every recorded change of a log, oldest first
+func (h *handler) GetLogRevisions(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		api.BadRequestError(w, r, "invalid-id", err)
+		return
+	}
+	account, err := middleware.AccountFromContext(r.Context())
+	if err != nil {
+		api.InternalServerError(w, r, "invalid-account-ctx", err)
+		return
+	}
+	_, err = h.ocdLogRepo.GetLog(r.Context(), account.ID, id)
+	if err != nil {
+		api.HandleRetrievalError(w, r, err)
+		return
+	}
+	result, err := h.ocdLogRepo.GetLogRevisions(r.Context(), account.ID, id)
+	if err != nil {
+		api.InternalServerError(w, r, "database-error", err)
+		return
+	}
+	render.JSON(w, r, result)
+}
+
+// RevertLogRevision undoes a revision by setting the fields it changed back to their old values; the revert is
+// recorded as a new revision
+func (h *handler) RevertLogRevision(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		api.BadRequestError(w, r, "invalid-id", err)
+		return
+	}
+	revision, err := strconv.Atoi(chi.URLParam(r, "rev"))
+	if err != nil || revision < 1 {
+		api.BadRequestError(w, r, "invalid-revision", fmt.Errorf("revision must be a positive integer"))
+		return
+	}
+	account, err := middleware.AccountFromContext(r.Context())
+	if err != nil {
+		api.InternalServerError(w, r, "invalid-account-ctx", err)
+		return
+	}
+	_, err = h.ocdLogRepo.GetLog(r.Context(), account.ID, id)
+	if err != nil {
+		api.HandleRetrievalError(w, r, err)
+		return
+	}
+	result, err := h.ocdLogRepo.GetLogRevision(r.Context(), account.ID, id, revision)
+	if err != nil {
+		api.HandleRetrievalError(w, r, err)
+		return
+	}
+	if update := result.Reverted(); update != nil {
+		err = h.ocdLogRepo.UpdateLog(r.Context(), account.ID, id, update)
+		if err != nil {
+			api.InternalServerError(w, r, "database-error", err)
+			return
+		}
+	}
+	render.NoContent(w, r)
+}
@@ -11,10 +11,19 @@ func NewRouter(h *handler) http.Handler {
 	r.Post("/", h.CreateLog)
 	r.Get("/", h.GetAllLogs)
 	r.Delete("/", h.DeleteAllLogs)
+	r.Get("/export.csv", h.ExportLogs)
+	r.Post("/import", h.ImportLogs)
+	r.Post("/batch", h.ApplyBatch)
+	r.Get("/stats", h.GetLogStats)
+	r.Get("/series", h.GetLogSeries)
+	r.Get("/trash", h.GetTrashedLogs)
 	r.Route("/{id}", func(r chi.Router) {
 		r.Patch("/", h.UpdateLog)
 		r.Get("/", h.GetLog)
 		r.Delete("/", h.DeleteLog)
+		r.Post("/restore", h.RestoreLog)
+		r.Get("/revisions", h.GetLogRevisions)
+		r.Post("/revisions/{rev}/revert", h.RevertLogRevision)
 	})
 	return r
 }
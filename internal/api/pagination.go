@@ -0,0 +1,30 @@
+package api
+
+import (
+	"fmt"
+	"github.com/cecobask/ocdtracker-api/pkg/entity"
+	"net/http"
+	"strings"
+)
+
+// SetCursorLinkHeader advertises the neighbouring pages of a cursor page in the Link header (RFC 8288)
+func SetCursorLinkHeader(w http.ResponseWriter, r *http.Request, pagination entity.PaginationDetails) {
+	var links []string
+	if pagination.NextCursor != "" {
+		links = append(links, fmt.Sprintf(`<%s>; rel="next"`, cursorURL(r, pagination.NextCursor)))
+	}
+	if pagination.PrevCursor != "" {
+		links = append(links, fmt.Sprintf(`<%s>; rel="prev"`, cursorURL(r, pagination.PrevCursor)))
+	}
+	if len(links) > 0 {
+		w.Header().Set("Link", strings.Join(links, ", "))
+	}
+}
+
+func cursorURL(r *http.Request, cursor string) string {
+	u := *r.URL
+	query := u.Query()
+	query.Set("cursor", cursor)
+	u.RawQuery = query.Encode()
+	return u.RequestURI()
+}
@@ -0,0 +1,203 @@
+package patient
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/cecobask/ocdtracker-api/internal/api"
+	"github.com/cecobask/ocdtracker-api/internal/api/middleware"
+	"github.com/cecobask/ocdtracker-api/internal/db"
+	"github.com/cecobask/ocdtracker-api/pkg/entity"
+	"github.com/cecobask/ocdtracker-api/pkg/log"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/render"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+type handler struct {
+	ctx         context.Context
+	grantRepo   db.GrantRepository
+	accountRepo db.AccountRepository
+}
+
+type redeemRequest struct {
+	Code string `json:"code"`
+}
+
+func NewHandler(ctx context.Context, grantRepo db.GrantRepository, accountRepo db.AccountRepository) *handler {
+	return &handler{
+		ctx:         ctx,
+		grantRepo:   grantRepo,
+		accountRepo: accountRepo,
+	}
+}
+
+// GetPatients returns the accounts that currently share their data with the requesting clinician
+func (h *handler) GetPatients(w http.ResponseWriter, r *http.Request) {
+	clinician, err := middleware.AccountFromContext(r.Context())
+	if err != nil {
+		api.InternalServerError(w, r, "invalid-account-ctx", err)
+		return
+	}
+	result, err := h.grantRepo.GetPatients(r.Context(), clinician.ID)
+	if err != nil {
+		api.InternalServerError(w, r, "database-error", err)
+		return
+	}
+	render.JSON(w, r, result)
+}
+
+// GetInvitations returns the pending invitations addressed to the email of the requesting clinician
+func (h *handler) GetInvitations(w http.ResponseWriter, r *http.Request) {
+	clinician, err := middleware.AccountFromContext(r.Context())
+	if err != nil {
+		api.InternalServerError(w, r, "invalid-account-ctx", err)
+		return
+	}
+	result := &entity.PatientList{
+		Patients: make([]entity.Patient, 0),
+	}
+	if clinician.Email != nil {
+		result, err = h.grantRepo.GetInvitations(r.Context(), *clinician.Email)
+		if err != nil {
+			api.InternalServerError(w, r, "database-error", err)
+			return
+		}
+	}
+	render.JSON(w, r, result)
+}
+
+// AcceptInvitation accepts an invitation addressed to the email of the requesting clinician
+func (h *handler) AcceptInvitation(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		api.BadRequestError(w, r, "invalid-id", err)
+		return
+	}
+	clinician, err := middleware.AccountFromContext(r.Context())
+	if err != nil {
+		api.InternalServerError(w, r, "invalid-account-ctx", err)
+		return
+	}
+	grant, err := h.grantRepo.GetGrant(r.Context(), id)
+	if err != nil {
+		api.HandleRetrievalError(w, r, err)
+		return
+	}
+	// invitations of other clinicians are reported as missing, so that their ids cannot be probed
+	if grant.Email == nil || !addressedTo(*grant, clinician) {
+		api.NotFoundError(w, r, "database-error", sql.ErrNoRows)
+		return
+	}
+	h.accept(w, r, grant, clinician)
+}
+
+// RedeemInvitation accepts the invitation with the code that the patient passed on to the requesting clinician
+func (h *handler) RedeemInvitation(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		api.BadRequestError(w, r, "invalid-request-body", err)
+		return
+	}
+	var requestBody redeemRequest
+	err = json.Unmarshal(body, &requestBody)
+	if err != nil {
+		api.BadRequestError(w, r, "invalid-request-body", err)
+		return
+	}
+	code := strings.ToUpper(strings.TrimSpace(requestBody.Code))
+	if code == "" {
+		api.BadRequestError(w, r, "invalid-request-body", fmt.Errorf("code: cannot be blank"))
+		return
+	}
+	clinician, err := middleware.AccountFromContext(r.Context())
+	if err != nil {
+		api.InternalServerError(w, r, "invalid-account-ctx", err)
+		return
+	}
+	grant, err := h.grantRepo.GetGrantByCode(r.Context(), code)
+	if err != nil {
+		api.HandleRetrievalError(w, r, err)
+		return
+	}
+	if !addressedTo(*grant, clinician) {
+		api.ForbiddenError(w, r, "invitation-email-mismatch", fmt.Errorf("the invitation is addressed to another email"))
+		return
+	}
+	h.accept(w, r, grant, clinician)
+}
+
+func (h *handler) accept(w http.ResponseWriter, r *http.Request, grant *entity.Grant, clinician *entity.Account) {
+	if grant.AccountID == clinician.ID {
+		api.BadRequestError(w, r, "invalid-invitation", fmt.Errorf("an account cannot accept its own invitation"))
+		return
+	}
+	if !grant.Pending() || grant.Expired(time.Now()) {
+		api.ConflictError(w, r, "invitation-not-pending", fmt.Errorf("the invitation has already been accepted or has expired"))
+		return
+	}
+	err := h.grantRepo.AcceptGrant(r.Context(), grant.ID, clinician.ID)
+	if errors.Is(err, sql.ErrNoRows) {
+		api.ConflictError(w, r, "invitation-not-pending", fmt.Errorf("the invitation has already been accepted or has expired"))
+		return
+	}
+	if err != nil {
+		api.InternalServerError(w, r, "database-error", err)
+		return
+	}
+	render.NoContent(w, r)
+}
+
+// RequireGrant only lets requests through if the clinician has an active grant with the scope from the patient in the
+// url. It then puts the patient in place of the clinician in the context, so that the handlers of the patient's own
+// routes serve the patient's data
+func (h *handler) RequireGrant(scope string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		handlerFn := func(w http.ResponseWriter, r *http.Request) {
+			clinician, err := middleware.AccountFromContext(r.Context())
+			if err != nil {
+				api.InternalServerError(w, r, "invalid-account-ctx", err)
+				return
+			}
+			patientID := chi.URLParam(r, "accountID")
+			grant, err := h.grantRepo.GetActiveGrant(r.Context(), patientID, clinician.ID)
+			if errors.Is(err, sql.ErrNoRows) {
+				api.NotFoundError(w, r, "grant-not-found", err)
+				return
+			}
+			if err != nil {
+				api.InternalServerError(w, r, "database-error", err)
+				return
+			}
+			if !grant.Scopes.Contains(scope) {
+				api.ForbiddenError(w, r, "missing-grant-scope", fmt.Errorf("the grant does not include %s", scope))
+				return
+			}
+			patient, err := h.accountRepo.GetAccount(r.Context(), patientID)
+			if err != nil {
+				api.HandleRetrievalError(w, r, err)
+				return
+			}
+			logger := log.LoggerFromContext(r.Context()).With(zap.String("patient", patientID))
+			ctx := middleware.ContextWithAccount(log.ContextWithLogger(r.Context(), logger), patient)
+			next.ServeHTTP(w, r.WithContext(ctx))
+		}
+		return http.HandlerFunc(handlerFn)
+	}
+}
+
+// addressedTo reports whether the clinician may accept the invitation; invitations without an email are open to anyone
+// with the code
+func addressedTo(grant entity.Grant, clinician *entity.Account) bool {
+	if grant.Email == nil {
+		return true
+	}
+	return clinician.Email != nil && strings.EqualFold(*grant.Email, *clinician.Email)
+}
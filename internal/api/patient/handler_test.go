@@ -0,0 +1,127 @@
+package patient
+
+import (
+	"context"
+	"github.com/cecobask/ocdtracker-api/internal/api/apitest"
+	"github.com/cecobask/ocdtracker-api/internal/api/ocdlog"
+	questionnaireAPI "github.com/cecobask/ocdtracker-api/internal/api/questionnaire"
+	"github.com/cecobask/ocdtracker-api/internal/db/memory"
+	"github.com/cecobask/ocdtracker-api/internal/questionnaire"
+	"github.com/cecobask/ocdtracker-api/pkg/entity"
+	"net/http"
+	"testing"
+	"time"
+)
+
+type fixture struct {
+	router     http.Handler
+	grantRepo  *memory.GrantRepository
+	ocdLogRepo *memory.OCDLogRepository
+	patient    *entity.Account
+	clinician  *entity.Account
+	stranger   *entity.Account
+}
+
+func newFixture(t *testing.T) *fixture {
+	registry, err := questionnaire.Load()
+	if err != nil {
+		t.Fatalf("failed to load questionnaires: %v", err)
+	}
+	memoryDB := memory.NewDB()
+	accountRepo := memory.NewAccountRepository(memoryDB)
+	f := &fixture{
+		grantRepo:  memory.NewGrantRepository(memoryDB),
+		ocdLogRepo: memory.NewOCDLogRepository(memoryDB),
+		patient:    apitest.CreateAccount(t, accountRepo, "patient"),
+		clinician:  apitest.CreateAccount(t, accountRepo, "clinician"),
+		stranger:   apitest.CreateAccount(t, accountRepo, "stranger"),
+	}
+	f.router = NewRouter(NewHandler(context.Background(), f.grantRepo, accountRepo), ocdlog.NewHandler(context.Background(), f.ocdLogRepo),
+		questionnaireAPI.NewHandler(context.Background(), registry, memory.NewQuestionnaireResponseRepository(memoryDB)))
+	return f
+}
+
+func (f *fixture) invite(t *testing.T, email *string, code string, expiresAt *time.Time, scopes ...string) *entity.Grant {
+	t.Helper()
+	grant := &entity.Grant{Email: email, Code: &code, Scopes: scopes, ExpiresAt: expiresAt}
+	if err := f.grantRepo.CreateGrant(context.Background(), f.patient.ID, grant); err != nil {
+		t.Fatalf("failed to create grant: %v", err)
+	}
+	return grant
+}
+
+func TestInvitationAddressedToEmail(t *testing.T) {
+	f := newFixture(t)
+	anxietyLevel := 7
+	if err := f.ocdLogRepo.CreateLog(context.Background(), f.patient.ID, &entity.OCDLog{AnxietyLevel: &anxietyLevel}); err != nil {
+		t.Fatalf("failed to create log: %v", err)
+	}
+	grant := f.invite(t, f.clinician.Email, "ADDRESSED", nil, entity.GrantScopeLogs)
+
+	invitations := apitest.Decode[entity.PatientList](t, apitest.Do(t, f.router, f.clinician, http.MethodGet, "/invitations", ""), http.StatusOK)
+	if len(invitations.Patients) != 1 || invitations.Patients[0].GrantID != grant.ID || *invitations.Patients[0].Email != *f.patient.Email {
+		t.Fatalf("expected the invitation addressed to the clinician, got %+v", invitations.Patients)
+	}
+	invitations = apitest.Decode[entity.PatientList](t, apitest.Do(t, f.router, f.stranger, http.MethodGet, "/invitations", ""), http.StatusOK)
+	if len(invitations.Patients) != 0 {
+		t.Fatalf("expected no invitations for another email, got %+v", invitations.Patients)
+	}
+
+	// invitations of other clinicians look missing, and their codes do not work for anyone else
+	apitest.ExpectStatus(t, apitest.Do(t, f.router, f.stranger, http.MethodPost, "/invitations/"+grant.ID.String()+"/accept", ""), http.StatusNotFound)
+	apitest.ExpectStatus(t, apitest.Do(t, f.router, f.stranger, http.MethodPost, "/invitations/redeem", `{"code":"ADDRESSED"}`), http.StatusForbidden)
+	apitest.ExpectStatus(t, apitest.Do(t, f.router, f.clinician, http.MethodGet, "/patient/ocdlog", ""), http.StatusNotFound)
+
+	apitest.ExpectStatus(t, apitest.Do(t, f.router, f.clinician, http.MethodPost, "/invitations/"+grant.ID.String()+"/accept", ""), http.StatusNoContent)
+	apitest.ExpectStatus(t, apitest.Do(t, f.router, f.clinician, http.MethodPost, "/invitations/"+grant.ID.String()+"/accept", ""), http.StatusConflict)
+	patients := apitest.Decode[entity.PatientList](t, apitest.Do(t, f.router, f.clinician, http.MethodGet, "/", ""), http.StatusOK)
+	if len(patients.Patients) != 1 || patients.Patients[0].AccountID != f.patient.ID || patients.Patients[0].AcceptedAt == nil {
+		t.Fatalf("expected the patient to be listed, got %+v", patients.Patients)
+	}
+	accepted, err := f.grantRepo.GetGrant(context.Background(), grant.ID)
+	if err != nil || accepted.Code != nil || *accepted.ClinicianID != f.clinician.ID {
+		t.Fatalf("expected the code to be cleared once accepted, got %+v (%v)", accepted, err)
+	}
+
+	logs := apitest.Decode[entity.OCDLogList](t, apitest.Do(t, f.router, f.clinician, http.MethodGet, "/patient/ocdlog", ""), http.StatusOK)
+	if len(logs.Logs) != 1 || *logs.Logs[0].AnxietyLevel != anxietyLevel {
+		t.Fatalf("expected the logs of the patient, got %+v", logs.Logs)
+	}
+	apitest.ExpectStatus(t, apitest.Do(t, f.router, f.clinician, http.MethodGet, "/patient/ocdlog/stats", ""), http.StatusForbidden)
+	apitest.ExpectStatus(t, apitest.Do(t, f.router, f.clinician, http.MethodGet, "/patient/questionnaire/ybocs/response", ""), http.StatusForbidden)
+	apitest.ExpectStatus(t, apitest.Do(t, f.router, f.stranger, http.MethodGet, "/patient/ocdlog", ""), http.StatusNotFound)
+
+	if err = f.grantRepo.DeleteGrant(context.Background(), f.patient.ID, grant.ID); err != nil {
+		t.Fatalf("failed to revoke grant: %v", err)
+	}
+	apitest.ExpectStatus(t, apitest.Do(t, f.router, f.clinician, http.MethodGet, "/patient/ocdlog", ""), http.StatusNotFound)
+}
+
+func TestRedeemInvitation(t *testing.T) {
+	f := newFixture(t)
+	open := f.invite(t, nil, "OPENCODE", nil, entity.GrantScopeStats)
+	past := time.Now().Add(-time.Hour)
+	f.invite(t, nil, "EXPIRED", &past, entity.GrantScopeLogs)
+
+	apitest.ExpectStatus(t, apitest.Do(t, f.router, f.patient, http.MethodPost, "/invitations/redeem", `{"code":"OPENCODE"}`), http.StatusBadRequest)
+	apitest.ExpectStatus(t, apitest.Do(t, f.router, f.clinician, http.MethodPost, "/invitations/redeem", `{"code":" "}`), http.StatusBadRequest)
+	apitest.ExpectStatus(t, apitest.Do(t, f.router, f.clinician, http.MethodPost, "/invitations/redeem", `{"code":"UNKNOWN"}`), http.StatusNotFound)
+	apitest.ExpectStatus(t, apitest.Do(t, f.router, f.clinician, http.MethodPost, "/invitations/redeem", `{"code":"EXPIRED"}`), http.StatusConflict)
+
+	// codes are matched regardless of case and surrounding whitespace
+	apitest.ExpectStatus(t, apitest.Do(t, f.router, f.stranger, http.MethodPost, "/invitations/redeem", `{"code":" opencode "}`), http.StatusNoContent)
+	apitest.ExpectStatus(t, apitest.Do(t, f.router, f.stranger, http.MethodGet, "/patient/ocdlog/stats", ""), http.StatusOK)
+	apitest.ExpectStatus(t, apitest.Do(t, f.router, f.stranger, http.MethodGet, "/patient/ocdlog", ""), http.StatusForbidden)
+
+	// accepting a new invitation replaces the earlier grant between the same accounts
+	replacement := f.invite(t, nil, "REPLACEMENT", nil, entity.GrantScopeLogs)
+	apitest.ExpectStatus(t, apitest.Do(t, f.router, f.stranger, http.MethodPost, "/invitations/redeem", `{"code":"REPLACEMENT"}`), http.StatusNoContent)
+	if _, err := f.grantRepo.GetGrant(context.Background(), open.ID); err == nil {
+		t.Fatalf("expected the earlier grant to be replaced")
+	}
+	patients := apitest.Decode[entity.PatientList](t, apitest.Do(t, f.router, f.stranger, http.MethodGet, "/", ""), http.StatusOK)
+	if len(patients.Patients) != 1 || patients.Patients[0].GrantID != replacement.ID {
+		t.Fatalf("expected only the replacement grant, got %+v", patients.Patients)
+	}
+	apitest.ExpectStatus(t, apitest.Do(t, f.router, f.stranger, http.MethodGet, "/patient/ocdlog", ""), http.StatusOK)
+}
@@ -0,0 +1,53 @@
+package patient
+
+import (
+	"github.com/cecobask/ocdtracker-api/pkg/entity"
+	"github.com/go-chi/chi/v5"
+	"net/http"
+)
+
+// ocdLogReader is the read-only part of the ocd log handler
+type ocdLogReader interface {
+	GetAllLogs(w http.ResponseWriter, r *http.Request)
+	ExportLogs(w http.ResponseWriter, r *http.Request)
+	GetLog(w http.ResponseWriter, r *http.Request)
+	GetLogStats(w http.ResponseWriter, r *http.Request)
+	GetLogSeries(w http.ResponseWriter, r *http.Request)
+}
+
+// responseReader is the read-only part of the questionnaire handler
+type responseReader interface {
+	GetAllResponses(w http.ResponseWriter, r *http.Request)
+	GetResponse(w http.ResponseWriter, r *http.Request)
+}
+
+// NewRouter creates all routes associated with the patients of a clinician; the data of a patient is served read-only
+// by the handlers of the patient's own routes
+func NewRouter(h *handler, ocdLogs ocdLogReader, responses responseReader) http.Handler {
+	r := chi.NewRouter()
+	r.Get("/", h.GetPatients)
+	r.Route("/invitations", func(r chi.Router) {
+		r.Get("/", h.GetInvitations)
+		r.Post("/redeem", h.RedeemInvitation)
+		r.Post("/{id}/accept", h.AcceptInvitation)
+	})
+	r.Route("/{accountID}", func(r chi.Router) {
+		r.Group(func(r chi.Router) {
+			r.Use(h.RequireGrant(entity.GrantScopeLogs))
+			r.Get("/ocdlog", ocdLogs.GetAllLogs)
+			r.Get("/ocdlog/export.csv", ocdLogs.ExportLogs)
+			r.Get("/ocdlog/{id}", ocdLogs.GetLog)
+		})
+		r.Group(func(r chi.Router) {
+			r.Use(h.RequireGrant(entity.GrantScopeStats))
+			r.Get("/ocdlog/stats", ocdLogs.GetLogStats)
+			r.Get("/ocdlog/series", ocdLogs.GetLogSeries)
+		})
+		r.Group(func(r chi.Router) {
+			r.Use(h.RequireGrant(entity.GrantScopeQuestionnaires))
+			r.Get("/questionnaire/{id}/response", responses.GetAllResponses)
+			r.Get("/questionnaire/{id}/response/{responseID}", responses.GetResponse)
+		})
+	})
+	return r
+}
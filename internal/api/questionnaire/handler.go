@@ -0,0 +1,152 @@
+package questionnaire
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"github.com/cecobask/ocdtracker-api/internal/api"
+	"github.com/cecobask/ocdtracker-api/internal/api/middleware"
+	"github.com/cecobask/ocdtracker-api/internal/db"
+	"github.com/cecobask/ocdtracker-api/internal/questionnaire"
+	"github.com/cecobask/ocdtracker-api/pkg/entity"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/render"
+	"github.com/google/uuid"
+	"io"
+	"net/http"
+)
+
+type handler struct {
+	ctx          context.Context
+	registry     *questionnaire.Registry
+	responseRepo db.QuestionnaireResponseRepository
+}
+
+type responseRequest struct {
+	Answers entity.QuestionnaireAnswers `json:"answers"`
+}
+
+func NewHandler(ctx context.Context, registry *questionnaire.Registry, responseRepo db.QuestionnaireResponseRepository) *handler {
+	return &handler{
+		ctx:          ctx,
+		registry:     registry,
+		responseRepo: responseRepo,
+	}
+}
+
+func (h *handler) GetAllQuestionnaires(w http.ResponseWriter, r *http.Request) {
+	render.JSON(w, r, h.registry.GetAll())
+}
+
+func (h *handler) GetQuestionnaire(w http.ResponseWriter, r *http.Request) {
+	result := h.questionnaireFromURL(w, r)
+	if result == nil {
+		return
+	}
+	render.JSON(w, r, result)
+}
+
+// CreateResponse scores a completed questionnaire and stores it in the history of the account
+func (h *handler) CreateResponse(w http.ResponseWriter, r *http.Request) {
+	definition := h.questionnaireFromURL(w, r)
+	if definition == nil {
+		return
+	}
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		api.BadRequestError(w, r, "invalid-request-body", err)
+		return
+	}
+	var requestBody responseRequest
+	err = json.Unmarshal(body, &requestBody)
+	if err != nil {
+		api.BadRequestError(w, r, "invalid-request-body", err)
+		return
+	}
+	response, err := questionnaire.Score(definition, requestBody.Answers)
+	if err != nil {
+		api.BadRequestError(w, r, "invalid-answers", err)
+		return
+	}
+	account, err := middleware.AccountFromContext(r.Context())
+	if err != nil {
+		api.InternalServerError(w, r, "invalid-account-ctx", err)
+		return
+	}
+	err = h.responseRepo.CreateResponse(r.Context(), account.ID, response)
+	if err != nil {
+		api.InternalServerError(w, r, "database-error", err)
+		return
+	}
+	result, err := h.responseRepo.GetResponse(r.Context(), account.ID, response.ID)
+	if err != nil {
+		api.HandleRetrievalError(w, r, err)
+		return
+	}
+	render.Status(r, http.StatusCreated)
+	render.JSON(w, r, result)
+}
+
+// GetAllResponses returns the score history of a questionnaire, most recent first
+func (h *handler) GetAllResponses(w http.ResponseWriter, r *http.Request) {
+	definition := h.questionnaireFromURL(w, r)
+	if definition == nil {
+		return
+	}
+	account, err := middleware.AccountFromContext(r.Context())
+	if err != nil {
+		api.InternalServerError(w, r, "invalid-account-ctx", err)
+		return
+	}
+	pagination := middleware.PaginationFromContext(r.Context())
+	if pagination.CursorMode {
+		api.BadRequestError(w, r, "invalid-pagination", fmt.Errorf("questionnaire responses only support offset pagination"))
+		return
+	}
+	result, err := h.responseRepo.GetAllResponses(r.Context(), account.ID, definition.ID, pagination.Limit, *pagination.Offset)
+	if err != nil {
+		api.InternalServerError(w, r, "database-error", err)
+		return
+	}
+	render.JSON(w, r, result)
+}
+
+func (h *handler) GetResponse(w http.ResponseWriter, r *http.Request) {
+	definition := h.questionnaireFromURL(w, r)
+	if definition == nil {
+		return
+	}
+	id, err := uuid.Parse(chi.URLParam(r, "responseID"))
+	if err != nil {
+		api.BadRequestError(w, r, "invalid-id", err)
+		return
+	}
+	account, err := middleware.AccountFromContext(r.Context())
+	if err != nil {
+		api.InternalServerError(w, r, "invalid-account-ctx", err)
+		return
+	}
+	result, err := h.responseRepo.GetResponse(r.Context(), account.ID, id)
+	if err != nil {
+		api.HandleRetrievalError(w, r, err)
+		return
+	}
+	if result.QuestionnaireID != definition.ID {
+		api.HandleRetrievalError(w, r, sql.ErrNoRows)
+		return
+	}
+	render.JSON(w, r, result)
+}
+
+// questionnaireFromURL looks up the questionnaire of the id url param; it writes a not found response and returns nil
+// if there is no such questionnaire
+func (h *handler) questionnaireFromURL(w http.ResponseWriter, r *http.Request) *entity.Questionnaire {
+	id := chi.URLParam(r, "id")
+	definition, ok := h.registry.Get(id)
+	if !ok {
+		api.NotFoundError(w, r, "unknown-questionnaire", fmt.Errorf("questionnaire %s does not exist", id))
+		return nil
+	}
+	return definition
+}
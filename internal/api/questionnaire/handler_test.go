@@ -0,0 +1,76 @@
+package questionnaire
+
+import (
+	"context"
+	"fmt"
+	"github.com/cecobask/ocdtracker-api/internal/api/apitest"
+	"github.com/cecobask/ocdtracker-api/internal/db/memory"
+	"github.com/cecobask/ocdtracker-api/internal/questionnaire"
+	"github.com/cecobask/ocdtracker-api/pkg/entity"
+	"github.com/google/uuid"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func newTestRouter(t *testing.T) (http.Handler, *entity.Account) {
+	registry, err := questionnaire.Load()
+	if err != nil {
+		t.Fatalf("failed to load questionnaires: %v", err)
+	}
+	memoryDB := memory.NewDB()
+	account := apitest.CreateAccount(t, memory.NewAccountRepository(memoryDB), "patient")
+	return NewRouter(NewHandler(context.Background(), registry, memory.NewQuestionnaireResponseRepository(memoryDB))), account
+}
+
+// ybocsAnswers answers every Y-BOCS item with the same value
+func ybocsAnswers(value int) string {
+	answers := make([]string, 0, 10)
+	for item := 1; item <= 10; item++ {
+		answers = append(answers, fmt.Sprintf(`{"item":%d,"value":%d}`, item, value))
+	}
+	return `{"answers":[` + strings.Join(answers, ",") + `]}`
+}
+
+func TestGetQuestionnaires(t *testing.T) {
+	router, account := newTestRouter(t)
+	questionnaireList := apitest.Decode[entity.QuestionnaireList](t, apitest.Do(t, router, account, http.MethodGet, "/", ""), http.StatusOK)
+	if len(questionnaireList.Questionnaires) != 2 {
+		t.Fatalf("expected the bundled questionnaires, got %+v", questionnaireList)
+	}
+	ybocs := apitest.Decode[entity.Questionnaire](t, apitest.Do(t, router, account, http.MethodGet, "/ybocs", ""), http.StatusOK)
+	if ybocs.ID != "ybocs" || len(ybocs.Items) != 10 || len(ybocs.SeverityBands) == 0 {
+		t.Fatalf("unexpected questionnaire %+v", ybocs)
+	}
+	apitest.ExpectStatus(t, apitest.Do(t, router, account, http.MethodGet, "/unknown", ""), http.StatusNotFound)
+}
+
+func TestResponses(t *testing.T) {
+	router, account := newTestRouter(t)
+	first := apitest.Decode[entity.QuestionnaireResponse](t, apitest.Do(t, router, account, http.MethodPost, "/ybocs/response", ybocsAnswers(3)), http.StatusCreated)
+	if first.ID == uuid.Nil || first.TotalScore != 30 || *first.Severity != "severe" || first.SubscaleScores["obsessions"] != 15 || first.CreatedAt == nil {
+		t.Fatalf("expected the scored response, got %+v", first)
+	}
+	apitest.Decode[entity.QuestionnaireResponse](t, apitest.Do(t, router, account, http.MethodPost, "/ybocs/response", ybocsAnswers(1)), http.StatusCreated)
+	apitest.ExpectStatus(t, apitest.Do(t, router, account, http.MethodPost, "/ybocs/response", ybocsAnswers(5)), http.StatusBadRequest)
+	apitest.ExpectStatus(t, apitest.Do(t, router, account, http.MethodPost, "/ybocs/response", `{"answers":[{"item":1,"value":1}]}`), http.StatusBadRequest)
+	apitest.ExpectStatus(t, apitest.Do(t, router, account, http.MethodPost, "/ybocs/response", `{"answers":`), http.StatusBadRequest)
+	apitest.ExpectStatus(t, apitest.Do(t, router, account, http.MethodPost, "/unknown/response", ybocsAnswers(1)), http.StatusNotFound)
+
+	responseList := apitest.Decode[entity.QuestionnaireResponseList](t, apitest.Do(t, router, account, http.MethodGet, "/ybocs/response?limit=1", ""), http.StatusOK)
+	if len(responseList.Responses) != 1 || *responseList.Pagination.Total != 2 {
+		t.Fatalf("expected a page of the score history, got %+v", responseList)
+	}
+	responseList = apitest.Decode[entity.QuestionnaireResponseList](t, apitest.Do(t, router, account, http.MethodGet, "/ocir/response", ""), http.StatusOK)
+	if len(responseList.Responses) != 0 {
+		t.Fatalf("expected no responses to another questionnaire, got %+v", responseList)
+	}
+
+	response := apitest.Decode[entity.QuestionnaireResponse](t, apitest.Do(t, router, account, http.MethodGet, "/ybocs/response/"+first.ID.String(), ""), http.StatusOK)
+	if response.ID != first.ID || len(response.Answers) != 10 {
+		t.Fatalf("unexpected response %+v", response)
+	}
+	apitest.ExpectStatus(t, apitest.Do(t, router, account, http.MethodGet, "/ocir/response/"+first.ID.String(), ""), http.StatusNotFound)
+	apitest.ExpectStatus(t, apitest.Do(t, router, &entity.Account{ID: "other"}, http.MethodGet, "/ybocs/response/"+first.ID.String(), ""), http.StatusNotFound)
+	apitest.ExpectStatus(t, apitest.Do(t, router, account, http.MethodGet, "/ybocs/response/nope", ""), http.StatusBadRequest)
+}
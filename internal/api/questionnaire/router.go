@@ -0,0 +1,21 @@
+package questionnaire
+
+import (
+	"github.com/go-chi/chi/v5"
+	"net/http"
+)
+
+// NewRouter creates all routes associated with questionnaires
+func NewRouter(h *handler) http.Handler {
+	r := chi.NewRouter()
+	r.Get("/", h.GetAllQuestionnaires)
+	r.Route("/{id}", func(r chi.Router) {
+		r.Get("/", h.GetQuestionnaire)
+		r.Route("/response", func(r chi.Router) {
+			r.Post("/", h.CreateResponse)
+			r.Get("/", h.GetAllResponses)
+			r.Get("/{responseID}", h.GetResponse)
+		})
+	})
+	return r
+}
@@ -0,0 +1,162 @@
+package tag
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/cecobask/ocdtracker-api/internal/api"
+	"github.com/cecobask/ocdtracker-api/internal/api/middleware"
+	"github.com/cecobask/ocdtracker-api/internal/db"
+	"github.com/cecobask/ocdtracker-api/pkg/entity"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/render"
+	"github.com/google/uuid"
+	"io"
+	"net/http"
+)
+
+type handler struct {
+	ctx     context.Context
+	tagRepo db.TagRepository
+}
+
+func NewHandler(ctx context.Context, tagRepo db.TagRepository) *handler {
+	return &handler{
+		ctx:     ctx,
+		tagRepo: tagRepo,
+	}
+}
+
+func (h *handler) GetAllTags(w http.ResponseWriter, r *http.Request) {
+	account, err := middleware.AccountFromContext(r.Context())
+	if err != nil {
+		api.InternalServerError(w, r, "invalid-account-ctx", err)
+		return
+	}
+	result, err := h.tagRepo.GetAllTags(r.Context(), account.ID)
+	if err != nil {
+		api.InternalServerError(w, r, "database-error", err)
+		return
+	}
+	render.JSON(w, r, result)
+}
+
+func (h *handler) CreateTag(w http.ResponseWriter, r *http.Request) {
+	requestBody := processRequestBody(w, r)
+	if requestBody == nil {
+		return
+	}
+	account, err := middleware.AccountFromContext(r.Context())
+	if err != nil {
+		api.InternalServerError(w, r, "invalid-account-ctx", err)
+		return
+	}
+	err = h.tagRepo.CreateTag(r.Context(), account.ID, requestBody)
+	if err != nil {
+		handleWriteError(w, r, err)
+		return
+	}
+	result, err := h.tagRepo.GetTag(r.Context(), account.ID, requestBody.ID)
+	if err != nil {
+		api.HandleRetrievalError(w, r, err)
+		return
+	}
+	render.Status(r, http.StatusCreated)
+	render.JSON(w, r, result)
+}
+
+func (h *handler) GetTag(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		api.BadRequestError(w, r, "invalid-id", err)
+		return
+	}
+	account, err := middleware.AccountFromContext(r.Context())
+	if err != nil {
+		api.InternalServerError(w, r, "invalid-account-ctx", err)
+		return
+	}
+	result, err := h.tagRepo.GetTag(r.Context(), account.ID, id)
+	if err != nil {
+		api.HandleRetrievalError(w, r, err)
+		return
+	}
+	render.JSON(w, r, result)
+}
+
+// UpdateTag renames a tag on every log that carries it
+func (h *handler) UpdateTag(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		api.BadRequestError(w, r, "invalid-id", err)
+		return
+	}
+	requestBody := processRequestBody(w, r)
+	if requestBody == nil {
+		return
+	}
+	account, err := middleware.AccountFromContext(r.Context())
+	if err != nil {
+		api.InternalServerError(w, r, "invalid-account-ctx", err)
+		return
+	}
+	_, err = h.tagRepo.GetTag(r.Context(), account.ID, id)
+	if err != nil {
+		api.HandleRetrievalError(w, r, err)
+		return
+	}
+	err = h.tagRepo.UpdateTag(r.Context(), account.ID, id, requestBody)
+	if err != nil {
+		handleWriteError(w, r, err)
+		return
+	}
+	render.NoContent(w, r)
+}
+
+// DeleteTag removes a tag and takes it off every log that carries it; the logs themselves are kept
+func (h *handler) DeleteTag(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		api.BadRequestError(w, r, "invalid-id", err)
+		return
+	}
+	account, err := middleware.AccountFromContext(r.Context())
+	if err != nil {
+		api.InternalServerError(w, r, "invalid-account-ctx", err)
+		return
+	}
+	err = h.tagRepo.DeleteTag(r.Context(), account.ID, id)
+	if err != nil {
+		api.InternalServerError(w, r, "database-error", err)
+		return
+	}
+	render.NoContent(w, r)
+}
+
+func handleWriteError(w http.ResponseWriter, r *http.Request, err error) {
+	switch {
+	case errors.Is(err, db.ErrorDuplicate):
+		api.ConflictError(w, r, "duplicate-tag", err)
+	default:
+		api.InternalServerError(w, r, "database-error", err)
+	}
+}
+
+func processRequestBody(w http.ResponseWriter, r *http.Request) *entity.Tag {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		api.BadRequestError(w, r, "invalid-request-body", err)
+		return nil
+	}
+	var tag entity.Tag
+	err = json.Unmarshal(body, &tag)
+	if err != nil {
+		api.BadRequestError(w, r, "invalid-request-body", err)
+		return nil
+	}
+	if err := tag.Validate(); err != nil {
+		api.BadRequestError(w, r, "invalid-request-body", err)
+		return nil
+	}
+	return &tag
+}
@@ -0,0 +1,64 @@
+package tag
+
+import (
+	"context"
+	"github.com/cecobask/ocdtracker-api/internal/api/apitest"
+	"github.com/cecobask/ocdtracker-api/internal/db/memory"
+	"github.com/cecobask/ocdtracker-api/pkg/entity"
+	"github.com/google/uuid"
+	"net/http"
+	"testing"
+)
+
+func newTestRouter(t *testing.T) (http.Handler, *entity.Account, *memory.OCDLogRepository) {
+	memoryDB := memory.NewDB()
+	account := apitest.CreateAccount(t, memory.NewAccountRepository(memoryDB), "patient")
+	return NewRouter(NewHandler(context.Background(), memory.NewTagRepository(memoryDB))), account, memory.NewOCDLogRepository(memoryDB)
+}
+
+func TestCreateGetUpdateAndDeleteTag(t *testing.T) {
+	router, account, _ := newTestRouter(t)
+	created := apitest.Decode[entity.Tag](t, apitest.Do(t, router, account, http.MethodPost, "/", `{"name":"  Work "}`), http.StatusCreated)
+	if created.ID == uuid.Nil || created.AccountID != account.ID || *created.Name != "work" {
+		t.Fatalf("expected the normalized tag in the response, got %+v", created)
+	}
+	apitest.ExpectStatus(t, apitest.Do(t, router, account, http.MethodPost, "/", `{"name":"WORK"}`), http.StatusConflict)
+	apitest.ExpectStatus(t, apitest.Do(t, router, account, http.MethodPost, "/", `{"name":"a;b"}`), http.StatusBadRequest)
+	apitest.ExpectStatus(t, apitest.Do(t, router, account, http.MethodPost, "/", `{}`), http.StatusBadRequest)
+
+	target := "/" + created.ID.String()
+	apitest.ExpectStatus(t, apitest.Do(t, router, account, http.MethodPatch, target, `{"name":"office"}`), http.StatusNoContent)
+	tag := apitest.Decode[entity.Tag](t, apitest.Do(t, router, account, http.MethodGet, target, ""), http.StatusOK)
+	if *tag.Name != "office" || tag.UpdatedAt == nil {
+		t.Fatalf("update was not applied: %+v", tag)
+	}
+	apitest.ExpectStatus(t, apitest.Do(t, router, account, http.MethodPatch, "/"+uuid.New().String(), `{"name":"home"}`), http.StatusNotFound)
+
+	apitest.ExpectStatus(t, apitest.Do(t, router, &entity.Account{ID: "other"}, http.MethodGet, target, ""), http.StatusNotFound)
+
+	apitest.ExpectStatus(t, apitest.Do(t, router, account, http.MethodDelete, target, ""), http.StatusNoContent)
+	apitest.ExpectStatus(t, apitest.Do(t, router, account, http.MethodGet, target, ""), http.StatusNotFound)
+}
+
+func TestRenameAndDeleteApplyToLogs(t *testing.T) {
+	router, account, ocdLogRepo := newTestRouter(t)
+	ctx := context.Background()
+	ocdLog := entity.OCDLog{Tags: entity.TagNames{"work", "commute"}}
+	if err := ocdLogRepo.CreateLog(ctx, account.ID, &ocdLog); err != nil {
+		t.Fatalf("failed to create log: %v", err)
+	}
+	tags := apitest.Decode[entity.TagList](t, apitest.Do(t, router, account, http.MethodGet, "/", ""), http.StatusOK)
+	if len(tags.Tags) != 2 || *tags.Tags[0].Name != "commute" || *tags.Tags[1].Name != "work" {
+		t.Fatalf("expected the tags of the log to be created, got %+v", tags.Tags)
+	}
+	apitest.ExpectStatus(t, apitest.Do(t, router, account, http.MethodPatch, "/"+tags.Tags[1].ID.String(), `{"name":"office"}`), http.StatusNoContent)
+	stored, err := ocdLogRepo.GetLog(ctx, account.ID, ocdLog.ID)
+	if err != nil || len(stored.Tags) != 2 || stored.Tags[0] != "commute" || stored.Tags[1] != "office" {
+		t.Fatalf("expected the rename to apply to the log, got %+v (%v)", stored, err)
+	}
+	apitest.ExpectStatus(t, apitest.Do(t, router, account, http.MethodDelete, "/"+tags.Tags[0].ID.String(), ""), http.StatusNoContent)
+	stored, err = ocdLogRepo.GetLog(ctx, account.ID, ocdLog.ID)
+	if err != nil || len(stored.Tags) != 1 || stored.Tags[0] != "office" {
+		t.Fatalf("expected the deleted tag to be taken off the log, got %+v (%v)", stored, err)
+	}
+}
@@ -0,0 +1,19 @@
+package tag
+
+import (
+	"github.com/go-chi/chi/v5"
+	"net/http"
+)
+
+// NewRouter creates all routes associated with tags
+func NewRouter(h *handler) http.Handler {
+	r := chi.NewRouter()
+	r.Post("/", h.CreateTag)
+	r.Get("/", h.GetAllTags)
+	r.Route("/{id}", func(r chi.Router) {
+		r.Patch("/", h.UpdateTag)
+		r.Get("/", h.GetTag)
+		r.Delete("/", h.DeleteTag)
+	})
+	return r
+}
@@ -0,0 +1,28 @@
+package auth
+
+import (
+	"context"
+	firebaseAuth "firebase.google.com/go/v4/auth"
+)
+
+// Client is the subset of the identity provider api used by the http layer
+type Client interface {
+	VerifyIDToken(ctx context.Context, idToken string) (*firebaseAuth.Token, error)
+	GetUser(ctx context.Context, uid string) (*firebaseAuth.UserRecord, error)
+	UpdateUser(ctx context.Context, uid string, user *firebaseAuth.UserToUpdate) (*firebaseAuth.UserRecord, error)
+	DeleteUser(ctx context.Context, uid string) error
+}
+
+var _ Client = (*firebaseAuth.Client)(nil)
+
+// probeUID is a user id that is never assigned; looking it up checks the identity provider without touching accounts
+const probeUID = "readiness-probe"
+
+// Ping checks that the identity provider is reachable and accepts the credentials
+func Ping(ctx context.Context, client Client) error {
+	_, err := client.GetUser(ctx, probeUID)
+	if err == nil || firebaseAuth.IsUserNotFound(err) {
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,46 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	firebaseAuth "firebase.google.com/go/v4/auth"
+)
+
+var (
+	ErrorEmptyIDToken = errors.New("id token is empty")
+)
+
+// StubClient stands in for firebase when the api runs without it, such as on the in-memory storage. It trusts the bearer
+// token as the uid of the user and derives the user from it, so it must never guard real data
+type StubClient struct{}
+
+var _ Client = (*StubClient)(nil)
+
+func NewStubClient() *StubClient {
+	return &StubClient{}
+}
+
+func (c *StubClient) VerifyIDToken(_ context.Context, idToken string) (*firebaseAuth.Token, error) {
+	if idToken == "" {
+		return nil, ErrorEmptyIDToken
+	}
+	return &firebaseAuth.Token{UID: idToken}, nil
+}
+
+func (c *StubClient) GetUser(_ context.Context, uid string) (*firebaseAuth.UserRecord, error) {
+	return &firebaseAuth.UserRecord{
+		UserInfo: &firebaseAuth.UserInfo{
+			UID:   uid,
+			Email: uid + "@localhost",
+		},
+	}, nil
+}
+
+// UpdateUser accepts every update without keeping it, since firebase does not expose the fields of the update
+func (c *StubClient) UpdateUser(ctx context.Context, uid string, _ *firebaseAuth.UserToUpdate) (*firebaseAuth.UserRecord, error) {
+	return c.GetUser(ctx, uid)
+}
+
+func (c *StubClient) DeleteUser(_ context.Context, _ string) error {
+	return nil
+}
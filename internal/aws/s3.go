@@ -15,13 +15,8 @@ type S3 struct {
 	client s3iface.S3API
 }
 
-const (
-	bucketOCDTrackerAPI     = "ocdtracker-api"
-	objectKeyGoogleAppCreds = "google-app-creds.json"
-)
-
-func NewS3(sess *session.Session) *S3 {
-	config := &aws.Config{Region: aws.String(defaultRegion)}
+func NewS3(sess *session.Session, region string) *S3 {
+	config := &aws.Config{Region: aws.String(region)}
 	return &S3{client: s3svc.New(sess, config)}
 }
 
@@ -39,8 +34,8 @@ func (s3 *S3) getObject(bucket, key string) ([]byte, error) {
 	return body, nil
 }
 
-func (s3 *S3) GetGoogleAppCreds(ctx context.Context) (*google.Credentials, error) {
-	objectOutput, err := s3.getObject(bucketOCDTrackerAPI, objectKeyGoogleAppCreds)
+func (s3 *S3) GetGoogleAppCreds(ctx context.Context, bucket, key string) (*google.Credentials, error) {
+	objectOutput, err := s3.getObject(bucket, key)
 	if err != nil {
 		return nil, err
 	}
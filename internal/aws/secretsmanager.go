@@ -23,13 +23,8 @@ type PostgresCredsSecret struct {
 	DBInstanceIdentifier string `json:"dbInstanceIdentifier"`
 }
 
-const (
-	secretNamePostgresCreds = "postgres-creds"
-	defaultRegion           = "eu-west-1"
-)
-
-func NewSecretsManager(sess *session.Session) *SecretsManager {
-	return &SecretsManager{client: secretsmanager.New(sess, &aws.Config{Region: aws.String(defaultRegion)})}
+func NewSecretsManager(sess *session.Session, region string) *SecretsManager {
+	return &SecretsManager{client: secretsmanager.New(sess, &aws.Config{Region: aws.String(region)})}
 }
 
 func (sm *SecretsManager) getSecret(secretName string) (*string, error) {
@@ -41,15 +36,15 @@ func (sm *SecretsManager) getSecret(secretName string) (*string, error) {
 	return secretOutput.SecretString, nil
 }
 
-func (sm *SecretsManager) GetPostgresCreds() (*PostgresCredsSecret, error) {
-	secretString, err := sm.getSecret(secretNamePostgresCreds)
+func (sm *SecretsManager) GetPostgresCreds(secretName string) (*PostgresCredsSecret, error) {
+	secretString, err := sm.getSecret(secretName)
 	if err != nil || secretString == nil {
-		return nil, fmt.Errorf("failed to get %s secret: %w", secretNamePostgresCreds, err)
+		return nil, fmt.Errorf("failed to get %s secret: %w", secretName, err)
 	}
 	var postgresCredsSecret PostgresCredsSecret
 	err = json.Unmarshal([]byte(*secretString), &postgresCredsSecret)
 	if err != nil {
-		return nil, fmt.Errorf("failed to unmarshal %s secret: %w", secretNamePostgresCreds, err)
+		return nil, fmt.Errorf("failed to unmarshal %s secret: %w", secretName, err)
 	}
 	return &postgresCredsSecret, nil
 }
@@ -0,0 +1,298 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	validation "github.com/go-ozzo/ozzo-validation/v4"
+	"gopkg.in/yaml.v3"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	envConfigFile = "CONFIG_FILE"
+)
+
+type Config struct {
+	Storage      string       `json:"storage" yaml:"storage"` // postgres, or memory for local development and tests
+	Server       Server       `json:"server" yaml:"server"`
+	AWS          AWS          `json:"aws" yaml:"aws"`
+	Postgres     Postgres     `json:"postgres" yaml:"postgres"`
+	Trash        Trash        `json:"trash" yaml:"trash"`
+	Reminder     Reminder     `json:"reminder" yaml:"reminder"`
+	Notification Notification `json:"notification" yaml:"notification"`
+}
+
+type Server struct {
+	Port               string   `json:"port" yaml:"port"`
+	ReadHeaderTimeout  Duration `json:"read_header_timeout" yaml:"read_header_timeout"`
+	ReadTimeout        Duration `json:"read_timeout" yaml:"read_timeout"`
+	IdleTimeout        Duration `json:"idle_timeout" yaml:"idle_timeout"`
+	ShutdownTimeout    Duration `json:"shutdown_timeout" yaml:"shutdown_timeout"`
+	HealthCheckTimeout Duration `json:"health_check_timeout" yaml:"health_check_timeout"`
+}
+
+type AWS struct {
+	Region                  string `json:"region" yaml:"region"`
+	Bucket                  string `json:"bucket" yaml:"bucket"`
+	GoogleAppCredsObjectKey string `json:"google_app_creds_object_key" yaml:"google_app_creds_object_key"`
+	PostgresCredsSecretName string `json:"postgres_creds_secret_name" yaml:"postgres_creds_secret_name"`
+}
+
+type Postgres struct {
+	SSLMode          string   `json:"ssl_mode" yaml:"ssl_mode"`
+	MigrationPath    string   `json:"migration_path" yaml:"migration_path"`
+	RetryMaxAttempts int      `json:"retry_max_attempts" yaml:"retry_max_attempts"`
+	RetryDelay       Duration `json:"retry_delay" yaml:"retry_delay"`
+}
+
+type Trash struct {
+	Retention     Duration `json:"retention" yaml:"retention"`
+	PurgeInterval Duration `json:"purge_interval" yaml:"purge_interval"`
+}
+
+type Reminder struct {
+	CheckInterval Duration `json:"check_interval" yaml:"check_interval"`
+}
+
+type Notification struct {
+	Sender string `json:"sender" yaml:"sender"`
+}
+
+// Duration is a time.Duration that can be decoded from strings such as "5s"
+type Duration time.Duration
+
+func (d *Duration) UnmarshalText(text []byte) error {
+	duration, err := time.ParseDuration(string(text))
+	if err != nil {
+		return err
+	}
+	*d = Duration(duration)
+	return nil
+}
+
+func (d Duration) MarshalText() ([]byte, error) {
+	return []byte(time.Duration(d).String()), nil
+}
+
+// Default returns the configuration used when nothing is overridden
+func Default() *Config {
+	return &Config{
+		Storage: "postgres",
+		Server: Server{
+			Port:               "8080",
+			ReadHeaderTimeout:  Duration(time.Second * 10),
+			ReadTimeout:        Duration(time.Second * 30),
+			IdleTimeout:        Duration(time.Second * 120),
+			ShutdownTimeout:    Duration(time.Second * 15),
+			HealthCheckTimeout: Duration(time.Second * 2),
+		},
+		AWS: AWS{
+			Region:                  "eu-west-1",
+			Bucket:                  "ocdtracker-api",
+			GoogleAppCredsObjectKey: "google-app-creds.json",
+			PostgresCredsSecretName: "postgres-creds",
+		},
+		Postgres: Postgres{
+			SSLMode:          "require",
+			MigrationPath:    "file:///migration",
+			RetryMaxAttempts: 10,
+			RetryDelay:       Duration(time.Second * 5),
+		},
+		Trash: Trash{
+			Retention:     Duration(time.Hour * 24 * 30),
+			PurgeInterval: Duration(time.Hour),
+		},
+		Reminder: Reminder{
+			CheckInterval: Duration(time.Minute),
+		},
+		Notification: Notification{
+			Sender: "fcm",
+		},
+	}
+}
+
+// Load builds the configuration from defaults, an optional yaml/json file and environment variables, in that order
+func Load() (*Config, error) {
+	config := Default()
+	if path := os.Getenv(envConfigFile); path != "" {
+		err := config.loadFile(path)
+		if err != nil {
+			return nil, err
+		}
+	}
+	err := config.loadEnv()
+	if err != nil {
+		return nil, err
+	}
+	err = config.Validate()
+	if err != nil {
+		return nil, fmt.Errorf("invalid configuration: %w", err)
+	}
+	return config, nil
+}
+
+func (c *Config) loadFile(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read config file %s: %w", path, err)
+	}
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, c)
+	case ".json":
+		err = json.Unmarshal(data, c)
+	default:
+		return fmt.Errorf("unsupported config file format %s", path)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to parse config file %s: %w", path, err)
+	}
+	return nil
+}
+
+func (c *Config) loadEnv() error {
+	lookupString("STORAGE", &c.Storage)
+	lookupString("PORT", &c.Server.Port)
+	if err := lookupDuration("READ_HEADER_TIMEOUT", &c.Server.ReadHeaderTimeout); err != nil {
+		return err
+	}
+	if err := lookupDuration("READ_TIMEOUT", &c.Server.ReadTimeout); err != nil {
+		return err
+	}
+	if err := lookupDuration("IDLE_TIMEOUT", &c.Server.IdleTimeout); err != nil {
+		return err
+	}
+	if err := lookupDuration("SHUTDOWN_TIMEOUT", &c.Server.ShutdownTimeout); err != nil {
+		return err
+	}
+	if err := lookupDuration("HEALTH_CHECK_TIMEOUT", &c.Server.HealthCheckTimeout); err != nil {
+		return err
+	}
+	lookupString("AWS_REGION", &c.AWS.Region)
+	lookupString("AWS_BUCKET", &c.AWS.Bucket)
+	lookupString("GOOGLE_APP_CREDS_OBJECT_KEY", &c.AWS.GoogleAppCredsObjectKey)
+	lookupString("POSTGRES_CREDS_SECRET_NAME", &c.AWS.PostgresCredsSecretName)
+	lookupString("POSTGRES_SSL_MODE", &c.Postgres.SSLMode)
+	lookupString("POSTGRES_MIGRATION_PATH", &c.Postgres.MigrationPath)
+	if err := lookupInt("POSTGRES_RETRY_MAX_ATTEMPTS", &c.Postgres.RetryMaxAttempts); err != nil {
+		return err
+	}
+	if err := lookupDuration("POSTGRES_RETRY_DELAY", &c.Postgres.RetryDelay); err != nil {
+		return err
+	}
+	if err := lookupDuration("TRASH_RETENTION", &c.Trash.Retention); err != nil {
+		return err
+	}
+	if err := lookupDuration("TRASH_PURGE_INTERVAL", &c.Trash.PurgeInterval); err != nil {
+		return err
+	}
+	if err := lookupDuration("REMINDER_CHECK_INTERVAL", &c.Reminder.CheckInterval); err != nil {
+		return err
+	}
+	lookupString("NOTIFICATION_SENDER", &c.Notification.Sender)
+	return nil
+}
+
+func (c Config) Validate() error {
+	return validation.ValidateStruct(&c,
+		validation.Field(&c.Storage, validation.Required, validation.In("postgres", "memory")),
+		validation.Field(&c.Server),
+		validation.Field(&c.AWS),
+		validation.Field(&c.Postgres),
+		validation.Field(&c.Trash),
+		validation.Field(&c.Reminder),
+		validation.Field(&c.Notification),
+	)
+}
+
+func (s Server) Validate() error {
+	return validation.ValidateStruct(&s,
+		validation.Field(&s.Port, validation.Required, validation.By(isPort)),
+		validation.Field(&s.ReadHeaderTimeout, validation.Required, validation.Min(Duration(0))),
+		validation.Field(&s.ReadTimeout, validation.Required, validation.Min(Duration(0))),
+		validation.Field(&s.IdleTimeout, validation.Required, validation.Min(Duration(0))),
+		validation.Field(&s.ShutdownTimeout, validation.Required, validation.Min(Duration(0))),
+		validation.Field(&s.HealthCheckTimeout, validation.Required, validation.Min(Duration(0))),
+	)
+}
+
+func (a AWS) Validate() error {
+	return validation.ValidateStruct(&a,
+		validation.Field(&a.Region, validation.Required),
+		validation.Field(&a.Bucket, validation.Required),
+		validation.Field(&a.GoogleAppCredsObjectKey, validation.Required),
+		validation.Field(&a.PostgresCredsSecretName, validation.Required),
+	)
+}
+
+func (p Postgres) Validate() error {
+	return validation.ValidateStruct(&p,
+		validation.Field(&p.SSLMode, validation.Required, validation.In("disable", "allow", "prefer", "require", "verify-ca", "verify-full")),
+		validation.Field(&p.MigrationPath, validation.Required),
+		validation.Field(&p.RetryMaxAttempts, validation.Required, validation.Min(1)),
+		validation.Field(&p.RetryDelay, validation.Min(Duration(0))),
+	)
+}
+
+func (t Trash) Validate() error {
+	return validation.ValidateStruct(&t,
+		validation.Field(&t.Retention, validation.Required, validation.Min(Duration(0))),
+		validation.Field(&t.PurgeInterval, validation.Required, validation.Min(Duration(0))),
+	)
+}
+
+func (r Reminder) Validate() error {
+	return validation.ValidateStruct(&r,
+		validation.Field(&r.CheckInterval, validation.Required, validation.Min(Duration(0))),
+	)
+}
+
+func (n Notification) Validate() error {
+	return validation.ValidateStruct(&n,
+		validation.Field(&n.Sender, validation.Required, validation.In("fcm", "log")),
+	)
+}
+
+func isPort(value interface{}) error {
+	port, err := strconv.Atoi(value.(string))
+	if err != nil || port < 1 || port > 65535 {
+		return fmt.Errorf("must be a number between 1 and 65535")
+	}
+	return nil
+}
+
+func lookupString(key string, target *string) {
+	if value, ok := os.LookupEnv(key); ok {
+		*target = value
+	}
+}
+
+func lookupInt(key string, target *int) error {
+	value, ok := os.LookupEnv(key)
+	if !ok {
+		return nil
+	}
+	parsed, err := strconv.Atoi(value)
+	if err != nil {
+		return fmt.Errorf("failed to parse %s: %w", key, err)
+	}
+	*target = parsed
+	return nil
+}
+
+func lookupDuration(key string, target *Duration) error {
+	value, ok := os.LookupEnv(key)
+	if !ok {
+		return nil
+	}
+	parsed, err := time.ParseDuration(value)
+	if err != nil {
+		return fmt.Errorf("failed to parse %s: %w", key, err)
+	}
+	*target = Duration(parsed)
+	return nil
+}
@@ -0,0 +1,92 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func writeConfigFile(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatalf("failed to write config file: %v", err)
+	}
+	return path
+}
+
+func TestLoadDefaults(t *testing.T) {
+	t.Setenv(envConfigFile, "")
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("expected the defaults to be valid: %v", err)
+	}
+	if cfg.Storage != "postgres" || cfg.Server.Port != "8080" || cfg.Server.ReadHeaderTimeout != Duration(10*time.Second) || cfg.Postgres.RetryDelay != Duration(5*time.Second) {
+		t.Fatalf("unexpected defaults %+v", cfg)
+	}
+}
+
+func TestLoadOverridesFileWithEnvironment(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+	}{
+		{name: "config.yaml", content: "server:\n  port: \"9090\"\npostgres:\n  ssl_mode: disable\n  retry_delay: 1s\n"},
+		{name: "config.json", content: `{"server":{"port":"9090"},"postgres":{"ssl_mode":"disable","retry_delay":"1s"}}`},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			t.Setenv(envConfigFile, writeConfigFile(t, test.name, test.content))
+			t.Setenv("PORT", "7070")
+			t.Setenv("STORAGE", "memory")
+			cfg, err := Load()
+			if err != nil {
+				t.Fatalf("failed to load configuration: %v", err)
+			}
+			if cfg.Server.Port != "7070" || cfg.Storage != "memory" {
+				t.Fatalf("expected the environment to win over the file, got %+v", cfg)
+			}
+			if cfg.Postgres.SSLMode != "disable" || cfg.Postgres.RetryDelay != Duration(time.Second) {
+				t.Fatalf("expected the file to override the defaults, got %+v", cfg.Postgres)
+			}
+			if cfg.AWS.Region != "eu-west-1" {
+				t.Fatalf("expected the defaults to be kept, got %+v", cfg.AWS)
+			}
+		})
+	}
+}
+
+func TestLoadRejectsInvalidConfiguration(t *testing.T) {
+	tests := []struct {
+		name string
+		env  map[string]string
+	}{
+		{name: "port out of range", env: map[string]string{"PORT": "70000"}},
+		{name: "unknown storage", env: map[string]string{"STORAGE": "sqlite"}},
+		{name: "unknown ssl mode", env: map[string]string{"POSTGRES_SSL_MODE": "sometimes"}},
+		{name: "unparsable retry attempts", env: map[string]string{"POSTGRES_RETRY_MAX_ATTEMPTS": "many"}},
+		{name: "unparsable retry delay", env: map[string]string{"POSTGRES_RETRY_DELAY": "5"}},
+		{name: "missing read header timeout", env: map[string]string{"READ_HEADER_TIMEOUT": "0s"}},
+		{name: "missing reminder check interval", env: map[string]string{"REMINDER_CHECK_INTERVAL": "0s"}},
+		{name: "unknown notification sender", env: map[string]string{"NOTIFICATION_SENDER": "sms"}},
+		{name: "missing config file", env: map[string]string{envConfigFile: "/does/not/exist.yaml"}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			t.Setenv(envConfigFile, "")
+			for key, value := range test.env {
+				t.Setenv(key, value)
+			}
+			if _, err := Load(); err == nil {
+				t.Fatalf("expected an error")
+			}
+		})
+	}
+	t.Run("unsupported file format", func(t *testing.T) {
+		t.Setenv(envConfigFile, writeConfigFile(t, "config.toml", "port = 1"))
+		if _, err := Load(); err == nil {
+			t.Fatalf("expected an error")
+		}
+	})
+}
@@ -0,0 +1,48 @@
+package db
+
+import (
+	"github.com/cecobask/ocdtracker-api/pkg/entity"
+)
+
+// CursorScanDescending reports the order rows have to be scanned in to serve a cursor page
+func CursorScanDescending(cursor *entity.Cursor, descending bool) bool {
+	backward := cursor != nil && cursor.Backward
+	return backward != descending
+}
+
+// BuildCursorPage turns up to limit+1 rows, scanned from the cursor in CursorScanDescending order, into a page in
+// listing order with the cursors pointing to its neighbours
+func BuildCursorPage(ocdLogs []entity.OCDLog, cursor *entity.Cursor, descending bool, limit int) *entity.OCDLogList {
+	hasMore := len(ocdLogs) > limit
+	if hasMore {
+		ocdLogs = ocdLogs[:limit]
+	}
+	backward := cursor != nil && cursor.Backward
+	if backward {
+		for i, j := 0, len(ocdLogs)-1; i < j; i, j = i+1, j-1 {
+			ocdLogs[i], ocdLogs[j] = ocdLogs[j], ocdLogs[i]
+		}
+	}
+	pagination := entity.PaginationDetails{
+		Limit: limit,
+		Count: len(ocdLogs),
+	}
+	if len(ocdLogs) > 0 {
+		first, last := ocdLogs[0], ocdLogs[len(ocdLogs)-1]
+		// a backward page always has a next page (the one it was requested from), a forward page has a previous one
+		// unless it is the first page
+		if backward || hasMore {
+			pagination.NextCursor = entity.Cursor{CreatedAt: *last.CreatedAt, ID: last.ID, Descending: descending}.Encode()
+		}
+		if (backward && hasMore) || (!backward && cursor != nil) {
+			pagination.PrevCursor = entity.Cursor{CreatedAt: *first.CreatedAt, ID: first.ID, Descending: descending, Backward: true}.Encode()
+		}
+	}
+	if ocdLogs == nil {
+		ocdLogs = make([]entity.OCDLog, 0)
+	}
+	return &entity.OCDLogList{
+		Logs:       ocdLogs,
+		Pagination: pagination,
+	}
+}
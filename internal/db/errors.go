@@ -0,0 +1,9 @@
+package db
+
+import (
+	"errors"
+)
+
+var (
+	ErrorDuplicate = errors.New("record already exists")
+)
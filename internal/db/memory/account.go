@@ -0,0 +1,188 @@
+package memory
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"github.com/cecobask/ocdtracker-api/internal/db"
+	"github.com/cecobask/ocdtracker-api/pkg/entity"
+	"github.com/cecobask/ocdtracker-api/pkg/log"
+	"sort"
+)
+
+type AccountRepository struct {
+	DB   *DB
+	inTx bool // set on repositories bound to a transaction by Store.WithTx, which already hold the lock
+}
+
+var _ db.AccountRepository = (*AccountRepository)(nil)
+
+func NewAccountRepository(db *DB) *AccountRepository {
+	return &AccountRepository{
+		DB: db,
+	}
+}
+
+func (repo *AccountRepository) lock() {
+	if !repo.inTx {
+		repo.DB.mu.Lock()
+	}
+}
+
+func (repo *AccountRepository) unlock() {
+	if !repo.inTx {
+		repo.DB.mu.Unlock()
+	}
+}
+
+func (repo *AccountRepository) rLock() {
+	if !repo.inTx {
+		repo.DB.mu.RLock()
+	}
+}
+
+func (repo *AccountRepository) rUnlock() {
+	if !repo.inTx {
+		repo.DB.mu.RUnlock()
+	}
+}
+
+func (repo *AccountRepository) CreateAccount(ctx context.Context, account *entity.Account) error {
+	repo.lock()
+	defer repo.unlock()
+	if _, ok := repo.DB.accounts[account.ID]; ok {
+		return ErrorDuplicateID
+	}
+	if account.Email == nil {
+		return fmt.Errorf("account email must not be null")
+	}
+	if repo.emailTaken(*account.Email, account.ID) {
+		return ErrorDuplicateEmail
+	}
+	createdAt := now()
+	repo.DB.accounts[account.ID] = entity.Account{
+		ID:                   account.ID,
+		Email:                clone(account.Email),
+		CreatedAt:            &createdAt,
+		DisplayName:          clone(account.DisplayName),
+		WakeTime:             valueOrDefault(account.WakeTime, entity.DefaultWakeTime),
+		SleepTime:            valueOrDefault(account.SleepTime, entity.DefaultSleepTime),
+		NotificationInterval: valueOrDefault(account.NotificationInterval, entity.DefaultNotificationInterval),
+		PhotoURL:             clone(account.PhotoURL),
+		TimeZone:             valueOrDefault(account.TimeZone, entity.DefaultTimeZone),
+	}
+	log.LoggerFromContext(ctx).Info("created 1 record/s")
+	return nil
+}
+
+func (repo *AccountRepository) UpdateAccount(ctx context.Context, id string, account *entity.Account) error {
+	repo.lock()
+	defer repo.unlock()
+	existing, ok := repo.DB.accounts[id]
+	if !ok {
+		log.LoggerFromContext(ctx).Info("updated 0 record/s")
+		return nil
+	}
+	if account.Email != nil && repo.emailTaken(*account.Email, id) {
+		return ErrorDuplicateEmail
+	}
+	updated := false
+	if account.Email != nil {
+		existing.Email, updated = clone(account.Email), true
+	}
+	if account.DisplayName != nil {
+		existing.DisplayName, updated = clone(account.DisplayName), true
+	}
+	if account.WakeTime != nil {
+		existing.WakeTime, updated = clone(account.WakeTime), true
+	}
+	if account.SleepTime != nil {
+		existing.SleepTime, updated = clone(account.SleepTime), true
+	}
+	if account.NotificationInterval != nil {
+		existing.NotificationInterval, updated = clone(account.NotificationInterval), true
+	}
+	if account.PhotoURL != nil {
+		existing.PhotoURL, updated = clone(account.PhotoURL), true
+	}
+	if account.TimeZone != nil {
+		existing.TimeZone, updated = clone(account.TimeZone), true
+	}
+	if !updated {
+		return nil // no action
+	}
+	updatedAt := now()
+	existing.UpdatedAt = &updatedAt
+	repo.DB.accounts[id] = existing
+	log.LoggerFromContext(ctx).Info("updated 1 record/s")
+	return nil
+}
+
+func (repo *AccountRepository) GetAccount(_ context.Context, id string) (*entity.Account, error) {
+	repo.rLock()
+	defer repo.rUnlock()
+	account, ok := repo.DB.accounts[id]
+	if !ok {
+		return nil, sql.ErrNoRows
+	}
+	return cloneAccount(account), nil
+}
+
+func (repo *AccountRepository) DeleteAccount(ctx context.Context, id string) error {
+	repo.lock()
+	defer repo.unlock()
+	rowsAffected := 0
+	if _, ok := repo.DB.accounts[id]; ok {
+		delete(repo.DB.accounts, id)
+		rowsAffected++
+		cascadeDeleteLogs(repo.DB, id)
+		cascadeDeleteTags(repo.DB, id)
+		cascadeDeleteExposures(repo.DB, id)
+		cascadeDeleteResponses(repo.DB, id)
+		cascadeDeleteDevices(repo.DB, id)
+		cascadeDeleteGrants(repo.DB, id)
+	}
+	log.LoggerFromContext(ctx).Info(fmt.Sprintf("deleted %d record/s", rowsAffected))
+	return nil
+}
+
+// GetReminderAccounts returns every account that has check-in reminders turned on
+func (repo *AccountRepository) GetReminderAccounts(_ context.Context) ([]entity.Account, error) {
+	repo.DB.mu.RLock()
+	defer repo.DB.mu.RUnlock()
+	accounts := make([]entity.Account, 0)
+	for _, account := range repo.DB.accounts {
+		if account.ReminderInterval() > 0 {
+			accounts = append(accounts, *cloneAccount(account))
+		}
+	}
+	sort.Slice(accounts, func(i, j int) bool {
+		return accounts[i].ID < accounts[j].ID
+	})
+	return accounts, nil
+}
+
+// emailTaken must be called while holding the lock
+func (repo *AccountRepository) emailTaken(email, exceptID string) bool {
+	for id, account := range repo.DB.accounts {
+		if id != exceptID && account.Email != nil && *account.Email == email {
+			return true
+		}
+	}
+	return false
+}
+
+func cloneAccount(account entity.Account) *entity.Account {
+	return &entity.Account{
+		ID:                   account.ID,
+		Email:                clone(account.Email),
+		CreatedAt:            clone(account.CreatedAt),
+		UpdatedAt:            clone(account.UpdatedAt),
+		DisplayName:          clone(account.DisplayName),
+		WakeTime:             clone(account.WakeTime),
+		SleepTime:            clone(account.SleepTime),
+		NotificationInterval: clone(account.NotificationInterval),
+		PhotoURL:             clone(account.PhotoURL),
+		TimeZone:             clone(account.TimeZone),
+	}
+}
@@ -0,0 +1,154 @@
+package memory
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"github.com/cecobask/ocdtracker-api/internal/db"
+	"github.com/cecobask/ocdtracker-api/pkg/entity"
+	"github.com/cecobask/ocdtracker-api/pkg/log"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"sort"
+)
+
+type DeviceRepository struct {
+	DB *DB
+}
+
+var _ db.DeviceRepository = (*DeviceRepository)(nil)
+
+func NewDeviceRepository(db *DB) *DeviceRepository {
+	return &DeviceRepository{
+		DB: db,
+	}
+}
+
+// RegisterDevice stores the token of a device; a token that is already registered, e.g. after another user logged in
+// on the same device, moves to the account and keeps its id
+func (repo *DeviceRepository) RegisterDevice(ctx context.Context, accountID string, device *entity.Device) error {
+	repo.DB.mu.Lock()
+	defer repo.DB.mu.Unlock()
+	if _, ok := repo.DB.accounts[accountID]; !ok {
+		return ErrorAccountNotExists
+	}
+	for id, existing := range repo.DB.devices {
+		if *existing.Token == *device.Token {
+			updatedAt := now()
+			previousAccountID := existing.AccountID
+			existing.AccountID, existing.Platform, existing.UpdatedAt = accountID, clone(device.Platform), &updatedAt
+			repo.DB.devices[id] = existing
+			device.ID = id
+			logDeviceMove(ctx, id, previousAccountID, accountID)
+			log.LoggerFromContext(ctx).Info("registered 1 device")
+			return nil
+		}
+	}
+	device.ID = uuid.New()
+	createdAt := now()
+	repo.DB.devices[device.ID] = entity.Device{
+		ID:        device.ID,
+		AccountID: accountID,
+		Token:     clone(device.Token),
+		Platform:  clone(device.Platform),
+		CreatedAt: &createdAt,
+	}
+	log.LoggerFromContext(ctx).Info("registered 1 device")
+	return nil
+}
+
+func (repo *DeviceRepository) GetAllDevices(_ context.Context, accountID string) (*entity.DeviceList, error) {
+	repo.DB.mu.RLock()
+	defer repo.DB.mu.RUnlock()
+	deviceList := entity.DeviceList{
+		Devices: make([]entity.Device, 0),
+	}
+	for _, device := range repo.DB.devices {
+		if device.AccountID == accountID {
+			deviceList.Devices = append(deviceList.Devices, *cloneDevice(device))
+		}
+	}
+	sort.Slice(deviceList.Devices, func(i, j int) bool {
+		a, b := deviceList.Devices[i], deviceList.Devices[j]
+		if !a.CreatedAt.Equal(*b.CreatedAt) {
+			return a.CreatedAt.Before(*b.CreatedAt)
+		}
+		return a.ID.String() < b.ID.String()
+	})
+	return &deviceList, nil
+}
+
+func (repo *DeviceRepository) GetDevice(_ context.Context, accountID string, id uuid.UUID) (*entity.Device, error) {
+	repo.DB.mu.RLock()
+	defer repo.DB.mu.RUnlock()
+	device, ok := repo.DB.devices[id]
+	if !ok || device.AccountID != accountID {
+		return nil, sql.ErrNoRows
+	}
+	return cloneDevice(device), nil
+}
+
+func (repo *DeviceRepository) DeleteDevice(ctx context.Context, accountID string, id uuid.UUID) error {
+	repo.DB.mu.Lock()
+	defer repo.DB.mu.Unlock()
+	rowsAffected := 0
+	if device, ok := repo.DB.devices[id]; ok && device.AccountID == accountID {
+		delete(repo.DB.devices, id)
+		rowsAffected++
+	}
+	log.LoggerFromContext(ctx).Info(fmt.Sprintf("deleted %d record/s", rowsAffected))
+	return nil
+}
+
+// DeleteDeviceTokens removes the devices of any account with the given tokens, e.g. the ones the messaging service
+// reported as invalid
+func (repo *DeviceRepository) DeleteDeviceTokens(ctx context.Context, tokens []string) (int, error) {
+	repo.DB.mu.Lock()
+	defer repo.DB.mu.Unlock()
+	invalid := make(map[string]bool, len(tokens))
+	for _, token := range tokens {
+		invalid[token] = true
+	}
+	rowsAffected := 0
+	for id, device := range repo.DB.devices {
+		if invalid[*device.Token] {
+			delete(repo.DB.devices, id)
+			rowsAffected++
+		}
+	}
+	log.LoggerFromContext(ctx).Info(fmt.Sprintf("deleted %d record/s", rowsAffected))
+	return rowsAffected, nil
+}
+
+// logDeviceMove records a token that moved to another account, since the previous account silently stops receiving
+// notifications on that device
+func logDeviceMove(ctx context.Context, id uuid.UUID, fromAccountID, toAccountID string) {
+	if fromAccountID == toAccountID {
+		return
+	}
+	log.LoggerFromContext(ctx).Warn("device moved to another account",
+		zap.String("device_id", id.String()),
+		zap.String("from_account_id", fromAccountID),
+		zap.String("to_account_id", toAccountID),
+	)
+}
+
+// cascadeDeleteDevices removes the devices of an account; must be called while holding the lock
+func cascadeDeleteDevices(db *DB, accountID string) {
+	for id, device := range db.devices {
+		if device.AccountID == accountID {
+			delete(db.devices, id)
+		}
+	}
+}
+
+func cloneDevice(device entity.Device) *entity.Device {
+	return &entity.Device{
+		ID:        device.ID,
+		AccountID: device.AccountID,
+		Token:     clone(device.Token),
+		Platform:  clone(device.Platform),
+		CreatedAt: clone(device.CreatedAt),
+		UpdatedAt: clone(device.UpdatedAt),
+	}
+}
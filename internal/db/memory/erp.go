@@ -0,0 +1,412 @@
+package memory
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"github.com/cecobask/ocdtracker-api/internal/db"
+	"github.com/cecobask/ocdtracker-api/pkg/entity"
+	"github.com/cecobask/ocdtracker-api/pkg/log"
+	"github.com/google/uuid"
+	"sort"
+	"time"
+)
+
+type ExposureItemRepository struct {
+	DB   *DB
+	inTx bool // set on repositories bound to a transaction by Store.WithTx, which already hold the lock
+}
+
+type ExposureSessionRepository struct {
+	DB   *DB
+	inTx bool // set on repositories bound to a transaction by Store.WithTx, which already hold the lock
+}
+
+var (
+	_ db.ExposureItemRepository    = (*ExposureItemRepository)(nil)
+	_ db.ExposureSessionRepository = (*ExposureSessionRepository)(nil)
+)
+
+func NewExposureItemRepository(db *DB) *ExposureItemRepository {
+	return &ExposureItemRepository{
+		DB: db,
+	}
+}
+
+func NewExposureSessionRepository(db *DB) *ExposureSessionRepository {
+	return &ExposureSessionRepository{
+		DB: db,
+	}
+}
+
+func (repo *ExposureItemRepository) lock() {
+	if !repo.inTx {
+		repo.DB.mu.Lock()
+	}
+}
+
+func (repo *ExposureItemRepository) unlock() {
+	if !repo.inTx {
+		repo.DB.mu.Unlock()
+	}
+}
+
+func (repo *ExposureItemRepository) rLock() {
+	if !repo.inTx {
+		repo.DB.mu.RLock()
+	}
+}
+
+func (repo *ExposureItemRepository) rUnlock() {
+	if !repo.inTx {
+		repo.DB.mu.RUnlock()
+	}
+}
+
+func (repo *ExposureItemRepository) CreateItem(ctx context.Context, accountID string, item *entity.ExposureItem) error {
+	repo.lock()
+	defer repo.unlock()
+	if _, ok := repo.DB.accounts[accountID]; !ok {
+		return ErrorAccountNotExists
+	}
+	item.ID = uuid.New()
+	createdAt := now()
+	repo.DB.items[item.ID] = entity.ExposureItem{
+		ID:            item.ID,
+		AccountID:     accountID,
+		CreatedAt:     &createdAt,
+		Description:   clone(item.Description),
+		PredictedSUDS: clone(item.PredictedSUDS),
+	}
+	log.LoggerFromContext(ctx).Info("created 1 record/s")
+	return nil
+}
+
+func (repo *ExposureItemRepository) GetAllItems(_ context.Context, accountID string) (*entity.ExposureItemList, error) {
+	repo.rLock()
+	defer repo.rUnlock()
+	itemList := entity.ExposureItemList{
+		Items: make([]entity.ExposureItem, 0),
+	}
+	for _, item := range repo.DB.items {
+		if item.AccountID == accountID {
+			itemList.Items = append(itemList.Items, *cloneExposureItem(item))
+		}
+	}
+	sort.Slice(itemList.Items, func(i, j int) bool {
+		a, b := itemList.Items[i], itemList.Items[j]
+		if *a.PredictedSUDS != *b.PredictedSUDS {
+			return *a.PredictedSUDS < *b.PredictedSUDS
+		}
+		if !a.CreatedAt.Equal(*b.CreatedAt) {
+			return a.CreatedAt.Before(*b.CreatedAt)
+		}
+		return a.ID.String() < b.ID.String()
+	})
+	return &itemList, nil
+}
+
+func (repo *ExposureItemRepository) GetItem(_ context.Context, accountID string, id uuid.UUID) (*entity.ExposureItem, error) {
+	repo.rLock()
+	defer repo.rUnlock()
+	item, ok := repo.DB.items[id]
+	if !ok || item.AccountID != accountID {
+		return nil, sql.ErrNoRows
+	}
+	return cloneExposureItem(item), nil
+}
+
+func (repo *ExposureItemRepository) UpdateItem(ctx context.Context, accountID string, id uuid.UUID, item *entity.ExposureItem) error {
+	repo.lock()
+	defer repo.unlock()
+	if item.Description == nil && item.PredictedSUDS == nil {
+		return nil // no action
+	}
+	existing, ok := repo.DB.items[id]
+	if !ok || existing.AccountID != accountID {
+		log.LoggerFromContext(ctx).Info("updated 0 record/s")
+		return nil
+	}
+	if item.Description != nil {
+		existing.Description = clone(item.Description)
+	}
+	if item.PredictedSUDS != nil {
+		existing.PredictedSUDS = clone(item.PredictedSUDS)
+	}
+	updatedAt := now()
+	existing.UpdatedAt = &updatedAt
+	repo.DB.items[id] = existing
+	log.LoggerFromContext(ctx).Info("updated 1 record/s")
+	return nil
+}
+
+// DeleteItem removes an item from the hierarchy together with its sessions
+func (repo *ExposureItemRepository) DeleteItem(ctx context.Context, accountID string, id uuid.UUID) error {
+	repo.lock()
+	defer repo.unlock()
+	rowsAffected := 0
+	if item, ok := repo.DB.items[id]; ok && item.AccountID == accountID {
+		delete(repo.DB.items, id)
+		rowsAffected++
+		for sessionID, session := range repo.DB.sessions {
+			if *session.ItemID == id {
+				delete(repo.DB.sessions, sessionID)
+			}
+		}
+	}
+	log.LoggerFromContext(ctx).Info(fmt.Sprintf("deleted %d record/s", rowsAffected))
+	return nil
+}
+
+// ImportItems inserts hierarchy items with their ids and timestamps preserved, all or none of them
+func (repo *ExposureItemRepository) ImportItems(ctx context.Context, accountID string, items []entity.ExposureItem) error {
+	repo.lock()
+	defer repo.unlock()
+	if _, ok := repo.DB.accounts[accountID]; !ok {
+		return ErrorAccountNotExists
+	}
+	ids := make(map[uuid.UUID]bool, len(items))
+	for _, item := range items {
+		if _, ok := repo.DB.items[item.ID]; ok || ids[item.ID] {
+			return ErrorDuplicateID
+		}
+		ids[item.ID] = true
+	}
+	for _, item := range items {
+		repo.DB.items[item.ID] = entity.ExposureItem{
+			ID:            item.ID,
+			AccountID:     accountID,
+			CreatedAt:     importedTimestamp(item.CreatedAt),
+			UpdatedAt:     utcOrNil(item.UpdatedAt),
+			Description:   clone(item.Description),
+			PredictedSUDS: clone(item.PredictedSUDS),
+		}
+	}
+	log.LoggerFromContext(ctx).Info(fmt.Sprintf("created %d record/s", len(items)))
+	return nil
+}
+
+func (repo *ExposureSessionRepository) lock() {
+	if !repo.inTx {
+		repo.DB.mu.Lock()
+	}
+}
+
+func (repo *ExposureSessionRepository) unlock() {
+	if !repo.inTx {
+		repo.DB.mu.Unlock()
+	}
+}
+
+func (repo *ExposureSessionRepository) rLock() {
+	if !repo.inTx {
+		repo.DB.mu.RLock()
+	}
+}
+
+func (repo *ExposureSessionRepository) rUnlock() {
+	if !repo.inTx {
+		repo.DB.mu.RUnlock()
+	}
+}
+
+func (repo *ExposureSessionRepository) CreateSession(ctx context.Context, accountID string, session *entity.ExposureSession) error {
+	repo.lock()
+	defer repo.unlock()
+	if _, ok := repo.DB.accounts[accountID]; !ok {
+		return ErrorAccountNotExists
+	}
+	if _, ok := repo.DB.items[*session.ItemID]; !ok {
+		return ErrorItemNotExists
+	}
+	session.ID = uuid.New()
+	createdAt := now()
+	startedAt := createdAt
+	if session.StartedAt != nil {
+		startedAt = session.StartedAt.UTC().Truncate(time.Microsecond)
+	}
+	repo.DB.sessions[session.ID] = entity.ExposureSession{
+		ID:              session.ID,
+		AccountID:       accountID,
+		ItemID:          clone(session.ItemID),
+		CreatedAt:       &createdAt,
+		StartedAt:       &startedAt,
+		DurationMinutes: clone(session.DurationMinutes),
+		SUDSStart:       clone(session.SUDSStart),
+		SUDSPeak:        clone(session.SUDSPeak),
+		SUDSEnd:         clone(session.SUDSEnd),
+		Notes:           clone(session.Notes),
+	}
+	log.LoggerFromContext(ctx).Info("created 1 record/s")
+	return nil
+}
+
+func (repo *ExposureSessionRepository) GetAllSessions(_ context.Context, accountID string, filter entity.ExposureSessionFilter, limit, offset int) (*entity.ExposureSessionList, error) {
+	repo.rLock()
+	defer repo.rUnlock()
+	sessions := make([]entity.ExposureSession, 0)
+	for _, session := range repo.DB.sessions {
+		if session.AccountID != accountID || (filter.ItemID != nil && *session.ItemID != *filter.ItemID) {
+			continue
+		}
+		sessions = append(sessions, *cloneExposureSession(session))
+	}
+	sort.Slice(sessions, func(i, j int) bool {
+		a, b := sessions[i], sessions[j]
+		if !a.StartedAt.Equal(*b.StartedAt) {
+			return a.StartedAt.After(*b.StartedAt)
+		}
+		return a.ID.String() < b.ID.String()
+	})
+	total := len(sessions)
+	sessions = paginate(sessions, limit, offset)
+	return &entity.ExposureSessionList{
+		Sessions: sessions,
+		Pagination: entity.PaginationDetails{
+			Limit:  limit,
+			Offset: &offset,
+			Count:  len(sessions),
+			Total:  &total,
+		},
+	}, nil
+}
+
+func (repo *ExposureSessionRepository) GetSession(_ context.Context, accountID string, id uuid.UUID) (*entity.ExposureSession, error) {
+	repo.rLock()
+	defer repo.rUnlock()
+	session, ok := repo.DB.sessions[id]
+	if !ok || session.AccountID != accountID {
+		return nil, sql.ErrNoRows
+	}
+	return cloneExposureSession(session), nil
+}
+
+func (repo *ExposureSessionRepository) UpdateSession(ctx context.Context, accountID string, id uuid.UUID, session *entity.ExposureSession) error {
+	repo.lock()
+	defer repo.unlock()
+	if session.StartedAt == nil && session.DurationMinutes == nil && session.SUDSStart == nil && session.SUDSPeak == nil &&
+		session.SUDSEnd == nil && session.Notes == nil {
+		return nil // no action
+	}
+	existing, ok := repo.DB.sessions[id]
+	if !ok || existing.AccountID != accountID {
+		log.LoggerFromContext(ctx).Info("updated 0 record/s")
+		return nil
+	}
+	if session.StartedAt != nil {
+		startedAt := session.StartedAt.UTC().Truncate(time.Microsecond)
+		existing.StartedAt = &startedAt
+	}
+	if session.DurationMinutes != nil {
+		existing.DurationMinutes = clone(session.DurationMinutes)
+	}
+	if session.SUDSStart != nil {
+		existing.SUDSStart = clone(session.SUDSStart)
+	}
+	if session.SUDSPeak != nil {
+		existing.SUDSPeak = clone(session.SUDSPeak)
+	}
+	if session.SUDSEnd != nil {
+		existing.SUDSEnd = clone(session.SUDSEnd)
+	}
+	if session.Notes != nil {
+		existing.Notes = clone(session.Notes)
+	}
+	updatedAt := now()
+	existing.UpdatedAt = &updatedAt
+	repo.DB.sessions[id] = existing
+	log.LoggerFromContext(ctx).Info("updated 1 record/s")
+	return nil
+}
+
+// ImportSessions inserts sessions with their ids and timestamps preserved, all or none of them; it returns
+// sql.ErrNoRows if a session belongs to an item that the account does not have
+func (repo *ExposureSessionRepository) ImportSessions(ctx context.Context, accountID string, sessions []entity.ExposureSession) error {
+	repo.lock()
+	defer repo.unlock()
+	ids := make(map[uuid.UUID]bool, len(sessions))
+	for _, session := range sessions {
+		if item, ok := repo.DB.items[*session.ItemID]; !ok || item.AccountID != accountID {
+			return fmt.Errorf("session %s of item %s: %w", session.ID, *session.ItemID, sql.ErrNoRows)
+		}
+		if _, ok := repo.DB.sessions[session.ID]; ok || ids[session.ID] {
+			return ErrorDuplicateID
+		}
+		ids[session.ID] = true
+	}
+	for _, session := range sessions {
+		createdAt := importedTimestamp(session.CreatedAt)
+		startedAt := createdAt
+		if session.StartedAt != nil {
+			startedAt = utcOrNil(session.StartedAt)
+		}
+		repo.DB.sessions[session.ID] = entity.ExposureSession{
+			ID:              session.ID,
+			AccountID:       accountID,
+			ItemID:          clone(session.ItemID),
+			CreatedAt:       createdAt,
+			UpdatedAt:       utcOrNil(session.UpdatedAt),
+			StartedAt:       startedAt,
+			DurationMinutes: clone(session.DurationMinutes),
+			SUDSStart:       clone(session.SUDSStart),
+			SUDSPeak:        clone(session.SUDSPeak),
+			SUDSEnd:         clone(session.SUDSEnd),
+			Notes:           clone(session.Notes),
+		}
+	}
+	log.LoggerFromContext(ctx).Info(fmt.Sprintf("created %d record/s", len(sessions)))
+	return nil
+}
+
+func (repo *ExposureSessionRepository) DeleteSession(ctx context.Context, accountID string, id uuid.UUID) error {
+	repo.lock()
+	defer repo.unlock()
+	rowsAffected := 0
+	if session, ok := repo.DB.sessions[id]; ok && session.AccountID == accountID {
+		delete(repo.DB.sessions, id)
+		rowsAffected++
+	}
+	log.LoggerFromContext(ctx).Info(fmt.Sprintf("deleted %d record/s", rowsAffected))
+	return nil
+}
+
+// cascadeDeleteExposures removes the hierarchy and sessions of an account; must be called while holding the lock
+func cascadeDeleteExposures(db *DB, accountID string) {
+	for id, session := range db.sessions {
+		if session.AccountID == accountID {
+			delete(db.sessions, id)
+		}
+	}
+	for id, item := range db.items {
+		if item.AccountID == accountID {
+			delete(db.items, id)
+		}
+	}
+}
+
+func cloneExposureItem(item entity.ExposureItem) *entity.ExposureItem {
+	return &entity.ExposureItem{
+		ID:            item.ID,
+		AccountID:     item.AccountID,
+		CreatedAt:     clone(item.CreatedAt),
+		UpdatedAt:     clone(item.UpdatedAt),
+		Description:   clone(item.Description),
+		PredictedSUDS: clone(item.PredictedSUDS),
+	}
+}
+
+func cloneExposureSession(session entity.ExposureSession) *entity.ExposureSession {
+	return &entity.ExposureSession{
+		ID:              session.ID,
+		AccountID:       session.AccountID,
+		ItemID:          clone(session.ItemID),
+		CreatedAt:       clone(session.CreatedAt),
+		UpdatedAt:       clone(session.UpdatedAt),
+		StartedAt:       clone(session.StartedAt),
+		DurationMinutes: clone(session.DurationMinutes),
+		SUDSStart:       clone(session.SUDSStart),
+		SUDSPeak:        clone(session.SUDSPeak),
+		SUDSEnd:         clone(session.SUDSEnd),
+		Notes:           clone(session.Notes),
+	}
+}
@@ -0,0 +1,227 @@
+package memory
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"github.com/cecobask/ocdtracker-api/internal/db"
+	"github.com/cecobask/ocdtracker-api/pkg/entity"
+	"github.com/cecobask/ocdtracker-api/pkg/log"
+	"github.com/google/uuid"
+	"sort"
+	"strings"
+	"time"
+)
+
+type GrantRepository struct {
+	DB *DB
+}
+
+var _ db.GrantRepository = (*GrantRepository)(nil)
+
+func NewGrantRepository(db *DB) *GrantRepository {
+	return &GrantRepository{
+		DB: db,
+	}
+}
+
+func (repo *GrantRepository) CreateGrant(ctx context.Context, accountID string, grant *entity.Grant) error {
+	repo.DB.mu.Lock()
+	defer repo.DB.mu.Unlock()
+	if _, ok := repo.DB.accounts[accountID]; !ok {
+		return ErrorAccountNotExists
+	}
+	for _, existing := range repo.DB.grants {
+		if grant.Code != nil && existing.Code != nil && *existing.Code == *grant.Code {
+			return fmt.Errorf("invitation code already exists: %w", db.ErrorDuplicate)
+		}
+	}
+	grant.ID = uuid.New()
+	createdAt := now()
+	var expiresAt *time.Time
+	if grant.ExpiresAt != nil {
+		value := grant.ExpiresAt.UTC().Truncate(time.Microsecond)
+		expiresAt = &value
+	}
+	repo.DB.grants[grant.ID] = entity.Grant{
+		ID:        grant.ID,
+		AccountID: accountID,
+		Email:     clone(grant.Email),
+		Code:      clone(grant.Code),
+		Scopes:    append(entity.GrantScopes{}, grant.Scopes...),
+		CreatedAt: &createdAt,
+		ExpiresAt: expiresAt,
+	}
+	log.LoggerFromContext(ctx).Info("created 1 record/s")
+	return nil
+}
+
+func (repo *GrantRepository) GetAllGrants(_ context.Context, accountID string) (*entity.GrantList, error) {
+	repo.DB.mu.RLock()
+	defer repo.DB.mu.RUnlock()
+	grantList := entity.GrantList{
+		Grants: make([]entity.Grant, 0),
+	}
+	for _, grant := range repo.DB.grants {
+		if grant.AccountID == accountID {
+			grantList.Grants = append(grantList.Grants, *cloneGrant(grant))
+		}
+	}
+	sort.Slice(grantList.Grants, func(i, j int) bool {
+		a, b := grantList.Grants[i], grantList.Grants[j]
+		if !a.CreatedAt.Equal(*b.CreatedAt) {
+			return a.CreatedAt.After(*b.CreatedAt)
+		}
+		return a.ID.String() < b.ID.String()
+	})
+	return &grantList, nil
+}
+
+// GetGrant returns a grant of any account; callers check that it concerns the requesting account
+func (repo *GrantRepository) GetGrant(_ context.Context, id uuid.UUID) (*entity.Grant, error) {
+	repo.DB.mu.RLock()
+	defer repo.DB.mu.RUnlock()
+	grant, ok := repo.DB.grants[id]
+	if !ok {
+		return nil, sql.ErrNoRows
+	}
+	return cloneGrant(grant), nil
+}
+
+func (repo *GrantRepository) GetGrantByCode(_ context.Context, code string) (*entity.Grant, error) {
+	repo.DB.mu.RLock()
+	defer repo.DB.mu.RUnlock()
+	for _, grant := range repo.DB.grants {
+		if grant.Code != nil && *grant.Code == code {
+			return cloneGrant(grant), nil
+		}
+	}
+	return nil, sql.ErrNoRows
+}
+
+// DeleteGrant revokes a grant, or withdraws an invitation that is still pending
+func (repo *GrantRepository) DeleteGrant(ctx context.Context, accountID string, id uuid.UUID) error {
+	repo.DB.mu.Lock()
+	defer repo.DB.mu.Unlock()
+	rowsAffected := 0
+	if grant, ok := repo.DB.grants[id]; ok && grant.AccountID == accountID {
+		delete(repo.DB.grants, id)
+		rowsAffected++
+	}
+	log.LoggerFromContext(ctx).Info(fmt.Sprintf("deleted %d record/s", rowsAffected))
+	return nil
+}
+
+// AcceptGrant makes a pending invitation active for the clinician, replacing any earlier grant between the same
+// accounts; it returns sql.ErrNoRows if the invitation has been accepted or has expired in the meantime
+func (repo *GrantRepository) AcceptGrant(ctx context.Context, id uuid.UUID, clinicianID string) error {
+	repo.DB.mu.Lock()
+	defer repo.DB.mu.Unlock()
+	acceptedAt := now()
+	grant, ok := repo.DB.grants[id]
+	if !ok || !grant.Pending() || grant.Expired(acceptedAt) {
+		return sql.ErrNoRows
+	}
+	if _, ok = repo.DB.accounts[clinicianID]; !ok {
+		return ErrorAccountNotExists
+	}
+	for otherID, other := range repo.DB.grants {
+		if otherID != id && other.AccountID == grant.AccountID && other.ClinicianID != nil && *other.ClinicianID == clinicianID {
+			delete(repo.DB.grants, otherID)
+		}
+	}
+	grant.ClinicianID, grant.Code, grant.AcceptedAt = &clinicianID, nil, &acceptedAt
+	repo.DB.grants[id] = grant
+	log.LoggerFromContext(ctx).Info("accepted 1 grant")
+	return nil
+}
+
+// GetActiveGrant returns the accepted, unexpired grant that gives the clinician access to the account
+func (repo *GrantRepository) GetActiveGrant(_ context.Context, accountID, clinicianID string) (*entity.Grant, error) {
+	repo.DB.mu.RLock()
+	defer repo.DB.mu.RUnlock()
+	for _, grant := range repo.DB.grants {
+		if grant.AccountID == accountID && grant.ClinicianID != nil && *grant.ClinicianID == clinicianID && !grant.Expired(now()) {
+			return cloneGrant(grant), nil
+		}
+	}
+	return nil, sql.ErrNoRows
+}
+
+// GetPatients returns the accounts that currently share their data with the clinician
+func (repo *GrantRepository) GetPatients(_ context.Context, clinicianID string) (*entity.PatientList, error) {
+	repo.DB.mu.RLock()
+	defer repo.DB.mu.RUnlock()
+	return repo.patients(func(grant entity.Grant) bool {
+		return grant.ClinicianID != nil && *grant.ClinicianID == clinicianID
+	}), nil
+}
+
+// GetInvitations returns the pending, unexpired invitations addressed to the email
+func (repo *GrantRepository) GetInvitations(_ context.Context, email string) (*entity.PatientList, error) {
+	repo.DB.mu.RLock()
+	defer repo.DB.mu.RUnlock()
+	return repo.patients(func(grant entity.Grant) bool {
+		return grant.Pending() && grant.Email != nil && strings.EqualFold(*grant.Email, email)
+	}), nil
+}
+
+// patients lists the unexpired grants that match, most recent first; must be called while holding the lock
+func (repo *GrantRepository) patients(match func(grant entity.Grant) bool) *entity.PatientList {
+	grants := make([]entity.Grant, 0)
+	for _, grant := range repo.DB.grants {
+		if match(grant) && !grant.Expired(now()) {
+			grants = append(grants, grant)
+		}
+	}
+	sort.Slice(grants, func(i, j int) bool {
+		a, b := grants[i], grants[j]
+		if a.AcceptedAt != nil && b.AcceptedAt != nil && !a.AcceptedAt.Equal(*b.AcceptedAt) {
+			return a.AcceptedAt.After(*b.AcceptedAt)
+		}
+		if !a.CreatedAt.Equal(*b.CreatedAt) {
+			return a.CreatedAt.After(*b.CreatedAt)
+		}
+		return a.ID.String() < b.ID.String()
+	})
+	patientList := entity.PatientList{
+		Patients: make([]entity.Patient, 0, len(grants)),
+	}
+	for _, grant := range grants {
+		account := repo.DB.accounts[grant.AccountID]
+		patientList.Patients = append(patientList.Patients, entity.Patient{
+			AccountID:   grant.AccountID,
+			Email:       clone(account.Email),
+			DisplayName: clone(account.DisplayName),
+			GrantID:     grant.ID,
+			Scopes:      append(entity.GrantScopes{}, grant.Scopes...),
+			AcceptedAt:  clone(grant.AcceptedAt),
+			ExpiresAt:   clone(grant.ExpiresAt),
+		})
+	}
+	return &patientList
+}
+
+// cascadeDeleteGrants removes the grants an account gave and the ones it received; must be called while holding the
+// lock
+func cascadeDeleteGrants(db *DB, accountID string) {
+	for id, grant := range db.grants {
+		if grant.AccountID == accountID || (grant.ClinicianID != nil && *grant.ClinicianID == accountID) {
+			delete(db.grants, id)
+		}
+	}
+}
+
+func cloneGrant(grant entity.Grant) *entity.Grant {
+	return &entity.Grant{
+		ID:          grant.ID,
+		AccountID:   grant.AccountID,
+		ClinicianID: clone(grant.ClinicianID),
+		Email:       clone(grant.Email),
+		Code:        clone(grant.Code),
+		Scopes:      append(entity.GrantScopes{}, grant.Scopes...),
+		CreatedAt:   clone(grant.CreatedAt),
+		AcceptedAt:  clone(grant.AcceptedAt),
+		ExpiresAt:   clone(grant.ExpiresAt),
+	}
+}
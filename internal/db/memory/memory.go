@@ -0,0 +1,111 @@
+package memory
+
+import (
+	"errors"
+	"fmt"
+	"github.com/cecobask/ocdtracker-api/internal/db"
+	"github.com/cecobask/ocdtracker-api/pkg/entity"
+	"github.com/google/uuid"
+	"sync"
+	"time"
+)
+
+var (
+	ErrorDuplicateEmail   = fmt.Errorf("account with this email already exists: %w", db.ErrorDuplicate)
+	ErrorDuplicateID      = fmt.Errorf("record with this id already exists: %w", db.ErrorDuplicate)
+	ErrorAccountNotExists = errors.New("account does not exist")
+	ErrorItemNotExists    = errors.New("exposure item does not exist")
+)
+
+// DB is an in-memory stand-in for the postgres database, shared between repositories
+type DB struct {
+	mu        sync.RWMutex
+	accounts  map[string]entity.Account
+	ocdLogs   map[uuid.UUID]entity.OCDLog
+	revisions map[uuid.UUID][]entity.OCDLogRevision // by log id, in revision order
+	tags      map[uuid.UUID]entity.Tag
+	items     map[uuid.UUID]entity.ExposureItem
+	sessions  map[uuid.UUID]entity.ExposureSession
+	responses map[uuid.UUID]entity.QuestionnaireResponse
+	devices   map[uuid.UUID]entity.Device
+	grants    map[uuid.UUID]entity.Grant
+}
+
+func NewDB() *DB {
+	return &DB{
+		accounts:  make(map[string]entity.Account),
+		ocdLogs:   make(map[uuid.UUID]entity.OCDLog),
+		revisions: make(map[uuid.UUID][]entity.OCDLogRevision),
+		tags:      make(map[uuid.UUID]entity.Tag),
+		items:     make(map[uuid.UUID]entity.ExposureItem),
+		sessions:  make(map[uuid.UUID]entity.ExposureSession),
+		responses: make(map[uuid.UUID]entity.QuestionnaireResponse),
+		devices:   make(map[uuid.UUID]entity.Device),
+		grants:    make(map[uuid.UUID]entity.Grant),
+	}
+}
+
+// snapshot copies the data and returns a function that restores it; both must be called while holding the lock
+func (db *DB) snapshot() (rollback func()) {
+	accounts, ocdLogs, tags := copyMap(db.accounts), copyMap(db.ocdLogs), copyMap(db.tags)
+	items, sessions, responses := copyMap(db.items), copyMap(db.sessions), copyMap(db.responses)
+	revisions := make(map[uuid.UUID][]entity.OCDLogRevision, len(db.revisions))
+	for id, logRevisions := range db.revisions {
+		revisions[id] = logRevisions[:len(logRevisions):len(logRevisions)] // appends after the snapshot must not share it
+	}
+	return func() {
+		db.accounts = accounts
+		db.ocdLogs = ocdLogs
+		db.revisions = revisions
+		db.tags = tags
+		db.items = items
+		db.sessions = sessions
+		db.responses = responses
+	}
+}
+
+func copyMap[K comparable, V any](m map[K]V) map[K]V {
+	copied := make(map[K]V, len(m))
+	for key, value := range m {
+		copied[key] = value
+	}
+	return copied
+}
+
+// now mimics the precision and location of postgres timestamps
+func now() time.Time {
+	return time.Now().UTC().Truncate(time.Microsecond)
+}
+
+// utcOrNil mimics how a timestamp column stores a time, or nil
+func utcOrNil(value *time.Time) *time.Time {
+	if value == nil {
+		return nil
+	}
+	utc := value.UTC().Truncate(time.Microsecond)
+	return &utc
+}
+
+// importedTimestamp mimics a timestamp column that defaults to the current time
+func importedTimestamp(value *time.Time) *time.Time {
+	if value == nil {
+		createdAt := now()
+		return &createdAt
+	}
+	return utcOrNil(value)
+}
+
+func clone[T any](value *T) *T {
+	if value == nil {
+		return nil
+	}
+	v := *value
+	return &v
+}
+
+func valueOrDefault[T any](value *T, defaultValue T) *T {
+	if value == nil {
+		return &defaultValue
+	}
+	return clone(value)
+}
@@ -0,0 +1,809 @@
+package memory
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"github.com/cecobask/ocdtracker-api/internal/db"
+	"github.com/cecobask/ocdtracker-api/pkg/entity"
+	"github.com/cecobask/ocdtracker-api/pkg/log"
+	"github.com/google/uuid"
+	"sort"
+	"strings"
+	"time"
+)
+
+type OCDLogRepository struct {
+	DB   *DB
+	inTx bool // set on repositories bound to a transaction by WithTx, which already hold the lock
+}
+
+var _ db.OCDLogRepository = (*OCDLogRepository)(nil)
+
+func NewOCDLogRepository(db *DB) *OCDLogRepository {
+	return &OCDLogRepository{
+		DB: db,
+	}
+}
+
+// WithTx holds the lock for the duration of fn and restores the data as it was if fn fails
+func (repo *OCDLogRepository) WithTx(_ context.Context, fn func(txRepo db.OCDLogRepository) error) error {
+	if repo.inTx {
+		return fn(repo)
+	}
+	repo.DB.mu.Lock()
+	defer repo.DB.mu.Unlock()
+	rollback := repo.DB.snapshot()
+	err := fn(&OCDLogRepository{DB: repo.DB, inTx: true})
+	if err != nil {
+		rollback()
+	}
+	return err
+}
+
+func (repo *OCDLogRepository) lock() {
+	if !repo.inTx {
+		repo.DB.mu.Lock()
+	}
+}
+
+func (repo *OCDLogRepository) unlock() {
+	if !repo.inTx {
+		repo.DB.mu.Unlock()
+	}
+}
+
+func (repo *OCDLogRepository) rLock() {
+	if !repo.inTx {
+		repo.DB.mu.RLock()
+	}
+}
+
+func (repo *OCDLogRepository) rUnlock() {
+	if !repo.inTx {
+		repo.DB.mu.RUnlock()
+	}
+}
+
+func (repo *OCDLogRepository) GetAllLogs(ctx context.Context, accountID string, filter entity.OCDLogFilter, sort entity.OCDLogSort, limit, offset int) (*entity.OCDLogList, error) {
+	repo.rLock()
+	defer repo.rUnlock()
+	ocdLogs := repo.accountLogs(accountID, filter)
+	sortLogs(ocdLogs, sort)
+	total := len(ocdLogs)
+	paginationDetails := entity.PaginationDetails{
+		Limit:  limit,
+		Offset: &offset,
+		Total:  &total,
+	}
+	ocdLogList := entity.OCDLogList{
+		Logs: paginate(ocdLogs, limit, offset),
+	}
+	paginationDetails.Count = len(ocdLogList.Logs)
+	ocdLogList.Pagination = paginationDetails
+	log.LoggerFromContext(ctx).Info(fmt.Sprintf("retrieved %d logs", len(ocdLogList.Logs)))
+	return &ocdLogList, nil
+}
+
+func (repo *OCDLogRepository) StreamLogs(ctx context.Context, accountID string, filter entity.OCDLogFilter, sort entity.OCDLogSort, fn func(ocdLog entity.OCDLog) error) error {
+	repo.rLock()
+	ocdLogs := repo.accountLogs(accountID, filter)
+	repo.rUnlock()
+	sortLogs(ocdLogs, sort)
+	for _, ocdLog := range ocdLogs {
+		if err := fn(ocdLog); err != nil {
+			return err
+		}
+	}
+	log.LoggerFromContext(ctx).Info(fmt.Sprintf("streamed %d logs", len(ocdLogs)))
+	return nil
+}
+
+func (repo *OCDLogRepository) GetLogsByCursor(ctx context.Context, accountID string, filter entity.OCDLogFilter, cursor *entity.Cursor, descending bool, limit int) (*entity.OCDLogList, error) {
+	repo.rLock()
+	defer repo.rUnlock()
+	scanDescending := db.CursorScanDescending(cursor, descending)
+	ocdLogs := repo.accountLogs(accountID, filter)
+	sortLogs(ocdLogs, entity.OCDLogSort{{Field: entity.SortFieldCreatedAt, Descending: scanDescending}})
+	scanned := make([]entity.OCDLog, 0, limit+1)
+	for _, ocdLog := range ocdLogs {
+		if len(scanned) > limit {
+			break
+		}
+		if cursor != nil && !pastCursor(ocdLog, cursor, scanDescending) {
+			continue
+		}
+		scanned = append(scanned, ocdLog)
+	}
+	ocdLogList := db.BuildCursorPage(scanned, cursor, descending, limit)
+	log.LoggerFromContext(ctx).Info(fmt.Sprintf("retrieved %d logs", len(ocdLogList.Logs)))
+	return ocdLogList, nil
+}
+
+func (repo *OCDLogRepository) DeleteAllLogs(ctx context.Context, accountID string) error {
+	repo.lock()
+	defer repo.unlock()
+	deletedAt := now()
+	rowsAffected := 0
+	for id, ocdLog := range repo.DB.ocdLogs {
+		if ocdLog.AccountID == accountID && ocdLog.DeletedAt == nil {
+			ocdLog.DeletedAt = &deletedAt
+			repo.DB.ocdLogs[id] = ocdLog
+			rowsAffected++
+		}
+	}
+	log.LoggerFromContext(ctx).Info(fmt.Sprintf("deleted %d record/s", rowsAffected))
+	return nil
+}
+
+func (repo *OCDLogRepository) GetLog(_ context.Context, accountID string, id uuid.UUID) (*entity.OCDLog, error) {
+	repo.rLock()
+	defer repo.rUnlock()
+	ocdLog, ok := repo.DB.ocdLogs[id]
+	if !ok || ocdLog.AccountID != accountID || ocdLog.DeletedAt != nil {
+		return nil, sql.ErrNoRows
+	}
+	return cloneOCDLog(ocdLog), nil
+}
+
+func (repo *OCDLogRepository) GetLogStats(_ context.Context, accountID string, filter entity.OCDLogFilter) (*entity.OCDLogStats, error) {
+	repo.rLock()
+	defer repo.rUnlock()
+	ocdLogs := repo.accountLogs(accountID, filter)
+	stats := entity.OCDLogStats{
+		Count:            len(ocdLogs),
+		AnxietyHistogram: entity.NewAnxietyHistogram(),
+		Compulsions:      compulsionStats(ocdLogs),
+		Tags:             tagStats(ocdLogs),
+	}
+	if len(ocdLogs) == 0 {
+		return &stats, nil
+	}
+	anxietyLevels := make([]int, 0, len(ocdLogs))
+	totalAnxietyLevel := 0
+	for _, ocdLog := range ocdLogs {
+		anxietyLevel := *ocdLog.AnxietyLevel
+		anxietyLevels = append(anxietyLevels, anxietyLevel)
+		totalAnxietyLevel += anxietyLevel
+		if anxietyLevel > stats.MaxAnxietyLevel {
+			stats.MaxAnxietyLevel = anxietyLevel
+		}
+		stats.TotalRuminateMinutes += *ocdLog.RuminateMinutes
+		if anxietyLevel >= 0 && anxietyLevel <= entity.MaxAnxietyLevel {
+			stats.AnxietyHistogram[anxietyLevel].Count++
+		}
+	}
+	var urgeIntensities, outcomes nullableAverage
+	for _, ocdLog := range ocdLogs {
+		urgeIntensities.addInt(ocdLog.UrgeIntensity)
+		outcomes.addBool(ocdLog.Resisted)
+		if ocdLog.Resisted != nil && *ocdLog.Resisted {
+			stats.ResistedCount++
+		}
+		if ocdLog.ResistedMinutes != nil {
+			stats.TotalResistedMinutes += *ocdLog.ResistedMinutes
+		}
+	}
+	stats.AvgUrgeIntensity, stats.ResistanceRate = urgeIntensities.avg(), outcomes.avg()
+	stats.AvgAnxietyLevel = float64(totalAnxietyLevel) / float64(len(ocdLogs))
+	stats.AvgRuminateMinutes = float64(stats.TotalRuminateMinutes) / float64(len(ocdLogs))
+	stats.MedianAnxietyLevel = median(anxietyLevels)
+	return &stats, nil
+}
+
+func (repo *OCDLogRepository) GetLogSeries(_ context.Context, accountID string, filter entity.OCDLogFilter, query entity.OCDLogSeriesQuery) (*entity.OCDLogSeries, error) {
+	repo.rLock()
+	defer repo.rUnlock()
+	pointsByBucket := make(map[time.Time]*entity.OCDLogSeriesPoint)
+	anxietyTotals := make(map[time.Time]int)
+	outcomes := make(map[time.Time]nullableAverage)
+	var points []entity.OCDLogSeriesPoint
+	for _, ocdLog := range repo.accountLogs(accountID, filter) {
+		createdAt := ocdLog.CreatedAt.In(query.Zone())
+		if query.AwakeWindow != nil && !query.AwakeWindow.Contains(createdAt) {
+			continue
+		}
+		bucketStart := query.Bucket.Truncate(createdAt)
+		point, ok := pointsByBucket[bucketStart]
+		if !ok {
+			point = &entity.OCDLogSeriesPoint{BucketStart: bucketStart}
+			pointsByBucket[bucketStart] = point
+		}
+		point.Count++
+		point.TotalRuminateMinutes += *ocdLog.RuminateMinutes
+		anxietyTotals[bucketStart] += *ocdLog.AnxietyLevel
+		outcome := outcomes[bucketStart]
+		outcome.addBool(ocdLog.Resisted)
+		outcomes[bucketStart] = outcome
+	}
+	for bucketStart, point := range pointsByBucket {
+		avgAnxietyLevel := float64(anxietyTotals[bucketStart]) / float64(point.Count)
+		point.AvgAnxietyLevel = &avgAnxietyLevel
+		point.ResistanceRate = outcomes[bucketStart].avg()
+		points = append(points, *point)
+	}
+	return db.FillSeries(points, query), nil
+}
+
+func (repo *OCDLogRepository) CreateLog(ctx context.Context, accountID string, ocdLog *entity.OCDLog) error {
+	repo.lock()
+	defer repo.unlock()
+	if _, ok := repo.DB.accounts[accountID]; !ok {
+		return ErrorAccountNotExists
+	}
+	createdAt := now()
+	ocdLog.ID = uuid.New()
+	tags := ocdLog.Tags.Normalized()
+	ensureTags(repo.DB, accountID, tags)
+	repo.DB.ocdLogs[ocdLog.ID] = entity.OCDLog{
+		ID:              ocdLog.ID,
+		AccountID:       accountID,
+		CreatedAt:       &createdAt,
+		RuminateMinutes: valueOrDefault(ocdLog.RuminateMinutes, 0),
+		AnxietyLevel:    valueOrDefault(ocdLog.AnxietyLevel, 0),
+		Notes:           clone(ocdLog.Notes),
+		CompulsionType:  clone(ocdLog.CompulsionType),
+		UrgeIntensity:   clone(ocdLog.UrgeIntensity),
+		Resisted:        clone(ocdLog.Resisted),
+		ResistedMinutes: clone(ocdLog.ResistedMinutes),
+		Tags:            tags,
+	}
+	log.LoggerFromContext(ctx).Info("created 1 record/s")
+	return nil
+}
+
+func (repo *OCDLogRepository) ImportLogs(ctx context.Context, accountID string, ocdLogs []entity.OCDLog) error {
+	repo.lock()
+	defer repo.unlock()
+	if _, ok := repo.DB.accounts[accountID]; !ok {
+		return ErrorAccountNotExists
+	}
+	for i, ocdLog := range ocdLogs {
+		if _, ok := repo.DB.ocdLogs[ocdLog.ID]; ok {
+			return ErrorDuplicateID
+		}
+		for _, other := range ocdLogs[:i] {
+			if other.ID == ocdLog.ID {
+				return ErrorDuplicateID
+			}
+		}
+	}
+	for _, ocdLog := range ocdLogs {
+		imported := importedLog(accountID, ocdLog)
+		ensureTags(repo.DB, accountID, imported.Tags)
+		repo.DB.ocdLogs[ocdLog.ID] = imported
+	}
+	log.LoggerFromContext(ctx).Info(fmt.Sprintf("created %d record/s", len(ocdLogs)))
+	return nil
+}
+
+func (repo *OCDLogRepository) UpdateLog(ctx context.Context, accountID string, id uuid.UUID, ocdLog *entity.OCDLog) error {
+	repo.lock()
+	defer repo.unlock()
+	if ocdLog.RuminateMinutes == nil && ocdLog.AnxietyLevel == nil && ocdLog.Notes == nil && ocdLog.CompulsionType == nil &&
+		ocdLog.UrgeIntensity == nil && ocdLog.Resisted == nil && ocdLog.ResistedMinutes == nil && ocdLog.Tags == nil && len(ocdLog.Cleared) == 0 {
+		return nil // no action
+	}
+	existing, ok := repo.DB.ocdLogs[id]
+	if !ok || existing.AccountID != accountID || existing.DeletedAt != nil {
+		log.LoggerFromContext(ctx).Info("updated 0 record/s")
+		return nil
+	}
+	oldValues := entity.NewOCDLogRevisionValues(existing)
+	if ocdLog.RuminateMinutes != nil {
+		existing.RuminateMinutes = clone(ocdLog.RuminateMinutes)
+	}
+	if ocdLog.AnxietyLevel != nil {
+		existing.AnxietyLevel = clone(ocdLog.AnxietyLevel)
+	}
+	if ocdLog.Notes != nil {
+		existing.Notes = clone(ocdLog.Notes)
+	}
+	if ocdLog.CompulsionType != nil {
+		existing.CompulsionType = clone(ocdLog.CompulsionType)
+	}
+	if ocdLog.UrgeIntensity != nil {
+		existing.UrgeIntensity = clone(ocdLog.UrgeIntensity)
+	}
+	if ocdLog.Resisted != nil {
+		existing.Resisted = clone(ocdLog.Resisted)
+	}
+	if ocdLog.ResistedMinutes != nil {
+		existing.ResistedMinutes = clone(ocdLog.ResistedMinutes)
+	}
+	if ocdLog.Clears(entity.OCDLogFieldCompulsionType) {
+		existing.CompulsionType = nil
+	}
+	if ocdLog.Clears(entity.OCDLogFieldUrgeIntensity) {
+		existing.UrgeIntensity = nil
+	}
+	if ocdLog.Clears(entity.OCDLogFieldResisted) {
+		existing.Resisted = nil
+	}
+	if ocdLog.Clears(entity.OCDLogFieldResistedMinutes) {
+		existing.ResistedMinutes = nil
+	}
+	if ocdLog.Tags != nil {
+		existing.Tags = ocdLog.Tags.Normalized()
+		ensureTags(repo.DB, accountID, existing.Tags)
+	}
+	updatedAt := now()
+	existing.UpdatedAt = &updatedAt
+	repo.DB.ocdLogs[id] = existing
+	log.LoggerFromContext(ctx).Info("updated 1 record/s")
+	if newValues := entity.NewOCDLogRevisionValues(existing); !oldValues.Equal(newValues) {
+		repo.DB.revisions[id] = append(repo.DB.revisions[id], entity.OCDLogRevision{
+			Revision:  len(repo.DB.revisions[id]) + 1,
+			OCDLogID:  id,
+			ChangedBy: accountID,
+			ChangedAt: updatedAt,
+			Old:       oldValues,
+			New:       newValues,
+		})
+	}
+	return nil
+}
+
+func (repo *OCDLogRepository) GetLogRevisions(_ context.Context, accountID string, id uuid.UUID) (*entity.OCDLogRevisionList, error) {
+	repo.rLock()
+	defer repo.rUnlock()
+	revisionList := entity.OCDLogRevisionList{
+		Revisions: make([]entity.OCDLogRevision, 0),
+	}
+	if ocdLog, ok := repo.DB.ocdLogs[id]; ok && ocdLog.AccountID == accountID {
+		for _, revision := range repo.DB.revisions[id] {
+			revisionList.Revisions = append(revisionList.Revisions, cloneRevision(revision))
+		}
+	}
+	return &revisionList, nil
+}
+
+func (repo *OCDLogRepository) GetLogRevision(_ context.Context, accountID string, id uuid.UUID, revision int) (*entity.OCDLogRevision, error) {
+	repo.rLock()
+	defer repo.rUnlock()
+	ocdLog, ok := repo.DB.ocdLogs[id]
+	revisions := repo.DB.revisions[id]
+	if !ok || ocdLog.AccountID != accountID || revision < 1 || revision > len(revisions) {
+		return nil, sql.ErrNoRows
+	}
+	result := cloneRevision(revisions[revision-1])
+	return &result, nil
+}
+
+// GetAccountLogRevisions returns the revisions of every log of the account, including the trash, by log and revision
+func (repo *OCDLogRepository) GetAccountLogRevisions(_ context.Context, accountID string) (*entity.OCDLogRevisionList, error) {
+	repo.rLock()
+	defer repo.rUnlock()
+	revisionList := entity.OCDLogRevisionList{
+		Revisions: make([]entity.OCDLogRevision, 0),
+	}
+	for id, revisions := range repo.DB.revisions {
+		if ocdLog, ok := repo.DB.ocdLogs[id]; ok && ocdLog.AccountID == accountID {
+			for _, revision := range revisions {
+				revisionList.Revisions = append(revisionList.Revisions, cloneRevision(revision))
+			}
+		}
+	}
+	sort.Slice(revisionList.Revisions, func(i, j int) bool {
+		a, b := revisionList.Revisions[i], revisionList.Revisions[j]
+		if a.OCDLogID != b.OCDLogID {
+			return a.OCDLogID.String() < b.OCDLogID.String()
+		}
+		return a.Revision < b.Revision
+	})
+	return &revisionList, nil
+}
+
+// ImportLogRevisions inserts revisions as they were recorded, all or none of them; the revisions of a log must follow on
+// from the ones it already has
+func (repo *OCDLogRepository) ImportLogRevisions(ctx context.Context, accountID string, revisions []entity.OCDLogRevision) error {
+	repo.lock()
+	defer repo.unlock()
+	imported := make(map[uuid.UUID][]entity.OCDLogRevision)
+	for _, revision := range revisions {
+		ocdLog, ok := repo.DB.ocdLogs[revision.OCDLogID]
+		if !ok || ocdLog.AccountID != accountID {
+			return fmt.Errorf("revision %d of log %s: %w", revision.Revision, revision.OCDLogID, sql.ErrNoRows)
+		}
+		logRevisions, ok := imported[revision.OCDLogID]
+		if !ok {
+			logRevisions = repo.DB.revisions[revision.OCDLogID]
+		}
+		if revision.Revision <= len(logRevisions) {
+			return ErrorDuplicateID
+		}
+		if revision.Revision != len(logRevisions)+1 {
+			return fmt.Errorf("revision %d of log %s does not follow revision %d", revision.Revision, revision.OCDLogID, len(logRevisions))
+		}
+		revision = cloneRevision(revision)
+		revision.ChangedAt = revision.ChangedAt.UTC().Truncate(time.Microsecond)
+		imported[revision.OCDLogID] = append(logRevisions[:len(logRevisions):len(logRevisions)], revision)
+	}
+	for id, logRevisions := range imported {
+		repo.DB.revisions[id] = logRevisions
+	}
+	log.LoggerFromContext(ctx).Info(fmt.Sprintf("created %d record/s", len(revisions)))
+	return nil
+}
+
+func (repo *OCDLogRepository) DeleteLog(ctx context.Context, accountID string, id uuid.UUID) error {
+	repo.lock()
+	defer repo.unlock()
+	rowsAffected := 0
+	if ocdLog, ok := repo.DB.ocdLogs[id]; ok && ocdLog.AccountID == accountID && ocdLog.DeletedAt == nil {
+		deletedAt := now()
+		ocdLog.DeletedAt = &deletedAt
+		repo.DB.ocdLogs[id] = ocdLog
+		rowsAffected++
+	}
+	log.LoggerFromContext(ctx).Info(fmt.Sprintf("deleted %d record/s", rowsAffected))
+	return nil
+}
+
+func (repo *OCDLogRepository) GetTrashedLogs(ctx context.Context, accountID string, limit, offset int) (*entity.OCDLogList, error) {
+	repo.rLock()
+	defer repo.rUnlock()
+	ocdLogs := make([]entity.OCDLog, 0)
+	for _, ocdLog := range repo.DB.ocdLogs {
+		if ocdLog.AccountID == accountID && ocdLog.DeletedAt != nil {
+			ocdLogs = append(ocdLogs, *cloneOCDLog(ocdLog))
+		}
+	}
+	sort.Slice(ocdLogs, func(i, j int) bool {
+		if !ocdLogs[i].DeletedAt.Equal(*ocdLogs[j].DeletedAt) {
+			return ocdLogs[i].DeletedAt.After(*ocdLogs[j].DeletedAt)
+		}
+		return ocdLogs[i].ID.String() < ocdLogs[j].ID.String()
+	})
+	total := len(ocdLogs)
+	ocdLogList := entity.OCDLogList{
+		Logs: paginate(ocdLogs, limit, offset),
+		Pagination: entity.PaginationDetails{
+			Limit:  limit,
+			Offset: &offset,
+			Total:  &total,
+		},
+	}
+	ocdLogList.Pagination.Count = len(ocdLogList.Logs)
+	log.LoggerFromContext(ctx).Info(fmt.Sprintf("retrieved %d trashed logs", len(ocdLogList.Logs)))
+	return &ocdLogList, nil
+}
+
+func (repo *OCDLogRepository) RestoreLog(ctx context.Context, accountID string, id uuid.UUID) error {
+	repo.lock()
+	defer repo.unlock()
+	ocdLog, ok := repo.DB.ocdLogs[id]
+	if !ok || ocdLog.AccountID != accountID || ocdLog.DeletedAt == nil {
+		return sql.ErrNoRows
+	}
+	ocdLog.DeletedAt = nil
+	repo.DB.ocdLogs[id] = ocdLog
+	log.LoggerFromContext(ctx).Info("restored 1 record/s")
+	return nil
+}
+
+func (repo *OCDLogRepository) PurgeTrashedLogs(_ context.Context, deletedBefore time.Time) (int, error) {
+	repo.lock()
+	defer repo.unlock()
+	rowsAffected := 0
+	for id, ocdLog := range repo.DB.ocdLogs {
+		if ocdLog.DeletedAt != nil && ocdLog.DeletedAt.Before(deletedBefore) {
+			delete(repo.DB.ocdLogs, id)
+			delete(repo.DB.revisions, id)
+			rowsAffected++
+		}
+	}
+	return rowsAffected, nil
+}
+
+// accountLogs returns copies of the account's logs outside the trash matching filter, ordered by created_at; must be
+// called while holding the lock
+func (repo *OCDLogRepository) accountLogs(accountID string, filter entity.OCDLogFilter) []entity.OCDLog {
+	ocdLogs := make([]entity.OCDLog, 0)
+	for _, ocdLog := range repo.DB.ocdLogs {
+		if ocdLog.AccountID == accountID && ocdLog.DeletedAt == nil && matchesFilter(ocdLog, filter) {
+			ocdLogs = append(ocdLogs, *cloneOCDLog(ocdLog))
+		}
+	}
+	sortLogs(ocdLogs, entity.DefaultOCDLogSort)
+	return ocdLogs
+}
+
+// sortLogs mirrors the postgres ordering: every key is NULLS LAST and ties are broken by id
+func sortLogs(ocdLogs []entity.OCDLog, ocdLogSort entity.OCDLogSort) {
+	if len(ocdLogSort) == 0 {
+		ocdLogSort = entity.DefaultOCDLogSort
+	}
+	sort.SliceStable(ocdLogs, func(i, j int) bool {
+		for _, sortField := range ocdLogSort {
+			a, b := sortValue(ocdLogs[i], sortField.Field), sortValue(ocdLogs[j], sortField.Field)
+			switch {
+			case a == nil && b == nil:
+				continue
+			case a == nil:
+				return false
+			case b == nil:
+				return true
+			case *a == *b:
+				continue
+			case sortField.Descending:
+				return *a > *b
+			default:
+				return *a < *b
+			}
+		}
+		return ocdLogs[i].ID.String() < ocdLogs[j].ID.String()
+	})
+}
+
+// sortValue returns a comparable representation of a sortable field; timestamps are unix microseconds
+func sortValue(ocdLog entity.OCDLog, field string) *int64 {
+	var value int64
+	switch field {
+	case entity.SortFieldCreatedAt:
+		if ocdLog.CreatedAt == nil {
+			return nil
+		}
+		value = ocdLog.CreatedAt.UnixMicro()
+	case entity.SortFieldUpdatedAt:
+		if ocdLog.UpdatedAt == nil {
+			return nil
+		}
+		value = ocdLog.UpdatedAt.UnixMicro()
+	case entity.SortFieldAnxietyLevel:
+		value = int64(*ocdLog.AnxietyLevel)
+	case entity.SortFieldRuminateMinutes:
+		value = int64(*ocdLog.RuminateMinutes)
+	default:
+		return nil
+	}
+	return &value
+}
+
+// pastCursor mirrors the postgres row comparison (created_at, id) > cursor, or < when scanning descending
+func pastCursor(ocdLog entity.OCDLog, cursor *entity.Cursor, scanDescending bool) bool {
+	var cmp int
+	switch {
+	case ocdLog.CreatedAt.Before(cursor.CreatedAt):
+		cmp = -1
+	case ocdLog.CreatedAt.After(cursor.CreatedAt):
+		cmp = 1
+	default:
+		cmp = strings.Compare(ocdLog.ID.String(), cursor.ID.String())
+	}
+	if scanDescending {
+		return cmp < 0
+	}
+	return cmp > 0
+}
+
+func matchesFilter(ocdLog entity.OCDLog, filter entity.OCDLogFilter) bool {
+	switch {
+	case filter.From != nil && ocdLog.CreatedAt.Before(*filter.From):
+		return false
+	case filter.To != nil && ocdLog.CreatedAt.After(*filter.To):
+		return false
+	case filter.AnxietyMin != nil && *ocdLog.AnxietyLevel < *filter.AnxietyMin:
+		return false
+	case filter.AnxietyMax != nil && *ocdLog.AnxietyLevel > *filter.AnxietyMax:
+		return false
+	case filter.RuminateMin != nil && *ocdLog.RuminateMinutes < *filter.RuminateMin:
+		return false
+	case filter.RuminateMax != nil && *ocdLog.RuminateMinutes > *filter.RuminateMax:
+		return false
+	case filter.HasNotes != nil && *filter.HasNotes != (ocdLog.Notes != nil && *ocdLog.Notes != ""):
+		return false
+	case filter.CompulsionType != nil && (ocdLog.CompulsionType == nil || *ocdLog.CompulsionType != *filter.CompulsionType):
+		return false
+	case filter.Resisted != nil && (ocdLog.Resisted == nil || *ocdLog.Resisted != *filter.Resisted):
+		return false
+	}
+	for _, name := range filter.Tags {
+		if !ocdLog.Tags.Contains(name) {
+			return false
+		}
+	}
+	return true
+}
+
+// cascadeDeleteLogs permanently removes all logs of an account and their revisions, including the trash; must be
+// called while holding the lock
+func cascadeDeleteLogs(db *DB, accountID string) int {
+	rowsAffected := 0
+	for id, ocdLog := range db.ocdLogs {
+		if ocdLog.AccountID == accountID {
+			delete(db.ocdLogs, id)
+			delete(db.revisions, id)
+			rowsAffected++
+		}
+	}
+	return rowsAffected
+}
+
+// nullableAverage mirrors a postgres avg over a nullable column, e.g. avg(resisted::int)
+type nullableAverage struct {
+	total int
+	count int
+}
+
+func (average *nullableAverage) addInt(value *int) {
+	if value != nil {
+		average.total += *value
+		average.count++
+	}
+}
+
+func (average *nullableAverage) addBool(value *bool) {
+	if value != nil {
+		if *value {
+			average.total++
+		}
+		average.count++
+	}
+}
+
+// avg returns nil when no value was added, like avg over only null values
+func (average nullableAverage) avg() *float64 {
+	if average.count == 0 {
+		return nil
+	}
+	value := float64(average.total) / float64(average.count)
+	return &value
+}
+
+// compulsionStats mirrors the per-compulsion-type aggregates of postgres, most frequent first and ties broken by type
+func compulsionStats(ocdLogs []entity.OCDLog) []entity.CompulsionStats {
+	countsByType := make(map[string]int)
+	urgeIntensities := make(map[string]*nullableAverage)
+	outcomes := make(map[string]*nullableAverage)
+	for _, ocdLog := range ocdLogs {
+		if ocdLog.CompulsionType == nil {
+			continue
+		}
+		compulsionType := *ocdLog.CompulsionType
+		if _, ok := countsByType[compulsionType]; !ok {
+			urgeIntensities[compulsionType], outcomes[compulsionType] = &nullableAverage{}, &nullableAverage{}
+		}
+		countsByType[compulsionType]++
+		urgeIntensities[compulsionType].addInt(ocdLog.UrgeIntensity)
+		outcomes[compulsionType].addBool(ocdLog.Resisted)
+	}
+	result := make([]entity.CompulsionStats, 0, len(countsByType))
+	for compulsionType, count := range countsByType {
+		result = append(result, entity.CompulsionStats{
+			CompulsionType:   compulsionType,
+			Count:            count,
+			AvgUrgeIntensity: urgeIntensities[compulsionType].avg(),
+			ResistanceRate:   outcomes[compulsionType].avg(),
+		})
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Count != result[j].Count {
+			return result[i].Count > result[j].Count
+		}
+		return result[i].CompulsionType < result[j].CompulsionType
+	})
+	return result
+}
+
+// tagStats mirrors the per-tag aggregates of postgres, most used tags first and ties broken by name
+func tagStats(ocdLogs []entity.OCDLog) []entity.TagStats {
+	statsByTag := make(map[string]*entity.TagStats)
+	anxietyTotals := make(map[string]int)
+	for _, ocdLog := range ocdLogs {
+		for _, name := range ocdLog.Tags {
+			stats, ok := statsByTag[name]
+			if !ok {
+				stats = &entity.TagStats{Tag: name}
+				statsByTag[name] = stats
+			}
+			stats.Count++
+			stats.TotalRuminateMinutes += *ocdLog.RuminateMinutes
+			anxietyTotals[name] += *ocdLog.AnxietyLevel
+		}
+	}
+	result := make([]entity.TagStats, 0, len(statsByTag))
+	for name, stats := range statsByTag {
+		stats.AvgAnxietyLevel = float64(anxietyTotals[name]) / float64(stats.Count)
+		result = append(result, *stats)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Count != result[j].Count {
+			return result[i].Count > result[j].Count
+		}
+		return result[i].Tag < result[j].Tag
+	})
+	return result
+}
+
+// median mirrors percentile_cont(0.5), interpolating between the two middle values
+func median(values []int) float64 {
+	sort.Ints(values)
+	middle := len(values) / 2
+	if len(values)%2 == 1 {
+		return float64(values[middle])
+	}
+	return float64(values[middle-1]+values[middle]) / 2
+}
+
+func paginate[T any](values []T, limit, offset int) []T {
+	if offset < 0 {
+		offset = 0
+	}
+	if offset > len(values) {
+		offset = len(values)
+	}
+	end := len(values)
+	if limit >= 0 && offset+limit < end {
+		end = offset + limit
+	}
+	return values[offset:end]
+}
+
+// importedLog applies the column defaults of an insert that preserves id and timestamps
+func importedLog(accountID string, ocdLog entity.OCDLog) entity.OCDLog {
+	createdAt := now()
+	if ocdLog.CreatedAt != nil {
+		createdAt = ocdLog.CreatedAt.UTC().Truncate(time.Microsecond)
+	}
+	var updatedAt *time.Time
+	if ocdLog.UpdatedAt != nil {
+		value := ocdLog.UpdatedAt.UTC().Truncate(time.Microsecond)
+		updatedAt = &value
+	}
+	var deletedAt *time.Time
+	if ocdLog.DeletedAt != nil {
+		value := ocdLog.DeletedAt.UTC().Truncate(time.Microsecond)
+		deletedAt = &value
+	}
+	return entity.OCDLog{
+		ID:              ocdLog.ID,
+		AccountID:       accountID,
+		CreatedAt:       &createdAt,
+		UpdatedAt:       updatedAt,
+		RuminateMinutes: valueOrDefault(ocdLog.RuminateMinutes, 0),
+		AnxietyLevel:    valueOrDefault(ocdLog.AnxietyLevel, 0),
+		Notes:           clone(ocdLog.Notes),
+		CompulsionType:  clone(ocdLog.CompulsionType),
+		UrgeIntensity:   clone(ocdLog.UrgeIntensity),
+		Resisted:        clone(ocdLog.Resisted),
+		ResistedMinutes: clone(ocdLog.ResistedMinutes),
+		DeletedAt:       deletedAt,
+		Tags:            ocdLog.Tags.Normalized(),
+	}
+}
+
+func cloneOCDLog(ocdLog entity.OCDLog) *entity.OCDLog {
+	return &entity.OCDLog{
+		ID:              ocdLog.ID,
+		AccountID:       ocdLog.AccountID,
+		CreatedAt:       clone(ocdLog.CreatedAt),
+		UpdatedAt:       clone(ocdLog.UpdatedAt),
+		RuminateMinutes: clone(ocdLog.RuminateMinutes),
+		AnxietyLevel:    clone(ocdLog.AnxietyLevel),
+		Notes:           clone(ocdLog.Notes),
+		CompulsionType:  clone(ocdLog.CompulsionType),
+		UrgeIntensity:   clone(ocdLog.UrgeIntensity),
+		Resisted:        clone(ocdLog.Resisted),
+		ResistedMinutes: clone(ocdLog.ResistedMinutes),
+		Tags:            append(entity.TagNames(nil), ocdLog.Tags...),
+		DeletedAt:       clone(ocdLog.DeletedAt),
+	}
+}
+
+func cloneRevision(revision entity.OCDLogRevision) entity.OCDLogRevision {
+	revision.Old, revision.New = cloneRevisionValues(revision.Old), cloneRevisionValues(revision.New)
+	return revision
+}
+
+func cloneRevisionValues(values entity.OCDLogRevisionValues) entity.OCDLogRevisionValues {
+	values.Notes = clone(values.Notes)
+	values.CompulsionType = clone(values.CompulsionType)
+	values.UrgeIntensity = clone(values.UrgeIntensity)
+	values.Resisted = clone(values.Resisted)
+	values.ResistedMinutes = clone(values.ResistedMinutes)
+	if values.Tags != nil {
+		values.Tags = append(entity.TagNames{}, values.Tags...)
+	}
+	return values
+}
@@ -0,0 +1,174 @@
+package memory
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"github.com/cecobask/ocdtracker-api/internal/db"
+	"github.com/cecobask/ocdtracker-api/pkg/entity"
+	"github.com/cecobask/ocdtracker-api/pkg/log"
+	"github.com/google/uuid"
+	"sort"
+)
+
+type QuestionnaireResponseRepository struct {
+	DB   *DB
+	inTx bool // set on repositories bound to a transaction by Store.WithTx, which already hold the lock
+}
+
+var _ db.QuestionnaireResponseRepository = (*QuestionnaireResponseRepository)(nil)
+
+func NewQuestionnaireResponseRepository(db *DB) *QuestionnaireResponseRepository {
+	return &QuestionnaireResponseRepository{
+		DB: db,
+	}
+}
+
+func (repo *QuestionnaireResponseRepository) lock() {
+	if !repo.inTx {
+		repo.DB.mu.Lock()
+	}
+}
+
+func (repo *QuestionnaireResponseRepository) unlock() {
+	if !repo.inTx {
+		repo.DB.mu.Unlock()
+	}
+}
+
+func (repo *QuestionnaireResponseRepository) rLock() {
+	if !repo.inTx {
+		repo.DB.mu.RLock()
+	}
+}
+
+func (repo *QuestionnaireResponseRepository) rUnlock() {
+	if !repo.inTx {
+		repo.DB.mu.RUnlock()
+	}
+}
+
+func (repo *QuestionnaireResponseRepository) CreateResponse(ctx context.Context, accountID string, response *entity.QuestionnaireResponse) error {
+	repo.lock()
+	defer repo.unlock()
+	if _, ok := repo.DB.accounts[accountID]; !ok {
+		return ErrorAccountNotExists
+	}
+	response.ID = uuid.New()
+	createdAt := now()
+	stored := *cloneQuestionnaireResponse(*response)
+	stored.AccountID, stored.CreatedAt = accountID, &createdAt
+	repo.DB.responses[response.ID] = stored
+	log.LoggerFromContext(ctx).Info("created 1 record/s")
+	return nil
+}
+
+// GetAllResponses returns the score history of a questionnaire, most recent first
+func (repo *QuestionnaireResponseRepository) GetAllResponses(_ context.Context, accountID, questionnaireID string, limit, offset int) (*entity.QuestionnaireResponseList, error) {
+	repo.rLock()
+	defer repo.rUnlock()
+	responses := make([]entity.QuestionnaireResponse, 0)
+	for _, response := range repo.DB.responses {
+		if response.AccountID == accountID && response.QuestionnaireID == questionnaireID {
+			responses = append(responses, *cloneQuestionnaireResponse(response))
+		}
+	}
+	sort.Slice(responses, func(i, j int) bool {
+		a, b := responses[i], responses[j]
+		if !a.CreatedAt.Equal(*b.CreatedAt) {
+			return a.CreatedAt.After(*b.CreatedAt)
+		}
+		return a.ID.String() < b.ID.String()
+	})
+	total := len(responses)
+	responses = paginate(responses, limit, offset)
+	return &entity.QuestionnaireResponseList{
+		Responses: responses,
+		Pagination: entity.PaginationDetails{
+			Limit:  limit,
+			Offset: &offset,
+			Count:  len(responses),
+			Total:  &total,
+		},
+	}, nil
+}
+
+func (repo *QuestionnaireResponseRepository) GetResponse(_ context.Context, accountID string, id uuid.UUID) (*entity.QuestionnaireResponse, error) {
+	repo.rLock()
+	defer repo.rUnlock()
+	response, ok := repo.DB.responses[id]
+	if !ok || response.AccountID != accountID {
+		return nil, sql.ErrNoRows
+	}
+	return cloneQuestionnaireResponse(response), nil
+}
+
+// GetAccountResponses returns the responses to every questionnaire, oldest first
+func (repo *QuestionnaireResponseRepository) GetAccountResponses(_ context.Context, accountID string) ([]entity.QuestionnaireResponse, error) {
+	repo.rLock()
+	defer repo.rUnlock()
+	responses := make([]entity.QuestionnaireResponse, 0)
+	for _, response := range repo.DB.responses {
+		if response.AccountID == accountID {
+			responses = append(responses, *cloneQuestionnaireResponse(response))
+		}
+	}
+	sort.Slice(responses, func(i, j int) bool {
+		a, b := responses[i], responses[j]
+		if !a.CreatedAt.Equal(*b.CreatedAt) {
+			return a.CreatedAt.Before(*b.CreatedAt)
+		}
+		return a.ID.String() < b.ID.String()
+	})
+	return responses, nil
+}
+
+// ImportResponses inserts questionnaire responses with their ids, timestamps and scores preserved, all or none of them
+func (repo *QuestionnaireResponseRepository) ImportResponses(ctx context.Context, accountID string, responses []entity.QuestionnaireResponse) error {
+	repo.lock()
+	defer repo.unlock()
+	if _, ok := repo.DB.accounts[accountID]; !ok {
+		return ErrorAccountNotExists
+	}
+	ids := make(map[uuid.UUID]bool, len(responses))
+	for _, response := range responses {
+		if _, ok := repo.DB.responses[response.ID]; ok || ids[response.ID] {
+			return ErrorDuplicateID
+		}
+		ids[response.ID] = true
+	}
+	for _, response := range responses {
+		stored := *cloneQuestionnaireResponse(response)
+		stored.AccountID, stored.CreatedAt = accountID, importedTimestamp(response.CreatedAt)
+		repo.DB.responses[response.ID] = stored
+	}
+	log.LoggerFromContext(ctx).Info(fmt.Sprintf("created %d record/s", len(responses)))
+	return nil
+}
+
+// cascadeDeleteResponses removes the questionnaire responses of an account; must be called while holding the lock
+func cascadeDeleteResponses(db *DB, accountID string) {
+	for id, response := range db.responses {
+		if response.AccountID == accountID {
+			delete(db.responses, id)
+		}
+	}
+}
+
+func cloneQuestionnaireResponse(response entity.QuestionnaireResponse) *entity.QuestionnaireResponse {
+	subscaleScores := make(entity.SubscaleScores, len(response.SubscaleScores))
+	for id, score := range response.SubscaleScores {
+		subscaleScores[id] = score
+	}
+	return &entity.QuestionnaireResponse{
+		ID:                   response.ID,
+		AccountID:            response.AccountID,
+		QuestionnaireID:      response.QuestionnaireID,
+		QuestionnaireVersion: response.QuestionnaireVersion,
+		CreatedAt:            clone(response.CreatedAt),
+		Answers:              append(entity.QuestionnaireAnswers(nil), response.Answers...),
+		TotalScore:           response.TotalScore,
+		SubscaleScores:       subscaleScores,
+		Severity:             clone(response.Severity),
+	}
+}
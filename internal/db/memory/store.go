@@ -0,0 +1,37 @@
+package memory
+
+import (
+	"context"
+	"github.com/cecobask/ocdtracker-api/internal/db"
+)
+
+type Store struct {
+	DB *DB
+}
+
+var _ db.Store = (*Store)(nil)
+
+func NewStore(db *DB) *Store {
+	return &Store{
+		DB: db,
+	}
+}
+
+// WithTx holds the lock for the duration of fn and restores the data as it was if fn fails
+func (store *Store) WithTx(_ context.Context, fn func(tx db.Tx) error) error {
+	store.DB.mu.Lock()
+	defer store.DB.mu.Unlock()
+	rollback := store.DB.snapshot()
+	err := fn(db.Tx{
+		Accounts:               &AccountRepository{DB: store.DB, inTx: true},
+		OCDLogs:                &OCDLogRepository{DB: store.DB, inTx: true},
+		Tags:                   &TagRepository{DB: store.DB, inTx: true},
+		ExposureItems:          &ExposureItemRepository{DB: store.DB, inTx: true},
+		ExposureSessions:       &ExposureSessionRepository{DB: store.DB, inTx: true},
+		QuestionnaireResponses: &QuestionnaireResponseRepository{DB: store.DB, inTx: true},
+	})
+	if err != nil {
+		rollback()
+	}
+	return err
+}
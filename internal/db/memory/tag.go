@@ -0,0 +1,257 @@
+package memory
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"github.com/cecobask/ocdtracker-api/internal/db"
+	"github.com/cecobask/ocdtracker-api/pkg/entity"
+	"github.com/cecobask/ocdtracker-api/pkg/log"
+	"github.com/google/uuid"
+	"sort"
+	"time"
+)
+
+var (
+	ErrorDuplicateTag = fmt.Errorf("tag with this name already exists: %w", db.ErrorDuplicate)
+)
+
+type TagRepository struct {
+	DB   *DB
+	inTx bool // set on repositories bound to a transaction by Store.WithTx, which already hold the lock
+}
+
+var _ db.TagRepository = (*TagRepository)(nil)
+
+func NewTagRepository(db *DB) *TagRepository {
+	return &TagRepository{
+		DB: db,
+	}
+}
+
+func (repo *TagRepository) lock() {
+	if !repo.inTx {
+		repo.DB.mu.Lock()
+	}
+}
+
+func (repo *TagRepository) unlock() {
+	if !repo.inTx {
+		repo.DB.mu.Unlock()
+	}
+}
+
+func (repo *TagRepository) rLock() {
+	if !repo.inTx {
+		repo.DB.mu.RLock()
+	}
+}
+
+func (repo *TagRepository) rUnlock() {
+	if !repo.inTx {
+		repo.DB.mu.RUnlock()
+	}
+}
+
+func (repo *TagRepository) CreateTag(ctx context.Context, accountID string, tag *entity.Tag) error {
+	repo.lock()
+	defer repo.unlock()
+	if _, ok := repo.DB.accounts[accountID]; !ok {
+		return ErrorAccountNotExists
+	}
+	name := entity.NormalizeTagName(*tag.Name)
+	if _, ok := findTag(repo.DB, accountID, name); ok {
+		return ErrorDuplicateTag
+	}
+	tag.ID = uuid.New()
+	createdAt := now()
+	repo.DB.tags[tag.ID] = entity.Tag{
+		ID:        tag.ID,
+		AccountID: accountID,
+		Name:      &name,
+		CreatedAt: &createdAt,
+	}
+	log.LoggerFromContext(ctx).Info("created 1 record/s")
+	return nil
+}
+
+func (repo *TagRepository) GetAllTags(_ context.Context, accountID string) (*entity.TagList, error) {
+	repo.rLock()
+	defer repo.rUnlock()
+	tagList := entity.TagList{
+		Tags: make([]entity.Tag, 0),
+	}
+	for _, tag := range repo.DB.tags {
+		if tag.AccountID == accountID {
+			tagList.Tags = append(tagList.Tags, *cloneTag(tag))
+		}
+	}
+	sort.Slice(tagList.Tags, func(i, j int) bool {
+		return *tagList.Tags[i].Name < *tagList.Tags[j].Name
+	})
+	return &tagList, nil
+}
+
+func (repo *TagRepository) GetTag(_ context.Context, accountID string, id uuid.UUID) (*entity.Tag, error) {
+	repo.rLock()
+	defer repo.rUnlock()
+	tag, ok := repo.DB.tags[id]
+	if !ok || tag.AccountID != accountID {
+		return nil, sql.ErrNoRows
+	}
+	return cloneTag(tag), nil
+}
+
+// UpdateTag renames a tag, which also renames it on every log that carries it
+func (repo *TagRepository) UpdateTag(ctx context.Context, accountID string, id uuid.UUID, tag *entity.Tag) error {
+	repo.lock()
+	defer repo.unlock()
+	if tag.Name == nil {
+		return nil // no action
+	}
+	existing, ok := repo.DB.tags[id]
+	if !ok || existing.AccountID != accountID {
+		log.LoggerFromContext(ctx).Info("updated 0 record/s")
+		return nil
+	}
+	name := entity.NormalizeTagName(*tag.Name)
+	if other, ok := findTag(repo.DB, accountID, name); ok && other.ID != id {
+		return ErrorDuplicateTag
+	}
+	replaceLogTag(repo.DB, accountID, *existing.Name, name)
+	updatedAt := now()
+	existing.Name, existing.UpdatedAt = &name, &updatedAt
+	repo.DB.tags[id] = existing
+	log.LoggerFromContext(ctx).Info("updated 1 record/s")
+	return nil
+}
+
+// DeleteTag removes a tag and takes it off every log that carries it
+func (repo *TagRepository) DeleteTag(ctx context.Context, accountID string, id uuid.UUID) error {
+	repo.lock()
+	defer repo.unlock()
+	rowsAffected := 0
+	if tag, ok := repo.DB.tags[id]; ok && tag.AccountID == accountID {
+		replaceLogTag(repo.DB, accountID, *tag.Name, "")
+		delete(repo.DB.tags, id)
+		rowsAffected++
+	}
+	log.LoggerFromContext(ctx).Info(fmt.Sprintf("deleted %d record/s", rowsAffected))
+	return nil
+}
+
+// ImportTags inserts tags with their ids and timestamps preserved, all or none of them; tags whose name the account
+// already has are skipped
+func (repo *TagRepository) ImportTags(ctx context.Context, accountID string, tags []entity.Tag) error {
+	repo.lock()
+	defer repo.unlock()
+	if _, ok := repo.DB.accounts[accountID]; !ok {
+		return ErrorAccountNotExists
+	}
+	imported := make([]entity.Tag, 0, len(tags))
+	ids, names := make(map[uuid.UUID]struct{}, len(tags)), make(map[string]struct{}, len(tags))
+	for _, tag := range tags {
+		tag = importedTag(accountID, tag)
+		if _, ok := names[*tag.Name]; ok {
+			continue
+		}
+		if _, ok := findTag(repo.DB, accountID, *tag.Name); ok {
+			continue
+		}
+		if _, ok := repo.DB.tags[tag.ID]; ok {
+			return ErrorDuplicateID
+		}
+		if _, ok := ids[tag.ID]; ok {
+			return ErrorDuplicateID
+		}
+		ids[tag.ID], names[*tag.Name] = struct{}{}, struct{}{}
+		imported = append(imported, tag)
+	}
+	for _, tag := range imported {
+		repo.DB.tags[tag.ID] = tag
+	}
+	log.LoggerFromContext(ctx).Info(fmt.Sprintf("created %d record/s", len(imported)))
+	return nil
+}
+
+// findTag must be called while holding the lock
+func findTag(db *DB, accountID, name string) (entity.Tag, bool) {
+	for _, tag := range db.tags {
+		if tag.AccountID == accountID && *tag.Name == name {
+			return tag, true
+		}
+	}
+	return entity.Tag{}, false
+}
+
+// ensureTags creates the tags an account does not have yet; must be called while holding the lock
+func ensureTags(db *DB, accountID string, names entity.TagNames) {
+	for _, name := range names {
+		if _, ok := findTag(db, accountID, name); ok {
+			continue
+		}
+		id, createdAt, tagName := uuid.New(), now(), name
+		db.tags[id] = entity.Tag{ID: id, AccountID: accountID, Name: &tagName, CreatedAt: &createdAt}
+	}
+}
+
+// replaceLogTag renames a tag on the logs of an account, or removes it if newName is empty; must be called while
+// holding the lock
+func replaceLogTag(db *DB, accountID, oldName, newName string) {
+	for id, ocdLog := range db.ocdLogs {
+		if ocdLog.AccountID != accountID || !ocdLog.Tags.Contains(oldName) {
+			continue
+		}
+		tags := make(entity.TagNames, 0, len(ocdLog.Tags))
+		for _, name := range ocdLog.Tags {
+			if name != oldName {
+				tags = append(tags, name)
+			}
+		}
+		if newName != "" {
+			tags = append(tags, newName)
+		}
+		ocdLog.Tags = tags.Normalized()
+		db.ocdLogs[id] = ocdLog
+	}
+}
+
+// cascadeDeleteTags removes all tags of an account; must be called while holding the lock
+func cascadeDeleteTags(db *DB, accountID string) {
+	for id, tag := range db.tags {
+		if tag.AccountID == accountID {
+			delete(db.tags, id)
+		}
+	}
+}
+
+// importedTag applies the column defaults of an insert that preserves id and timestamps
+func importedTag(accountID string, tag entity.Tag) entity.Tag {
+	createdAt := now()
+	if tag.CreatedAt != nil {
+		createdAt = tag.CreatedAt.UTC().Truncate(time.Microsecond)
+	}
+	var updatedAt *time.Time
+	if tag.UpdatedAt != nil {
+		value := tag.UpdatedAt.UTC().Truncate(time.Microsecond)
+		updatedAt = &value
+	}
+	name := entity.NormalizeTagName(*tag.Name)
+	return entity.Tag{
+		ID:        tag.ID,
+		AccountID: accountID,
+		Name:      &name,
+		CreatedAt: &createdAt,
+		UpdatedAt: updatedAt,
+	}
+}
+
+func cloneTag(tag entity.Tag) *entity.Tag {
+	return &entity.Tag{
+		ID:        tag.ID,
+		AccountID: tag.AccountID,
+		Name:      clone(tag.Name),
+		CreatedAt: clone(tag.CreatedAt),
+		UpdatedAt: clone(tag.UpdatedAt),
+	}
+}
@@ -10,13 +10,15 @@ import (
 
 type AccountRepository struct {
 	DB *sql.DB
+	tx *sql.Tx // set on repositories bound to a transaction by Store.WithTx
 }
 
 var _ db.AccountRepository = (*AccountRepository)(nil)
 
 const (
-	getAccountQuery    = `SELECT id, email, created_at, updated_at, display_name, wake_time, sleep_time, notification_interval, photo_url FROM account WHERE id = $1 LIMIT 1;`
-	deleteAccountQuery = `DELETE FROM account WHERE id = $1`
+	getAccountQuery          = `SELECT id, email, created_at, updated_at, display_name, wake_time, sleep_time, notification_interval, photo_url, time_zone FROM account WHERE id = $1 LIMIT 1;`
+	deleteAccountQuery       = `DELETE FROM account WHERE id = $1`
+	getReminderAccountsQuery = `SELECT id, email, created_at, updated_at, display_name, wake_time, sleep_time, notification_interval, photo_url, time_zone FROM account WHERE notification_interval > 0 ORDER BY id ASC;`
 )
 
 func NewAccountRepository(db *sql.DB) *AccountRepository {
@@ -25,12 +27,19 @@ func NewAccountRepository(db *sql.DB) *AccountRepository {
 	}
 }
 
+func (repo *AccountRepository) conn() querier {
+	if repo.tx != nil {
+		return repo.tx
+	}
+	return repo.DB
+}
+
 func (repo *AccountRepository) CreateAccount(ctx context.Context, account *entity.Account) error {
 	pgElems, err := buildCreateQuery(account, account.ID)
 	if err != nil {
 		return err
 	}
-	err = logExec(ctx, repo.DB, pgElems.query, "create", pgElems.fieldValues...)
+	err = logExec(ctx, repo.conn(), pgElems.query, "create", pgElems.fieldValues...)
 	if err != nil {
 		return err
 	}
@@ -43,7 +52,7 @@ func (repo *AccountRepository) UpdateAccount(ctx context.Context, id string, acc
 		return err
 	}
 	if pgElems != nil {
-		err = logExec(ctx, repo.DB, pgElems.query, "update", pgElems.fieldValues...)
+		err = logExec(ctx, repo.conn(), pgElems.query, "update", pgElems.fieldValues...)
 		if err != nil {
 			return err
 		}
@@ -53,7 +62,7 @@ func (repo *AccountRepository) UpdateAccount(ctx context.Context, id string, acc
 
 func (repo *AccountRepository) GetAccount(ctx context.Context, id string) (*entity.Account, error) {
 	account := entity.Account{}
-	err := sqlscan.Get(ctx, repo.DB, &account, getAccountQuery, id)
+	err := sqlscan.Get(ctx, repo.conn(), &account, getAccountQuery, id)
 	if err != nil {
 		return nil, err
 	}
@@ -61,9 +70,19 @@ func (repo *AccountRepository) GetAccount(ctx context.Context, id string) (*enti
 }
 
 func (repo *AccountRepository) DeleteAccount(ctx context.Context, id string) error {
-	err := logExec(ctx, repo.DB, deleteAccountQuery, "delete", id)
+	err := logExec(ctx, repo.conn(), deleteAccountQuery, "delete", id)
 	if err != nil {
 		return err
 	}
 	return nil
 }
+
+// GetReminderAccounts returns every account that has check-in reminders turned on
+func (repo *AccountRepository) GetReminderAccounts(ctx context.Context) ([]entity.Account, error) {
+	accounts := make([]entity.Account, 0)
+	err := sqlscan.Select(ctx, repo.DB, &accounts, getReminderAccountsQuery)
+	if err != nil {
+		return nil, err
+	}
+	return accounts, nil
+}
@@ -0,0 +1,107 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"github.com/cecobask/ocdtracker-api/internal/db"
+	"github.com/cecobask/ocdtracker-api/pkg/entity"
+	"github.com/cecobask/ocdtracker-api/pkg/log"
+	"github.com/georgysavva/scany/sqlscan"
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+	"go.uber.org/zap"
+)
+
+type DeviceRepository struct {
+	DB *sql.DB
+}
+
+var _ db.DeviceRepository = (*DeviceRepository)(nil)
+
+const (
+	registerDeviceQuery = `WITH previous AS (SELECT account_id FROM device WHERE token = $3)
+		INSERT INTO device (id, account_id, token, platform) VALUES ($1, $2, $3, $4)
+		ON CONFLICT (token) DO UPDATE SET account_id = EXCLUDED.account_id, platform = EXCLUDED.platform, updated_at = CURRENT_TIMESTAMP
+		RETURNING id, (SELECT account_id FROM previous);`
+	getAllDevicesQuery     = `SELECT id, account_id, token, platform, created_at, updated_at FROM device WHERE account_id = $1 ORDER BY created_at ASC, id ASC;`
+	getDeviceQuery         = `SELECT id, account_id, token, platform, created_at, updated_at FROM device WHERE account_id = $1 AND id = $2 LIMIT 1;`
+	deleteDeviceQuery      = `DELETE FROM device WHERE account_id = $1 AND id = $2;`
+	deleteDeviceTokenQuery = `DELETE FROM device WHERE token = ANY($1);`
+)
+
+func NewDeviceRepository(db *sql.DB) *DeviceRepository {
+	return &DeviceRepository{
+		DB: db,
+	}
+}
+
+// RegisterDevice stores the token of a device; a token that is already registered, e.g. after another user logged in
+// on the same device, moves to the account and keeps its id
+func (repo *DeviceRepository) RegisterDevice(ctx context.Context, accountID string, device *entity.Device) error {
+	var previousAccountID sql.NullString
+	err := repo.DB.QueryRowContext(ctx, registerDeviceQuery, uuid.New(), accountID, device.Token, device.Platform).Scan(&device.ID, &previousAccountID)
+	if err != nil {
+		return translateError(err)
+	}
+	if previousAccountID.Valid {
+		logDeviceMove(ctx, device.ID, previousAccountID.String, accountID)
+	}
+	log.LoggerFromContext(ctx).Info("registered 1 device")
+	return nil
+}
+
+func (repo *DeviceRepository) GetAllDevices(ctx context.Context, accountID string) (*entity.DeviceList, error) {
+	deviceList := entity.DeviceList{
+		Devices: make([]entity.Device, 0),
+	}
+	err := sqlscan.Select(ctx, repo.DB, &deviceList.Devices, getAllDevicesQuery, accountID)
+	if err != nil {
+		return nil, err
+	}
+	return &deviceList, nil
+}
+
+func (repo *DeviceRepository) GetDevice(ctx context.Context, accountID string, id uuid.UUID) (*entity.Device, error) {
+	device := entity.Device{}
+	err := sqlscan.Get(ctx, repo.DB, &device, getDeviceQuery, accountID, id)
+	if err != nil {
+		return nil, err
+	}
+	return &device, nil
+}
+
+func (repo *DeviceRepository) DeleteDevice(ctx context.Context, accountID string, id uuid.UUID) error {
+	return logExec(ctx, repo.DB, deleteDeviceQuery, "delete", accountID, id)
+}
+
+// DeleteDeviceTokens removes the devices of any account with the given tokens, e.g. the ones the messaging service
+// reported as invalid
+func (repo *DeviceRepository) DeleteDeviceTokens(ctx context.Context, tokens []string) (int, error) {
+	if len(tokens) == 0 {
+		return 0, nil
+	}
+	result, err := repo.DB.ExecContext(ctx, deleteDeviceTokenQuery, pq.Array(tokens))
+	if err != nil {
+		return 0, err
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+	log.LoggerFromContext(ctx).Info(fmt.Sprintf("deleted %d record/s", rowsAffected))
+	return int(rowsAffected), nil
+}
+
+// logDeviceMove records a token that moved to another account, since the previous account silently stops receiving
+// notifications on that device
+func logDeviceMove(ctx context.Context, id uuid.UUID, fromAccountID, toAccountID string) {
+	if fromAccountID == toAccountID {
+		return
+	}
+	log.LoggerFromContext(ctx).Warn("device moved to another account",
+		zap.String("device_id", id.String()),
+		zap.String("from_account_id", fromAccountID),
+		zap.String("to_account_id", toAccountID),
+	)
+}
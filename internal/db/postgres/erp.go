@@ -0,0 +1,218 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"github.com/cecobask/ocdtracker-api/internal/db"
+	"github.com/cecobask/ocdtracker-api/pkg/entity"
+	"github.com/cecobask/ocdtracker-api/pkg/log"
+	"github.com/georgysavva/scany/sqlscan"
+	"github.com/google/uuid"
+)
+
+type ExposureItemRepository struct {
+	DB *sql.DB
+	tx *sql.Tx // set on repositories bound to a transaction by Store.WithTx
+}
+
+type ExposureSessionRepository struct {
+	DB *sql.DB
+	tx *sql.Tx // set on repositories bound to a transaction by Store.WithTx
+}
+
+var (
+	_ db.ExposureItemRepository    = (*ExposureItemRepository)(nil)
+	_ db.ExposureSessionRepository = (*ExposureSessionRepository)(nil)
+)
+
+const (
+	itemColumns              = `id, account_id, created_at, updated_at, description, predicted_suds`
+	getAllItemsQuery         = `SELECT ` + itemColumns + ` FROM exposure_item WHERE account_id = $1 ORDER BY predicted_suds ASC, created_at ASC, id ASC;`
+	getItemQuery             = `SELECT ` + itemColumns + ` FROM exposure_item WHERE account_id = $1 AND id = $2 LIMIT 1;`
+	deleteItemQuery          = `DELETE FROM exposure_item WHERE account_id = $1 AND id = $2;`
+	importItemQuery          = `INSERT INTO exposure_item (id, account_id, created_at, updated_at, description, predicted_suds) VALUES ($1, $2, COALESCE($3, CURRENT_TIMESTAMP), $4, $5, $6);`
+	sessionColumns           = `id, account_id, item_id, created_at, updated_at, started_at, duration_minutes, suds_start, suds_peak, suds_end, notes`
+	sessionFilterClause      = `account_id = $1 AND ($2::uuid IS NULL OR item_id = $2)`
+	getAllSessionsQuery      = `SELECT ` + sessionColumns + ` FROM exposure_session WHERE ` + sessionFilterClause + ` ORDER BY started_at DESC, id ASC LIMIT $3 OFFSET $4;`
+	getSessionsRowCountQuery = `SELECT count(*) FROM exposure_session WHERE ` + sessionFilterClause + `;`
+	getSessionQuery          = `SELECT ` + sessionColumns + ` FROM exposure_session WHERE account_id = $1 AND id = $2 LIMIT 1;`
+	deleteSessionQuery       = `DELETE FROM exposure_session WHERE account_id = $1 AND id = $2;`
+	importSessionQuery       = `INSERT INTO exposure_session (id, account_id, item_id, created_at, updated_at, started_at, duration_minutes, suds_start, suds_peak, suds_end, notes) SELECT $1, account_id, id, COALESCE($4, CURRENT_TIMESTAMP), $5, COALESCE($6, $4, CURRENT_TIMESTAMP), $7, $8, $9, $10, $11 FROM exposure_item WHERE id = $3 AND account_id = $2;`
+)
+
+func NewExposureItemRepository(db *sql.DB) *ExposureItemRepository {
+	return &ExposureItemRepository{
+		DB: db,
+	}
+}
+
+func NewExposureSessionRepository(db *sql.DB) *ExposureSessionRepository {
+	return &ExposureSessionRepository{
+		DB: db,
+	}
+}
+
+func (repo *ExposureItemRepository) conn() querier {
+	if repo.tx != nil {
+		return repo.tx
+	}
+	return repo.DB
+}
+
+func (repo *ExposureItemRepository) CreateItem(ctx context.Context, accountID string, item *entity.ExposureItem) error {
+	item.ID = uuid.New()
+	pgElems, err := buildCreateQuery(item, accountID)
+	if err != nil {
+		return err
+	}
+	return logExec(ctx, repo.conn(), pgElems.query, "create", pgElems.fieldValues...)
+}
+
+func (repo *ExposureItemRepository) GetAllItems(ctx context.Context, accountID string) (*entity.ExposureItemList, error) {
+	itemList := entity.ExposureItemList{
+		Items: make([]entity.ExposureItem, 0),
+	}
+	err := sqlscan.Select(ctx, repo.conn(), &itemList.Items, getAllItemsQuery, accountID)
+	if err != nil {
+		return nil, err
+	}
+	return &itemList, nil
+}
+
+func (repo *ExposureItemRepository) GetItem(ctx context.Context, accountID string, id uuid.UUID) (*entity.ExposureItem, error) {
+	item := entity.ExposureItem{}
+	err := sqlscan.Get(ctx, repo.conn(), &item, getItemQuery, accountID, id)
+	if err != nil {
+		return nil, err
+	}
+	return &item, nil
+}
+
+func (repo *ExposureItemRepository) UpdateItem(ctx context.Context, accountID string, id uuid.UUID, item *entity.ExposureItem) error {
+	pgElems, err := buildUpdateQuery(item, accountID, &id)
+	if err != nil {
+		return err
+	}
+	if pgElems == nil {
+		return nil // no action
+	}
+	return logExec(ctx, repo.conn(), pgElems.query, "update", pgElems.fieldValues...)
+}
+
+// DeleteItem removes an item from the hierarchy together with its sessions
+func (repo *ExposureItemRepository) DeleteItem(ctx context.Context, accountID string, id uuid.UUID) error {
+	return logExec(ctx, repo.conn(), deleteItemQuery, "delete", accountID, id)
+}
+
+// ImportItems inserts hierarchy items with their ids and timestamps preserved, all or none of them
+func (repo *ExposureItemRepository) ImportItems(ctx context.Context, accountID string, items []entity.ExposureItem) error {
+	insert := func(conn querier) error {
+		for _, item := range items {
+			err := logExec(ctx, conn, importItemQuery, "create", item.ID, accountID, item.CreatedAt, item.UpdatedAt, item.Description, item.PredictedSUDS)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	if repo.tx != nil {
+		return insert(repo.tx)
+	}
+	return withTx(ctx, repo.DB, func(tx *sql.Tx) error {
+		return insert(tx)
+	})
+}
+
+func (repo *ExposureSessionRepository) conn() querier {
+	if repo.tx != nil {
+		return repo.tx
+	}
+	return repo.DB
+}
+
+func (repo *ExposureSessionRepository) CreateSession(ctx context.Context, accountID string, session *entity.ExposureSession) error {
+	session.ID = uuid.New()
+	pgElems, err := buildCreateQuery(session, accountID)
+	if err != nil {
+		return err
+	}
+	return logExec(ctx, repo.conn(), pgElems.query, "create", pgElems.fieldValues...)
+}
+
+func (repo *ExposureSessionRepository) GetAllSessions(ctx context.Context, accountID string, filter entity.ExposureSessionFilter, limit, offset int) (*entity.ExposureSessionList, error) {
+	var rowCount int
+	err := sqlscan.Get(ctx, repo.conn(), &rowCount, getSessionsRowCountQuery, accountID, filter.ItemID)
+	if err != nil {
+		return nil, err
+	}
+	sessions := make([]entity.ExposureSession, 0)
+	err = sqlscan.Select(ctx, repo.conn(), &sessions, getAllSessionsQuery, accountID, filter.ItemID, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	log.LoggerFromContext(ctx).Info(fmt.Sprintf("retrieved %d exposure sessions", len(sessions)))
+	return &entity.ExposureSessionList{
+		Sessions: sessions,
+		Pagination: entity.PaginationDetails{
+			Limit:  limit,
+			Offset: &offset,
+			Count:  len(sessions),
+			Total:  &rowCount,
+		},
+	}, nil
+}
+
+func (repo *ExposureSessionRepository) GetSession(ctx context.Context, accountID string, id uuid.UUID) (*entity.ExposureSession, error) {
+	session := entity.ExposureSession{}
+	err := sqlscan.Get(ctx, repo.conn(), &session, getSessionQuery, accountID, id)
+	if err != nil {
+		return nil, err
+	}
+	return &session, nil
+}
+
+func (repo *ExposureSessionRepository) UpdateSession(ctx context.Context, accountID string, id uuid.UUID, session *entity.ExposureSession) error {
+	pgElems, err := buildUpdateQuery(session, accountID, &id)
+	if err != nil {
+		return err
+	}
+	if pgElems == nil {
+		return nil // no action
+	}
+	return logExec(ctx, repo.conn(), pgElems.query, "update", pgElems.fieldValues...)
+}
+
+func (repo *ExposureSessionRepository) DeleteSession(ctx context.Context, accountID string, id uuid.UUID) error {
+	return logExec(ctx, repo.conn(), deleteSessionQuery, "delete", accountID, id)
+}
+
+// ImportSessions inserts sessions with their ids and timestamps preserved, all or none of them; it returns
+// sql.ErrNoRows if a session belongs to an item that the account does not have
+func (repo *ExposureSessionRepository) ImportSessions(ctx context.Context, accountID string, sessions []entity.ExposureSession) error {
+	insert := func(conn querier) error {
+		for _, session := range sessions {
+			result, err := conn.ExecContext(ctx, importSessionQuery, session.ID, accountID, session.ItemID, session.CreatedAt, session.UpdatedAt,
+				session.StartedAt, session.DurationMinutes, session.SUDSStart, session.SUDSPeak, session.SUDSEnd, session.Notes,
+			)
+			if err != nil {
+				return translateError(err)
+			}
+			rowsAffected, err := result.RowsAffected()
+			if err != nil {
+				return err
+			}
+			if rowsAffected == 0 {
+				return fmt.Errorf("session %s of item %s: %w", session.ID, *session.ItemID, sql.ErrNoRows)
+			}
+		}
+		log.LoggerFromContext(ctx).Info(fmt.Sprintf("created %d record/s", len(sessions)))
+		return nil
+	}
+	if repo.tx != nil {
+		return insert(repo.tx)
+	}
+	return withTx(ctx, repo.DB, func(tx *sql.Tx) error {
+		return insert(tx)
+	})
+}
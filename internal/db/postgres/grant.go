@@ -0,0 +1,136 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"github.com/cecobask/ocdtracker-api/internal/db"
+	"github.com/cecobask/ocdtracker-api/pkg/entity"
+	"github.com/cecobask/ocdtracker-api/pkg/log"
+	"github.com/georgysavva/scany/sqlscan"
+	"github.com/google/uuid"
+)
+
+type GrantRepository struct {
+	DB *sql.DB
+}
+
+var _ db.GrantRepository = (*GrantRepository)(nil)
+
+const (
+	createGrantQuery       = `INSERT INTO access_grant (id, account_id, email, code, scopes, expires_at) VALUES ($1, $2, $3, $4, $5, $6);`
+	getAllGrantsQuery      = `SELECT id, account_id, clinician_id, email, code, scopes, created_at, accepted_at, expires_at FROM access_grant WHERE account_id = $1 ORDER BY created_at DESC, id ASC;`
+	getGrantQuery          = `SELECT id, account_id, clinician_id, email, code, scopes, created_at, accepted_at, expires_at FROM access_grant WHERE id = $1 LIMIT 1;`
+	getGrantByCodeQuery    = `SELECT id, account_id, clinician_id, email, code, scopes, created_at, accepted_at, expires_at FROM access_grant WHERE code = $1 LIMIT 1;`
+	deleteGrantQuery       = `DELETE FROM access_grant WHERE account_id = $1 AND id = $2;`
+	deleteOtherGrantsQuery = `DELETE FROM access_grant WHERE clinician_id = $2 AND id <> $1 AND account_id = (SELECT account_id FROM access_grant WHERE id = $1);`
+	acceptGrantQuery       = `UPDATE access_grant SET clinician_id = $2, code = NULL, accepted_at = CURRENT_TIMESTAMP WHERE id = $1 AND clinician_id IS NULL AND (expires_at IS NULL OR expires_at > CURRENT_TIMESTAMP);`
+	getActiveGrantQuery    = `SELECT id, account_id, clinician_id, email, code, scopes, created_at, accepted_at, expires_at FROM access_grant WHERE account_id = $1 AND clinician_id = $2 AND (expires_at IS NULL OR expires_at > CURRENT_TIMESTAMP) LIMIT 1;`
+	getPatientsQuery       = `SELECT g.account_id, a.email, a.display_name, g.id AS grant_id, g.scopes, g.accepted_at, g.expires_at FROM access_grant g JOIN account a ON a.id = g.account_id
+		WHERE g.clinician_id = $1 AND (g.expires_at IS NULL OR g.expires_at > CURRENT_TIMESTAMP) ORDER BY g.accepted_at DESC, g.id ASC;`
+	getInvitationsQuery = `SELECT g.account_id, a.email, a.display_name, g.id AS grant_id, g.scopes, g.accepted_at, g.expires_at FROM access_grant g JOIN account a ON a.id = g.account_id
+		WHERE g.clinician_id IS NULL AND lower(g.email) = lower($1) AND (g.expires_at IS NULL OR g.expires_at > CURRENT_TIMESTAMP) ORDER BY g.created_at DESC, g.id ASC;`
+)
+
+func NewGrantRepository(db *sql.DB) *GrantRepository {
+	return &GrantRepository{
+		DB: db,
+	}
+}
+
+func (repo *GrantRepository) CreateGrant(ctx context.Context, accountID string, grant *entity.Grant) error {
+	grant.ID = uuid.New()
+	return logExec(ctx, repo.DB, createGrantQuery, "create", grant.ID, accountID, grant.Email, grant.Code, grant.Scopes, grant.ExpiresAt)
+}
+
+func (repo *GrantRepository) GetAllGrants(ctx context.Context, accountID string) (*entity.GrantList, error) {
+	grantList := entity.GrantList{
+		Grants: make([]entity.Grant, 0),
+	}
+	err := sqlscan.Select(ctx, repo.DB, &grantList.Grants, getAllGrantsQuery, accountID)
+	if err != nil {
+		return nil, err
+	}
+	return &grantList, nil
+}
+
+// GetGrant returns a grant of any account; callers check that it concerns the requesting account
+func (repo *GrantRepository) GetGrant(ctx context.Context, id uuid.UUID) (*entity.Grant, error) {
+	grant := entity.Grant{}
+	err := sqlscan.Get(ctx, repo.DB, &grant, getGrantQuery, id)
+	if err != nil {
+		return nil, err
+	}
+	return &grant, nil
+}
+
+func (repo *GrantRepository) GetGrantByCode(ctx context.Context, code string) (*entity.Grant, error) {
+	grant := entity.Grant{}
+	err := sqlscan.Get(ctx, repo.DB, &grant, getGrantByCodeQuery, code)
+	if err != nil {
+		return nil, err
+	}
+	return &grant, nil
+}
+
+// DeleteGrant revokes a grant, or withdraws an invitation that is still pending
+func (repo *GrantRepository) DeleteGrant(ctx context.Context, accountID string, id uuid.UUID) error {
+	return logExec(ctx, repo.DB, deleteGrantQuery, "delete", accountID, id)
+}
+
+// AcceptGrant makes a pending invitation active for the clinician, replacing any earlier grant between the same
+// accounts; it returns sql.ErrNoRows if the invitation has been accepted or has expired in the meantime
+func (repo *GrantRepository) AcceptGrant(ctx context.Context, id uuid.UUID, clinicianID string) error {
+	return withTx(ctx, repo.DB, func(tx *sql.Tx) error {
+		err := logExec(ctx, tx, deleteOtherGrantsQuery, "delete", id, clinicianID)
+		if err != nil {
+			return err
+		}
+		result, err := tx.ExecContext(ctx, acceptGrantQuery, id, clinicianID)
+		if err != nil {
+			return translateError(err)
+		}
+		rowsAffected, err := result.RowsAffected()
+		if err != nil {
+			return err
+		}
+		if rowsAffected == 0 {
+			return sql.ErrNoRows
+		}
+		log.LoggerFromContext(ctx).Info("accepted 1 grant")
+		return nil
+	})
+}
+
+// GetActiveGrant returns the accepted, unexpired grant that gives the clinician access to the account
+func (repo *GrantRepository) GetActiveGrant(ctx context.Context, accountID, clinicianID string) (*entity.Grant, error) {
+	grant := entity.Grant{}
+	err := sqlscan.Get(ctx, repo.DB, &grant, getActiveGrantQuery, accountID, clinicianID)
+	if err != nil {
+		return nil, err
+	}
+	return &grant, nil
+}
+
+// GetPatients returns the accounts that currently share their data with the clinician
+func (repo *GrantRepository) GetPatients(ctx context.Context, clinicianID string) (*entity.PatientList, error) {
+	patientList := entity.PatientList{
+		Patients: make([]entity.Patient, 0),
+	}
+	err := sqlscan.Select(ctx, repo.DB, &patientList.Patients, getPatientsQuery, clinicianID)
+	if err != nil {
+		return nil, err
+	}
+	return &patientList, nil
+}
+
+// GetInvitations returns the pending, unexpired invitations addressed to the email
+func (repo *GrantRepository) GetInvitations(ctx context.Context, email string) (*entity.PatientList, error) {
+	patientList := entity.PatientList{
+		Patients: make([]entity.Patient, 0),
+	}
+	err := sqlscan.Select(ctx, repo.DB, &patientList.Patients, getInvitationsQuery, email)
+	if err != nil {
+		return nil, err
+	}
+	return &patientList, nil
+}
@@ -0,0 +1,2 @@
+ALTER TABLE ocdlog ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMP;
+CREATE INDEX IF NOT EXISTS ocdlog_deleted_at_idx ON ocdlog(deleted_at) WHERE deleted_at IS NOT NULL;
\ No newline at end of file
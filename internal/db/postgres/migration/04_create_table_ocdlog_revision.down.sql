@@ -0,0 +1 @@
+DROP TABLE IF EXISTS ocdlog_revision;
\ No newline at end of file
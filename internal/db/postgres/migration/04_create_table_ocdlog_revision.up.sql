@@ -0,0 +1,13 @@
+CREATE TABLE IF NOT EXISTS ocdlog_revision(
+    ocdlog_id UUID REFERENCES ocdlog(id) ON DELETE CASCADE NOT NULL,
+    revision INTEGER NOT NULL,
+    changed_by VARCHAR(128) NOT NULL,
+    changed_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    old_ruminate_minutes INTEGER NOT NULL,
+    old_anxiety_level INTEGER NOT NULL,
+    old_notes TEXT,
+    new_ruminate_minutes INTEGER NOT NULL,
+    new_anxiety_level INTEGER NOT NULL,
+    new_notes TEXT,
+    PRIMARY KEY (ocdlog_id, revision)
+);
\ No newline at end of file
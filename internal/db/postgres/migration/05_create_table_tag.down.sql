@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS ocdlog_tag;
+DROP TABLE IF EXISTS tag;
\ No newline at end of file
@@ -0,0 +1,14 @@
+CREATE TABLE IF NOT EXISTS tag(
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    account_id VARCHAR(128) REFERENCES account(id) ON DELETE CASCADE NOT NULL,
+    name VARCHAR(64) NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP,
+    UNIQUE (account_id, name)
+);
+CREATE TABLE IF NOT EXISTS ocdlog_tag(
+    ocdlog_id UUID REFERENCES ocdlog(id) ON DELETE CASCADE NOT NULL,
+    tag_id UUID REFERENCES tag(id) ON DELETE CASCADE NOT NULL,
+    PRIMARY KEY (ocdlog_id, tag_id)
+);
+CREATE INDEX IF NOT EXISTS ocdlog_tag_tag_id_idx ON ocdlog_tag(tag_id);
\ No newline at end of file
@@ -0,0 +1,2 @@
+ALTER TABLE ocdlog_revision DROP COLUMN IF EXISTS new_tags;
+ALTER TABLE ocdlog_revision DROP COLUMN IF EXISTS old_tags;
\ No newline at end of file
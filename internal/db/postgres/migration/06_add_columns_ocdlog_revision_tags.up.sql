@@ -0,0 +1,2 @@
+ALTER TABLE ocdlog_revision ADD COLUMN IF NOT EXISTS old_tags JSONB;
+ALTER TABLE ocdlog_revision ADD COLUMN IF NOT EXISTS new_tags JSONB;
\ No newline at end of file
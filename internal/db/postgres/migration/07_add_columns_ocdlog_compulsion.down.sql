@@ -0,0 +1,4 @@
+ALTER TABLE ocdlog DROP COLUMN IF EXISTS resisted_minutes;
+ALTER TABLE ocdlog DROP COLUMN IF EXISTS resisted;
+ALTER TABLE ocdlog DROP COLUMN IF EXISTS urge_intensity;
+ALTER TABLE ocdlog DROP COLUMN IF EXISTS compulsion_type;
\ No newline at end of file
@@ -0,0 +1,4 @@
+ALTER TABLE ocdlog ADD COLUMN IF NOT EXISTS compulsion_type VARCHAR(32);
+ALTER TABLE ocdlog ADD COLUMN IF NOT EXISTS urge_intensity INTEGER;
+ALTER TABLE ocdlog ADD COLUMN IF NOT EXISTS resisted BOOLEAN;
+ALTER TABLE ocdlog ADD COLUMN IF NOT EXISTS resisted_minutes INTEGER;
\ No newline at end of file
@@ -0,0 +1,8 @@
+ALTER TABLE ocdlog_revision DROP COLUMN IF EXISTS new_resisted_minutes;
+ALTER TABLE ocdlog_revision DROP COLUMN IF EXISTS new_resisted;
+ALTER TABLE ocdlog_revision DROP COLUMN IF EXISTS new_urge_intensity;
+ALTER TABLE ocdlog_revision DROP COLUMN IF EXISTS new_compulsion_type;
+ALTER TABLE ocdlog_revision DROP COLUMN IF EXISTS old_resisted_minutes;
+ALTER TABLE ocdlog_revision DROP COLUMN IF EXISTS old_resisted;
+ALTER TABLE ocdlog_revision DROP COLUMN IF EXISTS old_urge_intensity;
+ALTER TABLE ocdlog_revision DROP COLUMN IF EXISTS old_compulsion_type;
\ No newline at end of file
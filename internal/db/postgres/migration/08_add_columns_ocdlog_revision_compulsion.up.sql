@@ -0,0 +1,8 @@
+ALTER TABLE ocdlog_revision ADD COLUMN IF NOT EXISTS old_compulsion_type VARCHAR(32);
+ALTER TABLE ocdlog_revision ADD COLUMN IF NOT EXISTS old_urge_intensity INTEGER;
+ALTER TABLE ocdlog_revision ADD COLUMN IF NOT EXISTS old_resisted BOOLEAN;
+ALTER TABLE ocdlog_revision ADD COLUMN IF NOT EXISTS old_resisted_minutes INTEGER;
+ALTER TABLE ocdlog_revision ADD COLUMN IF NOT EXISTS new_compulsion_type VARCHAR(32);
+ALTER TABLE ocdlog_revision ADD COLUMN IF NOT EXISTS new_urge_intensity INTEGER;
+ALTER TABLE ocdlog_revision ADD COLUMN IF NOT EXISTS new_resisted BOOLEAN;
+ALTER TABLE ocdlog_revision ADD COLUMN IF NOT EXISTS new_resisted_minutes INTEGER;
\ No newline at end of file
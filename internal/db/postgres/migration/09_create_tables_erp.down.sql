@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS exposure_session;
+DROP TABLE IF EXISTS exposure_item;
\ No newline at end of file
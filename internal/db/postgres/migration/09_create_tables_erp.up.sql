@@ -0,0 +1,22 @@
+CREATE TABLE IF NOT EXISTS exposure_item(
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    account_id VARCHAR(128) REFERENCES account(id) ON DELETE CASCADE NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP,
+    description VARCHAR(500) NOT NULL,
+    predicted_suds INTEGER NOT NULL
+);
+CREATE TABLE IF NOT EXISTS exposure_session(
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    account_id VARCHAR(128) REFERENCES account(id) ON DELETE CASCADE NOT NULL,
+    item_id UUID REFERENCES exposure_item(id) ON DELETE CASCADE NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP,
+    started_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    duration_minutes INTEGER NOT NULL,
+    suds_start INTEGER NOT NULL,
+    suds_peak INTEGER NOT NULL,
+    suds_end INTEGER NOT NULL,
+    notes TEXT
+);
+CREATE INDEX IF NOT EXISTS exposure_session_account_id_started_at_idx ON exposure_session(account_id, started_at);
\ No newline at end of file
@@ -0,0 +1 @@
+DROP TABLE IF EXISTS questionnaire_response;
\ No newline at end of file
@@ -0,0 +1,12 @@
+CREATE TABLE IF NOT EXISTS questionnaire_response(
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    account_id VARCHAR(128) REFERENCES account(id) ON DELETE CASCADE NOT NULL,
+    questionnaire_id VARCHAR(32) NOT NULL,
+    questionnaire_version INTEGER NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    answers JSONB NOT NULL,
+    total_score INTEGER NOT NULL,
+    subscale_scores JSONB NOT NULL,
+    severity VARCHAR(64)
+);
+CREATE INDEX IF NOT EXISTS questionnaire_response_account_id_questionnaire_id_created_at_idx ON questionnaire_response(account_id, questionnaire_id, created_at);
\ No newline at end of file
@@ -0,0 +1 @@
+DROP TABLE IF EXISTS device;
\ No newline at end of file
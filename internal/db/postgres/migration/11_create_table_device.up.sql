@@ -0,0 +1,9 @@
+CREATE TABLE IF NOT EXISTS device(
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    account_id VARCHAR(128) REFERENCES account(id) ON DELETE CASCADE NOT NULL,
+    token TEXT UNIQUE NOT NULL,
+    platform VARCHAR(16),
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP
+);
+CREATE INDEX IF NOT EXISTS device_account_id_idx ON device(account_id);
\ No newline at end of file
@@ -0,0 +1,14 @@
+CREATE TABLE IF NOT EXISTS access_grant(
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    account_id VARCHAR(128) REFERENCES account(id) ON DELETE CASCADE NOT NULL,
+    clinician_id VARCHAR(128) REFERENCES account(id) ON DELETE CASCADE,
+    email VARCHAR(90),
+    code VARCHAR(32) UNIQUE,
+    scopes JSONB NOT NULL,
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    accepted_at TIMESTAMPTZ,
+    expires_at TIMESTAMPTZ,
+    UNIQUE (account_id, clinician_id)
+);
+CREATE INDEX IF NOT EXISTS access_grant_clinician_id_idx ON access_grant(clinician_id);
+CREATE INDEX IF NOT EXISTS access_grant_email_idx ON access_grant(lower(email)) WHERE clinician_id IS NULL;
\ No newline at end of file
@@ -3,26 +3,83 @@ package postgres
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
 	"github.com/cecobask/ocdtracker-api/internal/db"
 	"github.com/cecobask/ocdtracker-api/pkg/entity"
 	"github.com/cecobask/ocdtracker-api/pkg/log"
 	"github.com/georgysavva/scany/sqlscan"
 	"github.com/google/uuid"
+	"github.com/lib/pq"
+	"strings"
+	"time"
 )
 
 type OCDLogRepository struct {
 	DB *sql.DB
+	tx *sql.Tx // set on repositories bound to a transaction by WithTx
+}
+
+// revisionRow is the flat layout of ocdlog_revision
+type revisionRow struct {
+	OCDLogID           uuid.UUID `db:"ocdlog_id"`
+	Revision           int
+	ChangedBy          string
+	ChangedAt          time.Time
+	OldRuminateMinutes int
+	OldAnxietyLevel    int
+	OldNotes           *string
+	OldCompulsionType  *string
+	OldUrgeIntensity   *int
+	OldResisted        *bool
+	OldResistedMinutes *int
+	NewRuminateMinutes int
+	NewAnxietyLevel    int
+	NewNotes           *string
+	NewCompulsionType  *string
+	NewUrgeIntensity   *int
+	NewResisted        *bool
+	NewResistedMinutes *int
+	OldTags            entity.TagNames
+	NewTags            entity.TagNames
 }
 
 var _ db.OCDLogRepository = (*OCDLogRepository)(nil)
 
 const (
-	deleteAllLogsQuery = `DELETE FROM ocdlog WHERE account_id = $1;`
-	deleteLogQuery     = `DELETE FROM ocdlog WHERE account_id = $1 AND id = $2;`
-	getAllLogsQuery    = `SELECT id, account_id, created_at, updated_at, ruminate_minutes, anxiety_level, notes FROM ocdlog WHERE account_id = $1 ORDER BY created_at LIMIT $2 OFFSET $3;`
-	getLogQuery        = `SELECT id, account_id, created_at, updated_at, ruminate_minutes, anxiety_level, notes FROM ocdlog WHERE account_id = $1 AND id = $2 LIMIT 1;`
-	getRowCountQuery   = `SELECT count(*) FROM ocdlog WHERE account_id = $1;`
+	logColumns = `id, account_id, created_at, updated_at, ruminate_minutes, anxiety_level, notes, compulsion_type, urge_intensity, resisted, resisted_minutes`
+	// tagsColumn selects the names of a log's tags as a json array, scanned by entity.TagNames
+	tagsColumn = `COALESCE((SELECT json_agg(t.name ORDER BY t.name) FROM ocdlog_tag lt JOIN tag t ON t.id = lt.tag_id WHERE lt.ocdlog_id = ocdlog.id), '[]') AS tags`
+)
+
+const (
+	deleteAllLogsQuery       = `UPDATE ocdlog SET deleted_at = CURRENT_TIMESTAMP WHERE account_id = $1 AND deleted_at IS NULL;`
+	deleteLogQuery           = `UPDATE ocdlog SET deleted_at = CURRENT_TIMESTAMP WHERE account_id = $1 AND id = $2 AND deleted_at IS NULL;`
+	getAllLogsQuery          = `SELECT ` + logColumns + `, ` + tagsColumn + ` FROM ocdlog WHERE %s ORDER BY %s LIMIT $%d OFFSET $%d;`
+	getLogQuery              = `SELECT ` + logColumns + `, ` + tagsColumn + ` FROM ocdlog WHERE account_id = $1 AND id = $2 AND deleted_at IS NULL LIMIT 1;`
+	getRowCountQuery         = `SELECT count(*) FROM ocdlog WHERE %s;`
+	streamLogsQuery          = `SELECT ` + logColumns + `, ` + tagsColumn + ` FROM ocdlog WHERE %s ORDER BY %s;`
+	importLogQuery           = `INSERT INTO ocdlog (id, account_id, created_at, updated_at, ruminate_minutes, anxiety_level, notes, compulsion_type, urge_intensity, resisted, resisted_minutes, deleted_at) VALUES ($1, $2, COALESCE($3, CURRENT_TIMESTAMP), $4, COALESCE($5, 0), COALESCE($6, 0), $7, $8, $9, $10, $11, $12);`
+	getLogStatsQuery         = `SELECT count(*) AS count, COALESCE(avg(anxiety_level), 0) AS avg_anxiety_level, COALESCE(percentile_cont(0.5) WITHIN GROUP (ORDER BY anxiety_level), 0) AS median_anxiety_level, COALESCE(max(anxiety_level), 0) AS max_anxiety_level, COALESCE(sum(ruminate_minutes), 0) AS total_ruminate_minutes, COALESCE(avg(ruminate_minutes), 0) AS avg_ruminate_minutes, avg(urge_intensity) AS avg_urge_intensity, count(*) FILTER (WHERE resisted) AS resisted_count, avg(resisted::int) AS resistance_rate, COALESCE(sum(resisted_minutes), 0) AS total_resisted_minutes FROM ocdlog WHERE %s;`
+	getAnxietyHistogramQuery = `SELECT anxiety_level, count(*) AS count FROM ocdlog WHERE %s GROUP BY anxiety_level;`
+	getLogSeriesQuery        = `SELECT %s AS bucket_start, count(*) AS count, avg(anxiety_level) AS avg_anxiety_level, sum(ruminate_minutes) AS total_ruminate_minutes, avg(resisted::int) AS resistance_rate FROM ocdlog WHERE %s GROUP BY bucket_start ORDER BY bucket_start;`
+	getLogsByCursorQuery     = `SELECT ` + logColumns + `, ` + tagsColumn + ` FROM ocdlog WHERE %s ORDER BY created_at %s, id %s LIMIT $%d;`
+	getTrashedLogsQuery      = `SELECT ` + logColumns + `, deleted_at, ` + tagsColumn + ` FROM ocdlog WHERE account_id = $1 AND deleted_at IS NOT NULL ORDER BY deleted_at DESC, id ASC LIMIT $2 OFFSET $3;`
+	getTrashedRowCountQuery  = `SELECT count(*) FROM ocdlog WHERE account_id = $1 AND deleted_at IS NOT NULL;`
+	restoreLogQuery          = `UPDATE ocdlog SET deleted_at = NULL WHERE account_id = $1 AND id = $2 AND deleted_at IS NOT NULL;`
+	purgeTrashedLogsQuery    = `DELETE FROM ocdlog WHERE deleted_at IS NOT NULL AND deleted_at < $1;`
+	getCompulsionStatsQuery  = `SELECT compulsion_type, count(*) AS count, avg(urge_intensity) AS avg_urge_intensity, avg(resisted::int) AS resistance_rate FROM ocdlog WHERE %s AND compulsion_type IS NOT NULL GROUP BY compulsion_type ORDER BY count DESC, compulsion_type ASC;`
+	getTagStatsQuery         = `SELECT t.name AS tag, count(*) AS count, avg(l.anxiety_level) AS avg_anxiety_level, sum(l.ruminate_minutes) AS total_ruminate_minutes FROM (SELECT id, anxiety_level, ruminate_minutes FROM ocdlog WHERE %s) l JOIN ocdlog_tag lt ON lt.ocdlog_id = l.id JOIN tag t ON t.id = lt.tag_id GROUP BY t.name ORDER BY count DESC, t.name ASC;`
+	touchLogQuery            = `UPDATE ocdlog SET updated_at = CURRENT_TIMESTAMP WHERE account_id = $1 AND id = $2;`
+	deleteLogTagsQuery       = `DELETE FROM ocdlog_tag WHERE ocdlog_id = $1;`
+	ensureTagQuery           = `INSERT INTO tag (account_id, name) VALUES ($1, $2) ON CONFLICT (account_id, name) DO NOTHING;`
+	createLogTagQuery        = `INSERT INTO ocdlog_tag (ocdlog_id, tag_id) SELECT $1, id FROM tag WHERE account_id = $2 AND name = $3 ON CONFLICT DO NOTHING;`
+	lockLogQuery             = `SELECT ` + logColumns + `, ` + tagsColumn + ` FROM ocdlog WHERE account_id = $1 AND id = $2 AND deleted_at IS NULL FOR UPDATE;`
+	createRevisionQuery      = `INSERT INTO ocdlog_revision (ocdlog_id, revision, changed_by, old_ruminate_minutes, old_anxiety_level, old_notes, old_compulsion_type, old_urge_intensity, old_resisted, old_resisted_minutes, new_ruminate_minutes, new_anxiety_level, new_notes, new_compulsion_type, new_urge_intensity, new_resisted, new_resisted_minutes, old_tags, new_tags) VALUES ($1, (SELECT COALESCE(max(revision), 0) + 1 FROM ocdlog_revision WHERE ocdlog_id = $1), $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18);`
+	getRevisionsQuery        = `SELECT r.ocdlog_id, r.revision, r.changed_by, r.changed_at, r.old_ruminate_minutes, r.old_anxiety_level, r.old_notes, r.old_compulsion_type, r.old_urge_intensity, r.old_resisted, r.old_resisted_minutes, r.new_ruminate_minutes, r.new_anxiety_level, r.new_notes, r.new_compulsion_type, r.new_urge_intensity, r.new_resisted, r.new_resisted_minutes, r.old_tags, r.new_tags FROM ocdlog_revision r JOIN ocdlog l ON l.id = r.ocdlog_id WHERE l.account_id = $1 AND r.ocdlog_id = $2 ORDER BY r.revision ASC;`
+	getAccountRevisionsQuery = `SELECT r.ocdlog_id, r.revision, r.changed_by, r.changed_at, r.old_ruminate_minutes, r.old_anxiety_level, r.old_notes, r.old_compulsion_type, r.old_urge_intensity, r.old_resisted, r.old_resisted_minutes, r.new_ruminate_minutes, r.new_anxiety_level, r.new_notes, r.new_compulsion_type, r.new_urge_intensity, r.new_resisted, r.new_resisted_minutes, r.old_tags, r.new_tags FROM ocdlog_revision r JOIN ocdlog l ON l.id = r.ocdlog_id WHERE l.account_id = $1 ORDER BY r.ocdlog_id ASC, r.revision ASC;`
+	importRevisionQuery      = `INSERT INTO ocdlog_revision (ocdlog_id, revision, changed_by, changed_at, old_ruminate_minutes, old_anxiety_level, old_notes, old_compulsion_type, old_urge_intensity, old_resisted, old_resisted_minutes, new_ruminate_minutes, new_anxiety_level, new_notes, new_compulsion_type, new_urge_intensity, new_resisted, new_resisted_minutes, old_tags, new_tags) SELECT id, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19, $20, $21 FROM ocdlog WHERE id = $1 AND account_id = $2;`
+	getRevisionQuery         = `SELECT r.ocdlog_id, r.revision, r.changed_by, r.changed_at, r.old_ruminate_minutes, r.old_anxiety_level, r.old_notes, r.old_compulsion_type, r.old_urge_intensity, r.old_resisted, r.old_resisted_minutes, r.new_ruminate_minutes, r.new_anxiety_level, r.new_notes, r.new_compulsion_type, r.new_urge_intensity, r.new_resisted, r.new_resisted_minutes, r.old_tags, r.new_tags FROM ocdlog_revision r JOIN ocdlog l ON l.id = r.ocdlog_id WHERE l.account_id = $1 AND r.ocdlog_id = $2 AND r.revision = $3 LIMIT 1;`
 )
 
 func NewOCDLogRepository(db *sql.DB) *OCDLogRepository {
@@ -31,22 +88,48 @@ func NewOCDLogRepository(db *sql.DB) *OCDLogRepository {
 	}
 }
 
-func (repo *OCDLogRepository) GetAllLogs(ctx context.Context, accountID string, limit, offset int) (*entity.OCDLogList, error) {
+// WithTx runs fn with a repository whose operations are applied atomically; a repository that is already bound to a
+// transaction reuses it
+func (repo *OCDLogRepository) WithTx(ctx context.Context, fn func(txRepo db.OCDLogRepository) error) error {
+	return repo.inTx(ctx, func(txRepo *OCDLogRepository) error {
+		return fn(txRepo)
+	})
+}
+
+func (repo *OCDLogRepository) inTx(ctx context.Context, fn func(txRepo *OCDLogRepository) error) error {
+	if repo.tx != nil {
+		return fn(repo)
+	}
+	return withTx(ctx, repo.DB, func(tx *sql.Tx) error {
+		return fn(&OCDLogRepository{DB: repo.DB, tx: tx})
+	})
+}
+
+func (repo *OCDLogRepository) conn() querier {
+	if repo.tx != nil {
+		return repo.tx
+	}
+	return repo.DB
+}
+
+func (repo *OCDLogRepository) GetAllLogs(ctx context.Context, accountID string, filter entity.OCDLogFilter, sort entity.OCDLogSort, limit, offset int) (*entity.OCDLogList, error) {
 	ocdLogList := entity.OCDLogList{
 		Logs: make([]entity.OCDLog, 0),
 	}
+	whereClause, args := buildLogFilterClause(accountID, filter)
 	var rowCount int
-	err := sqlscan.Get(ctx, repo.DB, &rowCount, getRowCountQuery, accountID)
+	err := sqlscan.Get(ctx, repo.conn(), &rowCount, fmt.Sprintf(getRowCountQuery, whereClause), args...)
 	if err != nil {
 		return nil, err
 	}
 	paginationDetails := entity.PaginationDetails{
 		Limit:  limit,
-		Offset: offset,
-		Total:  rowCount,
+		Offset: &offset,
+		Total:  &rowCount,
 	}
 	var ocdLogs []entity.OCDLog
-	err = sqlscan.Select(ctx, repo.DB, &ocdLogs, getAllLogsQuery, accountID, limit, offset)
+	query := fmt.Sprintf(getAllLogsQuery, whereClause, buildLogOrderClause(sort), len(args)+1, len(args)+2)
+	err = sqlscan.Select(ctx, repo.conn(), &ocdLogs, query, append(args, limit, offset)...)
 	if err != nil {
 		return nil, err
 	}
@@ -57,8 +140,61 @@ func (repo *OCDLogRepository) GetAllLogs(ctx context.Context, accountID string,
 	return &ocdLogList, nil
 }
 
+func (repo *OCDLogRepository) StreamLogs(ctx context.Context, accountID string, filter entity.OCDLogFilter, sort entity.OCDLogSort, fn func(ocdLog entity.OCDLog) error) error {
+	whereClause, args := buildLogFilterClause(accountID, filter)
+	rows, err := repo.conn().QueryContext(ctx, fmt.Sprintf(streamLogsQuery, whereClause, buildLogOrderClause(sort)), args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	rowScanner := sqlscan.NewRowScanner(rows)
+	streamed := 0
+	for rows.Next() {
+		var ocdLog entity.OCDLog
+		if err = rowScanner.Scan(&ocdLog); err != nil {
+			return err
+		}
+		if err = fn(ocdLog); err != nil {
+			return err
+		}
+		streamed++
+	}
+	if err = rows.Err(); err != nil {
+		return err
+	}
+	log.LoggerFromContext(ctx).Info(fmt.Sprintf("streamed %d logs", streamed))
+	return nil
+}
+
+func (repo *OCDLogRepository) GetLogsByCursor(ctx context.Context, accountID string, filter entity.OCDLogFilter, cursor *entity.Cursor, descending bool, limit int) (*entity.OCDLogList, error) {
+	whereClause, args := buildLogFilterClause(accountID, filter)
+	scanDescending := db.CursorScanDescending(cursor, descending)
+	direction := "ASC"
+	if scanDescending {
+		direction = "DESC"
+	}
+	if cursor != nil {
+		operator := ">"
+		if scanDescending {
+			operator = "<"
+		}
+		args = append(args, cursor.CreatedAt.UTC(), cursor.ID)
+		whereClause += fmt.Sprintf(" AND (created_at, id) %s ($%d, $%d)", operator, len(args)-1, len(args))
+	}
+	args = append(args, limit+1) // one extra row tells whether there is another page
+	query := fmt.Sprintf(getLogsByCursorQuery, whereClause, direction, direction, len(args))
+	var ocdLogs []entity.OCDLog
+	err := sqlscan.Select(ctx, repo.conn(), &ocdLogs, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	ocdLogList := db.BuildCursorPage(ocdLogs, cursor, descending, limit)
+	log.LoggerFromContext(ctx).Info(fmt.Sprintf("retrieved %d logs", len(ocdLogList.Logs)))
+	return ocdLogList, nil
+}
+
 func (repo *OCDLogRepository) DeleteAllLogs(ctx context.Context, accountID string) error {
-	err := logExec(ctx, repo.DB, deleteAllLogsQuery, "delete", accountID)
+	err := logExec(ctx, repo.conn(), deleteAllLogsQuery, "delete", accountID)
 	if err != nil {
 		return err
 	}
@@ -67,43 +203,405 @@ func (repo *OCDLogRepository) DeleteAllLogs(ctx context.Context, accountID strin
 
 func (repo *OCDLogRepository) GetLog(ctx context.Context, accountID string, id uuid.UUID) (*entity.OCDLog, error) {
 	ocdLog := entity.OCDLog{}
-	err := sqlscan.Get(ctx, repo.DB, &ocdLog, getLogQuery, accountID, id)
+	err := sqlscan.Get(ctx, repo.conn(), &ocdLog, getLogQuery, accountID, id)
 	if err != nil {
 		return nil, err
 	}
 	return &ocdLog, nil
 }
 
-func (repo *OCDLogRepository) CreateLog(ctx context.Context, accountID string, ocdLog *entity.OCDLog) error {
-	pgElems, err := buildCreateQuery(ocdLog, accountID)
+func (repo *OCDLogRepository) GetLogStats(ctx context.Context, accountID string, filter entity.OCDLogFilter) (*entity.OCDLogStats, error) {
+	whereClause, args := buildLogFilterClause(accountID, filter)
+	stats := entity.OCDLogStats{}
+	err := sqlscan.Get(ctx, repo.conn(), &stats, fmt.Sprintf(getLogStatsQuery, whereClause), args...)
 	if err != nil {
-		return err
+		return nil, err
+	}
+	var buckets []entity.AnxietyHistogramBucket
+	err = sqlscan.Select(ctx, repo.conn(), &buckets, fmt.Sprintf(getAnxietyHistogramQuery, whereClause), args...)
+	if err != nil {
+		return nil, err
+	}
+	stats.AnxietyHistogram = entity.NewAnxietyHistogram()
+	for _, bucket := range buckets {
+		if bucket.AnxietyLevel >= 0 && bucket.AnxietyLevel <= entity.MaxAnxietyLevel {
+			stats.AnxietyHistogram[bucket.AnxietyLevel].Count = bucket.Count
+		}
 	}
-	err = logExec(ctx, repo.DB, pgElems.query, "create", pgElems.fieldValues...)
+	stats.Compulsions = make([]entity.CompulsionStats, 0)
+	err = sqlscan.Select(ctx, repo.conn(), &stats.Compulsions, fmt.Sprintf(getCompulsionStatsQuery, whereClause), args...)
+	if err != nil {
+		return nil, err
+	}
+	stats.Tags = make([]entity.TagStats, 0)
+	err = sqlscan.Select(ctx, repo.conn(), &stats.Tags, fmt.Sprintf(getTagStatsQuery, whereClause), args...)
+	if err != nil {
+		return nil, err
+	}
+	return &stats, nil
+}
+
+func (repo *OCDLogRepository) GetLogSeries(ctx context.Context, accountID string, filter entity.OCDLogFilter, query entity.OCDLogSeriesQuery) (*entity.OCDLogSeries, error) {
+	whereClause, args := buildLogFilterClause(accountID, filter)
+	args = append(args, query.Zone().String())
+	timeZone := fmt.Sprintf("$%d::text", len(args))
+	if query.AwakeWindow != nil {
+		start, end := query.AwakeWindow.Bounds()
+		clock := fmt.Sprintf("(created_at AT TIME ZONE %s)::time", timeZone)
+		switch {
+		case start < end:
+			args = append(args, start, end)
+			whereClause += fmt.Sprintf(" AND %[1]s >= $%[2]d::time AND %[1]s < $%[3]d::time", clock, len(args)-1, len(args))
+		case start > end:
+			args = append(args, start, end)
+			whereClause += fmt.Sprintf(" AND (%[1]s >= $%[2]d::time OR %[1]s < $%[3]d::time)", clock, len(args)-1, len(args))
+		}
+	}
+	var points []entity.OCDLogSeriesPoint
+	err := sqlscan.Select(ctx, repo.conn(), &points, fmt.Sprintf(getLogSeriesQuery, buildSeriesBucketExpression(query.Bucket, timeZone), whereClause), args...)
+	if err != nil {
+		return nil, err
+	}
+	return db.FillSeries(points, query), nil
+}
+
+// buildSeriesBucketExpression truncates created_at to the start of its bucket in the time zone, like
+// entity.SeriesBucket.Truncate; hours subtract the time elapsed since the start of the local hour instead of converting
+// the local hour back, which is ambiguous in the hour repeated when daylight saving time ends
+func buildSeriesBucketExpression(bucket entity.SeriesBucket, timeZone string) string {
+	if bucket == entity.SeriesBucketHour {
+		return fmt.Sprintf("created_at - ((created_at AT TIME ZONE %[1]s) - date_trunc('hour', created_at AT TIME ZONE %[1]s))", timeZone)
+	}
+	// the bucket is validated against a whitelist, so it is safe to interpolate
+	return fmt.Sprintf("date_trunc('%[1]s', created_at AT TIME ZONE %[2]s) AT TIME ZONE %[2]s", bucket, timeZone)
+}
+
+func (repo *OCDLogRepository) CreateLog(ctx context.Context, accountID string, ocdLog *entity.OCDLog) error {
+	ocdLog.ID = uuid.New()
+	pgElems, err := buildCreateQuery(ocdLog, accountID)
 	if err != nil {
 		return err
 	}
-	return nil
+	return repo.inTx(ctx, func(txRepo *OCDLogRepository) error {
+		err := logExec(ctx, txRepo.conn(), pgElems.query, "create", pgElems.fieldValues...)
+		if err != nil {
+			return err
+		}
+		return txRepo.setLogTags(ctx, accountID, ocdLog.ID, ocdLog.Tags)
+	})
+}
+
+// ImportLogs inserts logs with their ids and timestamps preserved, all or none of them; logs with deleted_at set go
+// straight to the trash
+func (repo *OCDLogRepository) ImportLogs(ctx context.Context, accountID string, ocdLogs []entity.OCDLog) error {
+	return repo.inTx(ctx, func(txRepo *OCDLogRepository) error {
+		for _, ocdLog := range ocdLogs {
+			err := logExec(ctx, txRepo.conn(), importLogQuery, "create", ocdLog.ID, accountID, ocdLog.CreatedAt, ocdLog.UpdatedAt,
+				ocdLog.RuminateMinutes, ocdLog.AnxietyLevel, ocdLog.Notes, ocdLog.CompulsionType, ocdLog.UrgeIntensity, ocdLog.Resisted, ocdLog.ResistedMinutes,
+				ocdLog.DeletedAt,
+			)
+			if err != nil {
+				return err
+			}
+			if err = txRepo.setLogTags(ctx, accountID, ocdLog.ID, ocdLog.Tags); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
 }
 
+// UpdateLog applies the changes and records a revision with the values before and after them; non-nil tags replace
+// the tags of the log
 func (repo *OCDLogRepository) UpdateLog(ctx context.Context, accountID string, id uuid.UUID, ocdLog *entity.OCDLog) error {
 	pgElems, err := buildUpdateQuery(ocdLog, accountID, &id)
 	if err != nil {
 		return err
 	}
-	if pgElems != nil {
-		err = logExec(ctx, repo.DB, pgElems.query, "update", pgElems.fieldValues...)
+	if pgElems == nil && ocdLog.Tags == nil {
+		return nil // no action
+	}
+	return repo.inTx(ctx, func(txRepo *OCDLogRepository) error {
+		var existing entity.OCDLog
+		err := sqlscan.Get(ctx, txRepo.conn(), &existing, lockLogQuery, accountID, id)
+		if errors.Is(err, sql.ErrNoRows) {
+			log.LoggerFromContext(ctx).Info("updated 0 record/s")
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if ocdLog.Tags != nil {
+			if err = txRepo.setLogTags(ctx, accountID, id, ocdLog.Tags); err != nil {
+				return err
+			}
+		}
+		if pgElems == nil {
+			err = logExec(ctx, txRepo.conn(), touchLogQuery, "update", accountID, id)
+		} else {
+			err = logExec(ctx, txRepo.conn(), pgElems.query, "update", pgElems.fieldValues...)
+		}
+		if err != nil {
+			return err
+		}
+		var updated entity.OCDLog
+		err = sqlscan.Get(ctx, txRepo.conn(), &updated, getLogQuery, accountID, id)
 		if err != nil {
 			return err
 		}
+		oldValues, newValues := entity.NewOCDLogRevisionValues(existing), entity.NewOCDLogRevisionValues(updated)
+		if oldValues.Equal(newValues) {
+			return nil
+		}
+		return logExec(ctx, txRepo.conn(), createRevisionQuery, "create", id, accountID,
+			oldValues.RuminateMinutes, oldValues.AnxietyLevel, oldValues.Notes,
+			oldValues.CompulsionType, oldValues.UrgeIntensity, oldValues.Resisted, oldValues.ResistedMinutes,
+			newValues.RuminateMinutes, newValues.AnxietyLevel, newValues.Notes,
+			newValues.CompulsionType, newValues.UrgeIntensity, newValues.Resisted, newValues.ResistedMinutes,
+			oldValues.Tags, newValues.Tags,
+		)
+	})
+}
+
+func (repo *OCDLogRepository) GetLogRevisions(ctx context.Context, accountID string, id uuid.UUID) (*entity.OCDLogRevisionList, error) {
+	var rows []revisionRow
+	err := sqlscan.Select(ctx, repo.conn(), &rows, getRevisionsQuery, accountID, id)
+	if err != nil {
+		return nil, err
+	}
+	revisionList := entity.OCDLogRevisionList{
+		Revisions: make([]entity.OCDLogRevision, 0, len(rows)),
+	}
+	for _, row := range rows {
+		revisionList.Revisions = append(revisionList.Revisions, row.toEntity())
+	}
+	log.LoggerFromContext(ctx).Info(fmt.Sprintf("retrieved %d revisions", len(rows)))
+	return &revisionList, nil
+}
+
+func (repo *OCDLogRepository) GetLogRevision(ctx context.Context, accountID string, id uuid.UUID, revision int) (*entity.OCDLogRevision, error) {
+	var row revisionRow
+	err := sqlscan.Get(ctx, repo.conn(), &row, getRevisionQuery, accountID, id, revision)
+	if err != nil {
+		return nil, err
+	}
+	result := row.toEntity()
+	return &result, nil
+}
+
+// GetAccountLogRevisions returns the revisions of every log of the account, including the trash, by log and revision
+func (repo *OCDLogRepository) GetAccountLogRevisions(ctx context.Context, accountID string) (*entity.OCDLogRevisionList, error) {
+	var rows []revisionRow
+	err := sqlscan.Select(ctx, repo.conn(), &rows, getAccountRevisionsQuery, accountID)
+	if err != nil {
+		return nil, err
+	}
+	revisionList := entity.OCDLogRevisionList{
+		Revisions: make([]entity.OCDLogRevision, 0, len(rows)),
+	}
+	for _, row := range rows {
+		revisionList.Revisions = append(revisionList.Revisions, row.toEntity())
+	}
+	log.LoggerFromContext(ctx).Info(fmt.Sprintf("retrieved %d revisions", len(rows)))
+	return &revisionList, nil
+}
+
+// ImportLogRevisions inserts revisions as they were recorded, all or none of them; it returns sql.ErrNoRows if a
+// revision belongs to a log that the account does not have
+func (repo *OCDLogRepository) ImportLogRevisions(ctx context.Context, accountID string, revisions []entity.OCDLogRevision) error {
+	return repo.inTx(ctx, func(txRepo *OCDLogRepository) error {
+		for _, revision := range revisions {
+			result, err := txRepo.conn().ExecContext(ctx, importRevisionQuery, revision.OCDLogID, accountID, revision.Revision, revision.ChangedBy, revision.ChangedAt.UTC(),
+				revision.Old.RuminateMinutes, revision.Old.AnxietyLevel, revision.Old.Notes,
+				revision.Old.CompulsionType, revision.Old.UrgeIntensity, revision.Old.Resisted, revision.Old.ResistedMinutes,
+				revision.New.RuminateMinutes, revision.New.AnxietyLevel, revision.New.Notes,
+				revision.New.CompulsionType, revision.New.UrgeIntensity, revision.New.Resisted, revision.New.ResistedMinutes,
+				revision.Old.Tags, revision.New.Tags,
+			)
+			if err != nil {
+				return translateError(err)
+			}
+			rowsAffected, err := result.RowsAffected()
+			if err != nil {
+				return err
+			}
+			if rowsAffected == 0 {
+				return fmt.Errorf("revision %d of log %s: %w", revision.Revision, revision.OCDLogID, sql.ErrNoRows)
+			}
+		}
+		log.LoggerFromContext(ctx).Info(fmt.Sprintf("created %d record/s", len(revisions)))
+		return nil
+	})
+}
+
+func (row revisionRow) toEntity() entity.OCDLogRevision {
+	return entity.OCDLogRevision{
+		Revision:  row.Revision,
+		OCDLogID:  row.OCDLogID,
+		ChangedBy: row.ChangedBy,
+		ChangedAt: row.ChangedAt,
+		Old: entity.OCDLogRevisionValues{
+			RuminateMinutes: row.OldRuminateMinutes,
+			AnxietyLevel:    row.OldAnxietyLevel,
+			Notes:           row.OldNotes,
+			CompulsionType:  row.OldCompulsionType,
+			UrgeIntensity:   row.OldUrgeIntensity,
+			Resisted:        row.OldResisted,
+			ResistedMinutes: row.OldResistedMinutes,
+			Tags:            row.OldTags,
+		},
+		New: entity.OCDLogRevisionValues{
+			RuminateMinutes: row.NewRuminateMinutes,
+			AnxietyLevel:    row.NewAnxietyLevel,
+			Notes:           row.NewNotes,
+			CompulsionType:  row.NewCompulsionType,
+			UrgeIntensity:   row.NewUrgeIntensity,
+			Resisted:        row.NewResisted,
+			ResistedMinutes: row.NewResistedMinutes,
+			Tags:            row.NewTags,
+		},
 	}
-	return nil
 }
 
 func (repo *OCDLogRepository) DeleteLog(ctx context.Context, accountID string, id uuid.UUID) error {
-	err := logExec(ctx, repo.DB, deleteLogQuery, "delete", accountID, id)
+	err := logExec(ctx, repo.conn(), deleteLogQuery, "delete", accountID, id)
 	if err != nil {
 		return err
 	}
 	return nil
 }
+
+func (repo *OCDLogRepository) GetTrashedLogs(ctx context.Context, accountID string, limit, offset int) (*entity.OCDLogList, error) {
+	var rowCount int
+	err := sqlscan.Get(ctx, repo.conn(), &rowCount, getTrashedRowCountQuery, accountID)
+	if err != nil {
+		return nil, err
+	}
+	ocdLogs := make([]entity.OCDLog, 0)
+	err = sqlscan.Select(ctx, repo.conn(), &ocdLogs, getTrashedLogsQuery, accountID, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	log.LoggerFromContext(ctx).Info(fmt.Sprintf("retrieved %d trashed logs", len(ocdLogs)))
+	return &entity.OCDLogList{
+		Logs: ocdLogs,
+		Pagination: entity.PaginationDetails{
+			Limit:  limit,
+			Offset: &offset,
+			Count:  len(ocdLogs),
+			Total:  &rowCount,
+		},
+	}, nil
+}
+
+// RestoreLog takes a log out of the trash; it returns sql.ErrNoRows if the log is not in the trash
+func (repo *OCDLogRepository) RestoreLog(ctx context.Context, accountID string, id uuid.UUID) error {
+	result, err := repo.conn().ExecContext(ctx, restoreLogQuery, accountID, id)
+	if err != nil {
+		return err
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return sql.ErrNoRows
+	}
+	log.LoggerFromContext(ctx).Info("restored 1 record/s")
+	return nil
+}
+
+// PurgeTrashedLogs permanently removes the logs of every account that were trashed before deletedBefore
+func (repo *OCDLogRepository) PurgeTrashedLogs(ctx context.Context, deletedBefore time.Time) (int, error) {
+	result, err := repo.conn().ExecContext(ctx, purgeTrashedLogsQuery, deletedBefore.UTC())
+	if err != nil {
+		return 0, err
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+	return int(rowsAffected), nil
+}
+
+// setLogTags replaces the tags of a log, creating the ones the account does not have yet; nil tags are left alone
+func (repo *OCDLogRepository) setLogTags(ctx context.Context, accountID string, id uuid.UUID, tags entity.TagNames) error {
+	if tags == nil {
+		return nil
+	}
+	if _, err := repo.conn().ExecContext(ctx, deleteLogTagsQuery, id); err != nil {
+		return err
+	}
+	for _, name := range tags.Normalized() {
+		if _, err := repo.conn().ExecContext(ctx, ensureTagQuery, accountID, name); err != nil {
+			return err
+		}
+		if _, err := repo.conn().ExecContext(ctx, createLogTagQuery, id, accountID, name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// buildLogFilterClause returns the where clause scoping logs to an account, excluding the trash, and the filter, with
+// its positional args
+func buildLogFilterClause(accountID string, filter entity.OCDLogFilter) (string, []interface{}) {
+	conditions := []string{"account_id = $1", "deleted_at IS NULL"}
+	args := []interface{}{accountID}
+	addCondition := func(condition string, arg interface{}) {
+		args = append(args, arg)
+		conditions = append(conditions, fmt.Sprintf(condition, len(args)))
+	}
+	if filter.From != nil {
+		addCondition("created_at >= $%d", filter.From.UTC())
+	}
+	if filter.To != nil {
+		addCondition("created_at <= $%d", filter.To.UTC())
+	}
+	if filter.AnxietyMin != nil {
+		addCondition("anxiety_level >= $%d", *filter.AnxietyMin)
+	}
+	if filter.AnxietyMax != nil {
+		addCondition("anxiety_level <= $%d", *filter.AnxietyMax)
+	}
+	if filter.RuminateMin != nil {
+		addCondition("ruminate_minutes >= $%d", *filter.RuminateMin)
+	}
+	if filter.RuminateMax != nil {
+		addCondition("ruminate_minutes <= $%d", *filter.RuminateMax)
+	}
+	if filter.CompulsionType != nil {
+		addCondition("compulsion_type = $%d", *filter.CompulsionType)
+	}
+	if filter.Resisted != nil {
+		addCondition("resisted = $%d", *filter.Resisted)
+	}
+	for _, name := range filter.Tags {
+		addCondition("EXISTS (SELECT 1 FROM ocdlog_tag lt JOIN tag t ON t.id = lt.tag_id WHERE lt.ocdlog_id = ocdlog.id AND t.name = $%d)", name)
+	}
+	if filter.HasNotes != nil {
+		if *filter.HasNotes {
+			conditions = append(conditions, "COALESCE(notes, '') <> ''")
+		} else {
+			conditions = append(conditions, "COALESCE(notes, '') = ''")
+		}
+	}
+	return strings.Join(conditions, " AND "), args
+}
+
+// buildLogOrderClause returns the order by clause for sort; field names are whitelisted by entity.OCDLogSort validation
+func buildLogOrderClause(sort entity.OCDLogSort) string {
+	if len(sort) == 0 {
+		sort = entity.DefaultOCDLogSort
+	}
+	orderBy := make([]string, 0, len(sort)+1)
+	for _, sortField := range sort {
+		direction := "ASC"
+		if sortField.Descending {
+			direction = "DESC"
+		}
+		orderBy = append(orderBy, fmt.Sprintf("%s %s NULLS LAST", pq.QuoteIdentifier(sortField.Field), direction))
+	}
+	orderBy = append(orderBy, "id ASC")
+	return strings.Join(orderBy, ", ")
+}
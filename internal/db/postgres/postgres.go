@@ -7,13 +7,18 @@ import (
 	"errors"
 	"fmt"
 	"github.com/cecobask/ocdtracker-api/internal/aws"
+	"github.com/cecobask/ocdtracker-api/internal/config"
+	"github.com/cecobask/ocdtracker-api/internal/db"
 	"github.com/cecobask/ocdtracker-api/pkg/entity"
 	"github.com/cecobask/ocdtracker-api/pkg/log"
 	"github.com/golang-migrate/migrate/v4"
 	pgMigrate "github.com/golang-migrate/migrate/v4/database/postgres"
+	"github.com/golang-migrate/migrate/v4/source"
 	_ "github.com/golang-migrate/migrate/v4/source/file"
 	"github.com/google/uuid"
+	"github.com/lib/pq"
 	"go.uber.org/zap"
+	"os"
 	"reflect"
 	"strconv"
 	"strings"
@@ -30,10 +35,18 @@ type ConnectionConfig struct {
 	Host             string
 	DBName           string
 	Port             string
+	SSLMode          string
 	RetryMaxAttempts int
 	RetryDelay       time.Duration
 }
 
+// querier is implemented by both *sql.DB and *sql.Tx
+type querier interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
+
 type postgresElements struct {
 	query       string
 	fieldValues []interface{}
@@ -42,15 +55,25 @@ type postgresElements struct {
 type entityType string
 
 const (
-	entityTypeAccount entityType = "account"
-	entityTypeOCDLog  entityType = "ocdlog"
+	entityTypeAccount         entityType = "account"
+	entityTypeOCDLog          entityType = "ocdlog"
+	entityTypeExposureItem    entityType = "exposure_item"
+	entityTypeExposureSession entityType = "exposure_session"
+)
+
+const (
+	pqUniqueViolation = "23505"
+)
+
+const (
+	getMigrationVersionQuery = `SELECT version, dirty FROM schema_migrations LIMIT 1;`
 )
 
 // ConnectWithConfig connects to a database with custom config
 func ConnectWithConfig(ctx context.Context, credentials Credentials, connectionConfig ConnectionConfig) (*sql.DB, error) {
 	logger := log.LoggerFromContext(ctx)
-	connString := fmt.Sprintf("host=%s port=%s user=%s password=%s dbname=%s sslmode=require",
-		connectionConfig.Host, connectionConfig.Port, credentials.User, credentials.Password, connectionConfig.DBName,
+	connString := fmt.Sprintf("host=%s port=%s user=%s password=%s dbname=%s sslmode=%s",
+		connectionConfig.Host, connectionConfig.Port, credentials.User, credentials.Password, connectionConfig.DBName, connectionConfig.SSLMode,
 	)
 	db, err := sql.Open("postgres", connString)
 	if err != nil {
@@ -62,10 +85,15 @@ func ConnectWithConfig(ctx context.Context, credentials Credentials, connectionC
 		if attempts == connectionConfig.RetryMaxAttempts {
 			return nil, fmt.Errorf("reached max database connection retry attempts")
 		}
-		err := db.Ping()
+		err := db.PingContext(ctx)
 		if err != nil {
 			logger.Warn("failed attempt to establish database connection", zap.Int("attempts", attempts), zap.Error(err))
-			time.Sleep(connectionConfig.RetryDelay)
+			select {
+			case <-ctx.Done():
+				_ = db.Close()
+				return nil, fmt.Errorf("aborted database connection retries: %w", ctx.Err())
+			case <-time.After(connectionConfig.RetryDelay):
+			}
 			continue
 		}
 		logger.Info("established database connection")
@@ -73,8 +101,8 @@ func ConnectWithConfig(ctx context.Context, credentials Credentials, connectionC
 	}
 }
 
-// Connect connects to a database with default config
-func Connect(ctx context.Context, postgresCredsSecret *aws.PostgresCredsSecret) (*sql.DB, error) {
+// Connect connects to a database with the credentials secret and postgres settings from config
+func Connect(ctx context.Context, postgresCredsSecret *aws.PostgresCredsSecret, postgresConfig config.Postgres) (*sql.DB, error) {
 	credentials := Credentials{
 		User:     postgresCredsSecret.Username,
 		Password: postgresCredsSecret.Password,
@@ -83,18 +111,19 @@ func Connect(ctx context.Context, postgresCredsSecret *aws.PostgresCredsSecret)
 		Host:             postgresCredsSecret.Host,
 		DBName:           postgresCredsSecret.DBName,
 		Port:             strconv.Itoa(postgresCredsSecret.Port),
-		RetryMaxAttempts: 10,
-		RetryDelay:       time.Second * 5,
+		SSLMode:          postgresConfig.SSLMode,
+		RetryMaxAttempts: postgresConfig.RetryMaxAttempts,
+		RetryDelay:       time.Duration(postgresConfig.RetryDelay),
 	}
 	return ConnectWithConfig(ctx, credentials, connectionConfig)
 }
 
-func Migrate(db *sql.DB) error {
+func Migrate(db *sql.DB, migrationPath string) error {
 	driver, err := pgMigrate.WithInstance(db, &pgMigrate.Config{})
 	if err != nil {
 		return fmt.Errorf("failed to link database and migrator: %w", err)
 	}
-	instance, err := migrate.NewWithDatabaseInstance("file:///migration", "postgres", driver)
+	instance, err := migrate.NewWithDatabaseInstance(migrationPath, "postgres", driver)
 	if err != nil {
 		return fmt.Errorf("failed to initialise database migrator: %w", err)
 	}
@@ -105,10 +134,52 @@ func Migrate(db *sql.DB) error {
 	return nil
 }
 
-func logExec(ctx context.Context, db *sql.DB, query, action string, args ...interface{}) error {
+// LatestMigrationVersion returns the highest migration version available at migrationPath
+func LatestMigrationVersion(migrationPath string) (uint, error) {
+	sourceDriver, err := source.Open(migrationPath)
+	if err != nil {
+		return 0, fmt.Errorf("failed to open migration source: %w", err)
+	}
+	defer sourceDriver.Close()
+	version, err := sourceDriver.First()
+	if err != nil {
+		return 0, fmt.Errorf("failed to read first migration: %w", err)
+	}
+	for {
+		next, err := sourceDriver.Next(version)
+		if errors.Is(err, os.ErrNotExist) {
+			return version, nil
+		}
+		if err != nil {
+			return 0, fmt.Errorf("failed to read migration after version %d: %w", version, err)
+		}
+		version = next
+	}
+}
+
+// CheckMigrationVersion verifies that the database schema is clean and at the expected version
+func CheckMigrationVersion(ctx context.Context, db *sql.DB, expectedVersion uint) error {
+	var (
+		version int64
+		dirty   bool
+	)
+	err := db.QueryRowContext(ctx, getMigrationVersionQuery).Scan(&version, &dirty)
+	if err != nil {
+		return fmt.Errorf("failed to get migration version: %w", err)
+	}
+	if dirty {
+		return fmt.Errorf("migration version %d is dirty", version)
+	}
+	if version != int64(expectedVersion) {
+		return fmt.Errorf("migration version %d does not match expected version %d", version, expectedVersion)
+	}
+	return nil
+}
+
+func logExec(ctx context.Context, db querier, query, action string, args ...interface{}) error {
 	result, err := db.ExecContext(ctx, query, args...)
 	if err != nil {
-		return err
+		return translateError(err)
 	}
 	rowsAffected, err := result.RowsAffected()
 	if err != nil {
@@ -118,6 +189,33 @@ func logExec(ctx context.Context, db *sql.DB, query, action string, args ...inte
 	return nil
 }
 
+// translateError maps postgres errors that callers act upon to the errors of the db package
+func translateError(err error) error {
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code == pqUniqueViolation {
+		return fmt.Errorf("%s: %w", pqErr.Message, db.ErrorDuplicate)
+	}
+	return err
+}
+
+// withTx runs fn in a transaction, committing if it succeeds and rolling back otherwise
+func withTx(ctx context.Context, db *sql.DB, fn func(tx *sql.Tx) error) error {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	if err = fn(tx); err != nil {
+		if rollbackErr := tx.Rollback(); rollbackErr != nil {
+			log.LoggerFromContext(ctx).Error("failed to roll back transaction", zap.Error(rollbackErr))
+		}
+		return err
+	}
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
 func buildCreateQuery(object interface{}, accountID string) (*postgresElements, error) {
 	var (
 		fieldsAllowed []string
@@ -130,13 +228,21 @@ func buildCreateQuery(object interface{}, accountID string) (*postgresElements,
 	}
 	switch entityType {
 	case entityTypeAccount:
-		fieldsAllowed = append(fieldsAllowed, "email", "display_name", "wake_time", "sleep_time", "notification_interval", "photo_url")
+		fieldsAllowed = append(fieldsAllowed, "email", "display_name", "wake_time", "sleep_time", "notification_interval", "photo_url", "time_zone")
 		fieldNames = append(fieldNames, "id")
 		jsonData, err = json.Marshal(object.(*entity.Account))
 	case entityTypeOCDLog:
-		fieldsAllowed = append(fieldsAllowed, "ruminate_minutes", "anxiety_level", "notes")
+		fieldsAllowed = append(fieldsAllowed, "id", "ruminate_minutes", "anxiety_level", "notes", "compulsion_type", "urge_intensity", "resisted", "resisted_minutes")
 		fieldNames = append(fieldNames, "account_id")
 		jsonData, err = json.Marshal(object.(*entity.OCDLog))
+	case entityTypeExposureItem:
+		fieldsAllowed = append(fieldsAllowed, "id", "description", "predicted_suds")
+		fieldNames = append(fieldNames, "account_id")
+		jsonData, err = json.Marshal(object.(*entity.ExposureItem))
+	case entityTypeExposureSession:
+		fieldsAllowed = append(fieldsAllowed, "id", "item_id", "started_at", "duration_minutes", "suds_start", "suds_peak", "suds_end", "notes")
+		fieldNames = append(fieldNames, "account_id")
+		jsonData, err = json.Marshal(object.(*entity.ExposureSession))
 	}
 	fieldValues := []interface{}{accountID}
 	fieldIndexes := []string{"$1"}
@@ -169,6 +275,7 @@ func buildUpdateQuery(object interface{}, accountID string, logID *uuid.UUID) (*
 		fieldValues   []interface{}
 		whereClause   string
 		jsonData      []byte
+		cleared       []string
 	)
 	entityType, err := getEntityType(object)
 	if err != nil {
@@ -176,15 +283,26 @@ func buildUpdateQuery(object interface{}, accountID string, logID *uuid.UUID) (*
 	}
 	switch entityType {
 	case entityTypeAccount:
-		fieldsAllowed = append(fieldsAllowed, "email", "display_name", "wake_time", "sleep_time", "notification_interval", "photo_url")
+		fieldsAllowed = append(fieldsAllowed, "email", "display_name", "wake_time", "sleep_time", "notification_interval", "photo_url", "time_zone")
 		fieldValues = append(fieldValues, accountID)
 		whereClause = "id = $1"
 		jsonData, err = json.Marshal(object.(*entity.Account))
 	case entityTypeOCDLog:
-		fieldsAllowed = append(fieldsAllowed, "ruminate_minutes", "anxiety_level", "notes")
+		fieldsAllowed = append(fieldsAllowed, "ruminate_minutes", "anxiety_level", "notes", "compulsion_type", "urge_intensity", "resisted", "resisted_minutes")
 		fieldValues = append(fieldValues, accountID, logID)
-		whereClause = "account_id = $1 AND id = $2"
+		whereClause = "account_id = $1 AND id = $2 AND deleted_at IS NULL"
 		jsonData, err = json.Marshal(object.(*entity.OCDLog))
+		cleared = object.(*entity.OCDLog).Cleared
+	case entityTypeExposureItem:
+		fieldsAllowed = append(fieldsAllowed, "description", "predicted_suds")
+		fieldValues = append(fieldValues, accountID, logID)
+		whereClause = "account_id = $1 AND id = $2"
+		jsonData, err = json.Marshal(object.(*entity.ExposureItem))
+	case entityTypeExposureSession:
+		fieldsAllowed = append(fieldsAllowed, "started_at", "duration_minutes", "suds_start", "suds_peak", "suds_end", "notes")
+		fieldValues = append(fieldValues, accountID, logID)
+		whereClause = "account_id = $1 AND id = $2"
+		jsonData, err = json.Marshal(object.(*entity.ExposureSession))
 	}
 	fieldUpdates := make(map[string]interface{})
 	err = json.Unmarshal(jsonData, &fieldUpdates)
@@ -200,6 +318,11 @@ func buildUpdateQuery(object interface{}, accountID string, logID *uuid.UUID) (*
 				index++
 			}
 		}
+		for _, clearedField := range cleared {
+			if clearedField == allowedField {
+				fields = append(fields, fmt.Sprintf("%s = NULL,", clearedField))
+			}
+		}
 	}
 	if len(fields) > 0 {
 		fields = append(fields, "updated_at = CURRENT_TIMESTAMP")
@@ -219,6 +342,10 @@ func getEntityType(object interface{}) (entityType, error) {
 		return entityTypeAccount, nil
 	case "ocdlog":
 		return entityTypeOCDLog, nil
+	case "exposureitem":
+		return entityTypeExposureItem, nil
+	case "exposuresession":
+		return entityTypeExposureSession, nil
 	default:
 		return "", fmt.Errorf("unknown entity type %s", entityTypeStr)
 	}
@@ -0,0 +1,114 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"github.com/cecobask/ocdtracker-api/internal/db"
+	"github.com/cecobask/ocdtracker-api/pkg/entity"
+	"github.com/cecobask/ocdtracker-api/pkg/log"
+	"github.com/georgysavva/scany/sqlscan"
+	"github.com/google/uuid"
+)
+
+type QuestionnaireResponseRepository struct {
+	DB *sql.DB
+	tx *sql.Tx // set on repositories bound to a transaction by Store.WithTx
+}
+
+var _ db.QuestionnaireResponseRepository = (*QuestionnaireResponseRepository)(nil)
+
+const (
+	responseColumns           = `id, account_id, questionnaire_id, questionnaire_version, created_at, answers, total_score, subscale_scores, severity`
+	createResponseQuery       = `INSERT INTO questionnaire_response (id, account_id, questionnaire_id, questionnaire_version, answers, total_score, subscale_scores, severity) VALUES ($1, $2, $3, $4, $5, $6, $7, $8);`
+	getAllResponsesQuery      = `SELECT ` + responseColumns + ` FROM questionnaire_response WHERE account_id = $1 AND questionnaire_id = $2 ORDER BY created_at DESC, id ASC LIMIT $3 OFFSET $4;`
+	getResponsesRowCountQuery = `SELECT count(*) FROM questionnaire_response WHERE account_id = $1 AND questionnaire_id = $2;`
+	getResponseQuery          = `SELECT ` + responseColumns + ` FROM questionnaire_response WHERE account_id = $1 AND id = $2 LIMIT 1;`
+	getAccountResponsesQuery  = `SELECT ` + responseColumns + ` FROM questionnaire_response WHERE account_id = $1 ORDER BY created_at ASC, id ASC;`
+	importResponseQuery       = `INSERT INTO questionnaire_response (id, account_id, questionnaire_id, questionnaire_version, created_at, answers, total_score, subscale_scores, severity) VALUES ($1, $2, $3, $4, COALESCE($5, CURRENT_TIMESTAMP), $6, $7, $8, $9);`
+)
+
+func NewQuestionnaireResponseRepository(db *sql.DB) *QuestionnaireResponseRepository {
+	return &QuestionnaireResponseRepository{
+		DB: db,
+	}
+}
+
+func (repo *QuestionnaireResponseRepository) conn() querier {
+	if repo.tx != nil {
+		return repo.tx
+	}
+	return repo.DB
+}
+
+func (repo *QuestionnaireResponseRepository) CreateResponse(ctx context.Context, accountID string, response *entity.QuestionnaireResponse) error {
+	response.ID = uuid.New()
+	return logExec(ctx, repo.conn(), createResponseQuery, "create", response.ID, accountID, response.QuestionnaireID, response.QuestionnaireVersion,
+		response.Answers, response.TotalScore, response.SubscaleScores, response.Severity,
+	)
+}
+
+// GetAllResponses returns the score history of a questionnaire, most recent first
+func (repo *QuestionnaireResponseRepository) GetAllResponses(ctx context.Context, accountID, questionnaireID string, limit, offset int) (*entity.QuestionnaireResponseList, error) {
+	var rowCount int
+	err := sqlscan.Get(ctx, repo.conn(), &rowCount, getResponsesRowCountQuery, accountID, questionnaireID)
+	if err != nil {
+		return nil, err
+	}
+	responses := make([]entity.QuestionnaireResponse, 0)
+	err = sqlscan.Select(ctx, repo.conn(), &responses, getAllResponsesQuery, accountID, questionnaireID, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	log.LoggerFromContext(ctx).Info(fmt.Sprintf("retrieved %d questionnaire responses", len(responses)))
+	return &entity.QuestionnaireResponseList{
+		Responses: responses,
+		Pagination: entity.PaginationDetails{
+			Limit:  limit,
+			Offset: &offset,
+			Count:  len(responses),
+			Total:  &rowCount,
+		},
+	}, nil
+}
+
+func (repo *QuestionnaireResponseRepository) GetResponse(ctx context.Context, accountID string, id uuid.UUID) (*entity.QuestionnaireResponse, error) {
+	response := entity.QuestionnaireResponse{}
+	err := sqlscan.Get(ctx, repo.conn(), &response, getResponseQuery, accountID, id)
+	if err != nil {
+		return nil, err
+	}
+	return &response, nil
+}
+
+// GetAccountResponses returns the responses to every questionnaire, oldest first
+func (repo *QuestionnaireResponseRepository) GetAccountResponses(ctx context.Context, accountID string) ([]entity.QuestionnaireResponse, error) {
+	responses := make([]entity.QuestionnaireResponse, 0)
+	err := sqlscan.Select(ctx, repo.conn(), &responses, getAccountResponsesQuery, accountID)
+	if err != nil {
+		return nil, err
+	}
+	log.LoggerFromContext(ctx).Info(fmt.Sprintf("retrieved %d questionnaire responses", len(responses)))
+	return responses, nil
+}
+
+// ImportResponses inserts questionnaire responses with their ids, timestamps and scores preserved, all or none of them
+func (repo *QuestionnaireResponseRepository) ImportResponses(ctx context.Context, accountID string, responses []entity.QuestionnaireResponse) error {
+	insert := func(conn querier) error {
+		for _, response := range responses {
+			err := logExec(ctx, conn, importResponseQuery, "create", response.ID, accountID, response.QuestionnaireID, response.QuestionnaireVersion,
+				response.CreatedAt, response.Answers, response.TotalScore, response.SubscaleScores, response.Severity,
+			)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	if repo.tx != nil {
+		return insert(repo.tx)
+	}
+	return withTx(ctx, repo.DB, func(tx *sql.Tx) error {
+		return insert(tx)
+	})
+}
@@ -0,0 +1,33 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"github.com/cecobask/ocdtracker-api/internal/db"
+)
+
+type Store struct {
+	DB *sql.DB
+}
+
+var _ db.Store = (*Store)(nil)
+
+func NewStore(db *sql.DB) *Store {
+	return &Store{
+		DB: db,
+	}
+}
+
+// WithTx runs fn with repositories that share one transaction, committing if it succeeds and rolling back otherwise
+func (store *Store) WithTx(ctx context.Context, fn func(tx db.Tx) error) error {
+	return withTx(ctx, store.DB, func(tx *sql.Tx) error {
+		return fn(db.Tx{
+			Accounts:               &AccountRepository{DB: store.DB, tx: tx},
+			OCDLogs:                &OCDLogRepository{DB: store.DB, tx: tx},
+			Tags:                   &TagRepository{DB: store.DB, tx: tx},
+			ExposureItems:          &ExposureItemRepository{DB: store.DB, tx: tx},
+			ExposureSessions:       &ExposureSessionRepository{DB: store.DB, tx: tx},
+			QuestionnaireResponses: &QuestionnaireResponseRepository{DB: store.DB, tx: tx},
+		})
+	})
+}
@@ -0,0 +1,107 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"github.com/cecobask/ocdtracker-api/internal/db"
+	"github.com/cecobask/ocdtracker-api/pkg/entity"
+	"github.com/georgysavva/scany/sqlscan"
+	"github.com/google/uuid"
+)
+
+type TagRepository struct {
+	DB *sql.DB
+	tx *sql.Tx // set on repositories bound to a transaction by Store.WithTx
+}
+
+var _ db.TagRepository = (*TagRepository)(nil)
+
+const (
+	createTagQuery  = `INSERT INTO tag (id, account_id, name) VALUES ($1, $2, $3);`
+	getAllTagsQuery = `SELECT id, account_id, name, created_at, updated_at FROM tag WHERE account_id = $1 ORDER BY name ASC;`
+	getTagQuery     = `SELECT id, account_id, name, created_at, updated_at FROM tag WHERE account_id = $1 AND id = $2 LIMIT 1;`
+	updateTagQuery  = `UPDATE tag SET name = $3, updated_at = CURRENT_TIMESTAMP WHERE account_id = $1 AND id = $2;`
+	deleteTagQuery  = `DELETE FROM tag WHERE account_id = $1 AND id = $2;`
+	importTagQuery  = `INSERT INTO tag (id, account_id, name, created_at, updated_at) VALUES ($1, $2, $3, COALESCE($4, CURRENT_TIMESTAMP), $5) ON CONFLICT (account_id, name) DO NOTHING;`
+)
+
+func NewTagRepository(db *sql.DB) *TagRepository {
+	return &TagRepository{
+		DB: db,
+	}
+}
+
+func (repo *TagRepository) conn() querier {
+	if repo.tx != nil {
+		return repo.tx
+	}
+	return repo.DB
+}
+
+func (repo *TagRepository) CreateTag(ctx context.Context, accountID string, tag *entity.Tag) error {
+	tag.ID = uuid.New()
+	err := logExec(ctx, repo.conn(), createTagQuery, "create", tag.ID, accountID, entity.NormalizeTagName(*tag.Name))
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+func (repo *TagRepository) GetAllTags(ctx context.Context, accountID string) (*entity.TagList, error) {
+	tagList := entity.TagList{
+		Tags: make([]entity.Tag, 0),
+	}
+	err := sqlscan.Select(ctx, repo.conn(), &tagList.Tags, getAllTagsQuery, accountID)
+	if err != nil {
+		return nil, err
+	}
+	return &tagList, nil
+}
+
+func (repo *TagRepository) GetTag(ctx context.Context, accountID string, id uuid.UUID) (*entity.Tag, error) {
+	tag := entity.Tag{}
+	err := sqlscan.Get(ctx, repo.conn(), &tag, getTagQuery, accountID, id)
+	if err != nil {
+		return nil, err
+	}
+	return &tag, nil
+}
+
+func (repo *TagRepository) UpdateTag(ctx context.Context, accountID string, id uuid.UUID, tag *entity.Tag) error {
+	if tag.Name == nil {
+		return nil // no action
+	}
+	err := logExec(ctx, repo.conn(), updateTagQuery, "update", accountID, id, entity.NormalizeTagName(*tag.Name))
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+func (repo *TagRepository) DeleteTag(ctx context.Context, accountID string, id uuid.UUID) error {
+	err := logExec(ctx, repo.conn(), deleteTagQuery, "delete", accountID, id)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+// ImportTags inserts tags with their ids and timestamps preserved, all or none of them; tags whose name the account
+// already has are skipped
+func (repo *TagRepository) ImportTags(ctx context.Context, accountID string, tags []entity.Tag) error {
+	insert := func(conn querier) error {
+		for _, tag := range tags {
+			err := logExec(ctx, conn, importTagQuery, "create", tag.ID, accountID, entity.NormalizeTagName(*tag.Name), tag.CreatedAt, tag.UpdatedAt)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	if repo.tx != nil {
+		return insert(repo.tx)
+	}
+	return withTx(ctx, repo.DB, func(tx *sql.Tx) error {
+		return insert(tx)
+	})
+}
@@ -4,20 +4,111 @@ import (
 	"context"
 	"github.com/cecobask/ocdtracker-api/pkg/entity"
 	"github.com/google/uuid"
+	"time"
 )
 
+// storage backends of the repositories
+const (
+	StoragePostgres = "postgres"
+	StorageMemory   = "memory"
+)
+
+// Store runs operations that span several repositories in a single transaction
+type Store interface {
+	WithTx(ctx context.Context, fn func(tx Tx) error) error
+}
+
+// Tx holds the repositories bound to a transaction of a Store
+type Tx struct {
+	Accounts               AccountRepository
+	OCDLogs                OCDLogRepository
+	Tags                   TagRepository
+	ExposureItems          ExposureItemRepository
+	ExposureSessions       ExposureSessionRepository
+	QuestionnaireResponses QuestionnaireResponseRepository
+}
+
 type AccountRepository interface {
 	CreateAccount(ctx context.Context, account *entity.Account) error
 	DeleteAccount(ctx context.Context, id string) error
 	GetAccount(ctx context.Context, id string) (*entity.Account, error)
 	UpdateAccount(ctx context.Context, id string, account *entity.Account) error
+	GetReminderAccounts(ctx context.Context) ([]entity.Account, error)
 }
 
 type OCDLogRepository interface {
+	WithTx(ctx context.Context, fn func(txRepo OCDLogRepository) error) error
 	CreateLog(ctx context.Context, accountID string, ocdLog *entity.OCDLog) error
+	ImportLogs(ctx context.Context, accountID string, ocdLogs []entity.OCDLog) error
 	DeleteAllLogs(ctx context.Context, accountID string) error
 	DeleteLog(ctx context.Context, accountID string, id uuid.UUID) error
-	GetAllLogs(ctx context.Context, accountID string, limit, offset int) (*entity.OCDLogList, error)
+	GetAllLogs(ctx context.Context, accountID string, filter entity.OCDLogFilter, sort entity.OCDLogSort, limit, offset int) (*entity.OCDLogList, error)
+	StreamLogs(ctx context.Context, accountID string, filter entity.OCDLogFilter, sort entity.OCDLogSort, fn func(ocdLog entity.OCDLog) error) error
+	GetLogsByCursor(ctx context.Context, accountID string, filter entity.OCDLogFilter, cursor *entity.Cursor, descending bool, limit int) (*entity.OCDLogList, error)
 	GetLog(ctx context.Context, accountID string, id uuid.UUID) (*entity.OCDLog, error)
+	GetLogStats(ctx context.Context, accountID string, filter entity.OCDLogFilter) (*entity.OCDLogStats, error)
+	GetLogSeries(ctx context.Context, accountID string, filter entity.OCDLogFilter, query entity.OCDLogSeriesQuery) (*entity.OCDLogSeries, error)
 	UpdateLog(ctx context.Context, accountID string, id uuid.UUID, ocdLog *entity.OCDLog) error
+	GetLogRevisions(ctx context.Context, accountID string, id uuid.UUID) (*entity.OCDLogRevisionList, error)
+	GetLogRevision(ctx context.Context, accountID string, id uuid.UUID, revision int) (*entity.OCDLogRevision, error)
+	GetAccountLogRevisions(ctx context.Context, accountID string) (*entity.OCDLogRevisionList, error)
+	ImportLogRevisions(ctx context.Context, accountID string, revisions []entity.OCDLogRevision) error
+	GetTrashedLogs(ctx context.Context, accountID string, limit, offset int) (*entity.OCDLogList, error)
+	RestoreLog(ctx context.Context, accountID string, id uuid.UUID) error
+	PurgeTrashedLogs(ctx context.Context, deletedBefore time.Time) (int, error)
+}
+
+type TagRepository interface {
+	CreateTag(ctx context.Context, accountID string, tag *entity.Tag) error
+	GetAllTags(ctx context.Context, accountID string) (*entity.TagList, error)
+	GetTag(ctx context.Context, accountID string, id uuid.UUID) (*entity.Tag, error)
+	UpdateTag(ctx context.Context, accountID string, id uuid.UUID, tag *entity.Tag) error
+	DeleteTag(ctx context.Context, accountID string, id uuid.UUID) error
+	ImportTags(ctx context.Context, accountID string, tags []entity.Tag) error
+}
+
+type ExposureItemRepository interface {
+	CreateItem(ctx context.Context, accountID string, item *entity.ExposureItem) error
+	GetAllItems(ctx context.Context, accountID string) (*entity.ExposureItemList, error)
+	GetItem(ctx context.Context, accountID string, id uuid.UUID) (*entity.ExposureItem, error)
+	UpdateItem(ctx context.Context, accountID string, id uuid.UUID, item *entity.ExposureItem) error
+	DeleteItem(ctx context.Context, accountID string, id uuid.UUID) error
+	ImportItems(ctx context.Context, accountID string, items []entity.ExposureItem) error
+}
+
+type ExposureSessionRepository interface {
+	CreateSession(ctx context.Context, accountID string, session *entity.ExposureSession) error
+	GetAllSessions(ctx context.Context, accountID string, filter entity.ExposureSessionFilter, limit, offset int) (*entity.ExposureSessionList, error)
+	GetSession(ctx context.Context, accountID string, id uuid.UUID) (*entity.ExposureSession, error)
+	UpdateSession(ctx context.Context, accountID string, id uuid.UUID, session *entity.ExposureSession) error
+	DeleteSession(ctx context.Context, accountID string, id uuid.UUID) error
+	ImportSessions(ctx context.Context, accountID string, sessions []entity.ExposureSession) error
+}
+
+type QuestionnaireResponseRepository interface {
+	CreateResponse(ctx context.Context, accountID string, response *entity.QuestionnaireResponse) error
+	GetAllResponses(ctx context.Context, accountID, questionnaireID string, limit, offset int) (*entity.QuestionnaireResponseList, error)
+	GetResponse(ctx context.Context, accountID string, id uuid.UUID) (*entity.QuestionnaireResponse, error)
+	GetAccountResponses(ctx context.Context, accountID string) ([]entity.QuestionnaireResponse, error)
+	ImportResponses(ctx context.Context, accountID string, responses []entity.QuestionnaireResponse) error
+}
+
+type DeviceRepository interface {
+	RegisterDevice(ctx context.Context, accountID string, device *entity.Device) error
+	GetAllDevices(ctx context.Context, accountID string) (*entity.DeviceList, error)
+	GetDevice(ctx context.Context, accountID string, id uuid.UUID) (*entity.Device, error)
+	DeleteDevice(ctx context.Context, accountID string, id uuid.UUID) error
+	DeleteDeviceTokens(ctx context.Context, tokens []string) (int, error)
+}
+
+type GrantRepository interface {
+	CreateGrant(ctx context.Context, accountID string, grant *entity.Grant) error
+	GetAllGrants(ctx context.Context, accountID string) (*entity.GrantList, error)
+	GetGrant(ctx context.Context, id uuid.UUID) (*entity.Grant, error)
+	GetGrantByCode(ctx context.Context, code string) (*entity.Grant, error)
+	DeleteGrant(ctx context.Context, accountID string, id uuid.UUID) error
+	AcceptGrant(ctx context.Context, id uuid.UUID, clinicianID string) error
+	GetActiveGrant(ctx context.Context, accountID, clinicianID string) (*entity.Grant, error)
+	GetPatients(ctx context.Context, clinicianID string) (*entity.PatientList, error)
+	GetInvitations(ctx context.Context, email string) (*entity.PatientList, error)
 }
@@ -0,0 +1,34 @@
+package db
+
+import (
+	"github.com/cecobask/ocdtracker-api/pkg/entity"
+	"time"
+)
+
+// FillSeries returns a series with a point for every bucket of the query, using the aggregated points where present
+func FillSeries(points []entity.OCDLogSeriesPoint, query entity.OCDLogSeriesQuery) *entity.OCDLogSeries {
+	location := query.Zone()
+	pointsByBucket := make(map[time.Time]entity.OCDLogSeriesPoint, len(points))
+	for _, point := range points {
+		pointsByBucket[query.Bucket.Truncate(point.BucketStart.In(location))] = point
+	}
+	from, to := query.From.In(location), query.To.In(location)
+	series := entity.OCDLogSeries{
+		Bucket: query.Bucket,
+		From:   query.Bucket.Truncate(from),
+		To:     query.Bucket.Truncate(to),
+		Points: make([]entity.OCDLogSeriesPoint, 0, query.Bucket.PointCount(from, to)),
+	}
+	for bucketStart := series.From; !bucketStart.After(series.To); bucketStart = query.Bucket.Next(bucketStart) {
+		if query.Bucket == entity.SeriesBucketHour && query.AwakeWindow != nil && !query.AwakeWindow.OverlapsHour(bucketStart) {
+			continue
+		}
+		point, ok := pointsByBucket[bucketStart]
+		if !ok {
+			point = entity.OCDLogSeriesPoint{BucketStart: bucketStart}
+		}
+		point.BucketStart = bucketStart
+		series.Points = append(series.Points, point)
+	}
+	return &series
+}
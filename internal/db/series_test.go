@@ -0,0 +1,53 @@
+package db
+
+import (
+	"github.com/cecobask/ocdtracker-api/pkg/entity"
+	"testing"
+	"time"
+)
+
+func TestFillSeriesAddsEmptyBuckets(t *testing.T) {
+	avgAnxietyLevel := 4.5
+	query := entity.OCDLogSeriesQuery{
+		Bucket: entity.SeriesBucketDay,
+		From:   time.Date(2022, 3, 1, 18, 0, 0, 0, time.UTC),
+		To:     time.Date(2022, 3, 4, 6, 0, 0, 0, time.UTC),
+	}
+	points := []entity.OCDLogSeriesPoint{
+		{BucketStart: time.Date(2022, 3, 2, 0, 0, 0, 0, time.UTC), Count: 2, AvgAnxietyLevel: &avgAnxietyLevel, TotalRuminateMinutes: 15},
+	}
+	series := FillSeries(points, query)
+	if !series.From.Equal(time.Date(2022, 3, 1, 0, 0, 0, 0, time.UTC)) || !series.To.Equal(time.Date(2022, 3, 4, 0, 0, 0, 0, time.UTC)) {
+		t.Fatalf("expected the range to be truncated to whole days, got %s - %s", series.From, series.To)
+	}
+	if len(series.Points) != 4 {
+		t.Fatalf("expected a point per day, got %+v", series.Points)
+	}
+	for i, point := range series.Points {
+		if want := series.From.AddDate(0, 0, i); !point.BucketStart.Equal(want) {
+			t.Fatalf("expected point %d to start at %s, got %s", i, want, point.BucketStart)
+		}
+		if i == 1 {
+			if point.Count != 2 || *point.AvgAnxietyLevel != avgAnxietyLevel || point.TotalRuminateMinutes != 15 {
+				t.Fatalf("expected the aggregated point to be kept, got %+v", point)
+			}
+			continue
+		}
+		if point.Count != 0 || point.AvgAnxietyLevel != nil {
+			t.Fatalf("expected an empty bucket, got %+v", point)
+		}
+	}
+}
+
+func TestFillSeriesSkipsHoursOutsideAwakeWindow(t *testing.T) {
+	query := entity.OCDLogSeriesQuery{
+		Bucket:      entity.SeriesBucketHour,
+		From:        time.Date(2022, 3, 1, 0, 0, 0, 0, time.UTC),
+		To:          time.Date(2022, 3, 1, 23, 0, 0, 0, time.UTC),
+		AwakeWindow: &entity.DailyWindow{Start: "07:30", End: "22:00"},
+	}
+	series := FillSeries(nil, query)
+	if len(series.Points) != 15 || series.Points[0].BucketStart.Hour() != 7 || series.Points[14].BucketStart.Hour() != 21 {
+		t.Fatalf("expected the hours from 07:00 to 21:00, got %+v", series.Points)
+	}
+}
@@ -0,0 +1,170 @@
+package export
+
+import (
+	"archive/zip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/cecobask/ocdtracker-api/internal/db"
+	"github.com/cecobask/ocdtracker-api/pkg/entity"
+	"io"
+	"time"
+)
+
+const (
+	zipManifestName = "manifest.json"
+	zipDataName     = "data.json"
+	exportPageSize  = 500
+)
+
+// Repositories are the stores an archive collects the data of an account from
+type Repositories struct {
+	Accounts               db.AccountRepository
+	OCDLogs                db.OCDLogRepository
+	Tags                   db.TagRepository
+	ExposureItems          db.ExposureItemRepository
+	ExposureSessions       db.ExposureSessionRepository
+	QuestionnaireResponses db.QuestionnaireResponseRepository
+	Devices                db.DeviceRepository
+	Grants                 db.GrantRepository
+}
+
+// Build collects everything stored about an account into a versioned archive
+func Build(ctx context.Context, repos Repositories, accountID string) (*entity.AccountExport, error) {
+	account, err := repos.Accounts.GetAccount(ctx, accountID)
+	if err != nil {
+		return nil, err
+	}
+	data := entity.AccountExportData{
+		Account:        *account,
+		OCDLogs:        make([]entity.OCDLog, 0),
+		TrashedOCDLogs: make([]entity.OCDLog, 0),
+	}
+	err = repos.OCDLogs.StreamLogs(ctx, accountID, entity.OCDLogFilter{}, entity.DefaultOCDLogSort, func(ocdLog entity.OCDLog) error {
+		data.OCDLogs = append(data.OCDLogs, ocdLog)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	data.TrashedOCDLogs, err = collectPages(func(limit, offset int) ([]entity.OCDLog, error) {
+		ocdLogList, err := repos.OCDLogs.GetTrashedLogs(ctx, accountID, limit, offset)
+		if err != nil {
+			return nil, err
+		}
+		return ocdLogList.Logs, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	revisionList, err := repos.OCDLogs.GetAccountLogRevisions(ctx, accountID)
+	if err != nil {
+		return nil, err
+	}
+	data.OCDLogRevisions = revisionList.Revisions
+	tagList, err := repos.Tags.GetAllTags(ctx, accountID)
+	if err != nil {
+		return nil, err
+	}
+	data.Tags = tagList.Tags
+	itemList, err := repos.ExposureItems.GetAllItems(ctx, accountID)
+	if err != nil {
+		return nil, err
+	}
+	data.ExposureItems = itemList.Items
+	data.ExposureSessions, err = collectPages(func(limit, offset int) ([]entity.ExposureSession, error) {
+		sessionList, err := repos.ExposureSessions.GetAllSessions(ctx, accountID, entity.ExposureSessionFilter{}, limit, offset)
+		if err != nil {
+			return nil, err
+		}
+		return sessionList.Sessions, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	data.QuestionnaireResponses, err = repos.QuestionnaireResponses.GetAccountResponses(ctx, accountID)
+	if err != nil {
+		return nil, err
+	}
+	deviceList, err := repos.Devices.GetAllDevices(ctx, accountID)
+	if err != nil {
+		return nil, err
+	}
+	data.Devices = deviceList.Devices
+	for i := range data.Devices {
+		// a push token lets anyone who holds it send notifications to the device
+		data.Devices[i].Token = nil
+	}
+	grantList, err := repos.Grants.GetAllGrants(ctx, accountID)
+	if err != nil {
+		return nil, err
+	}
+	data.Grants = grantList.Grants
+	for i := range data.Grants {
+		// a pending invitation can be accepted by anyone who holds its code
+		data.Grants[i].Code = nil
+	}
+	dataJSON, err := json.Marshal(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode export data: %w", err)
+	}
+	checksum, err := entity.ExportChecksum(dataJSON)
+	if err != nil {
+		return nil, err
+	}
+	return &entity.AccountExport{
+		Manifest: entity.ExportManifest{
+			SchemaVersion: entity.ExportSchemaVersion,
+			GeneratedAt:   time.Now().UTC(),
+			AccountID:     accountID,
+			Checksum:      checksum,
+			Counts:        data.Counts(),
+		},
+		Data: dataJSON,
+	}, nil
+}
+
+// collectPages fetches a paginated listing page by page until a page comes back short
+func collectPages[T any](fetch func(limit, offset int) ([]T, error)) ([]T, error) {
+	all := make([]T, 0)
+	for offset := 0; ; offset += exportPageSize {
+		page, err := fetch(exportPageSize, offset)
+		if err != nil {
+			return nil, err
+		}
+		all = append(all, page...)
+		if len(page) < exportPageSize {
+			return all, nil
+		}
+	}
+}
+
+// WriteZip writes the manifest and the data as separate files of a zip archive
+func WriteZip(w io.Writer, accountExport *entity.AccountExport) error {
+	zipWriter := zip.NewWriter(w)
+	manifestJSON, err := json.MarshalIndent(accountExport.Manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+	files := []struct {
+		name string
+		body []byte
+	}{
+		{name: zipManifestName, body: manifestJSON},
+		{name: zipDataName, body: accountExport.Data},
+	}
+	for _, file := range files {
+		fileWriter, err := zipWriter.CreateHeader(&zip.FileHeader{
+			Name:     file.name,
+			Method:   zip.Deflate,
+			Modified: accountExport.Manifest.GeneratedAt,
+		})
+		if err != nil {
+			return err
+		}
+		if _, err = fileWriter.Write(file.body); err != nil {
+			return err
+		}
+	}
+	return zipWriter.Close()
+}
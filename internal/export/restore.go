@@ -0,0 +1,221 @@
+package export
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/cecobask/ocdtracker-api/internal/db"
+	"github.com/cecobask/ocdtracker-api/pkg/entity"
+	"github.com/google/uuid"
+)
+
+var (
+	ErrorUnsupportedSchemaVersion = errors.New("unsupported export schema version")
+	ErrorChecksumMismatch         = errors.New("export checksum does not match its data")
+	ErrorInvalidExport            = errors.New("invalid export")
+	errorDryRun                   = errors.New("dry run")
+)
+
+// Verify checks the schema version and the checksum of an archive and returns its decoded, validated data
+func Verify(accountExport *entity.AccountExport) (*entity.AccountExportData, error) {
+	if accountExport.Manifest.SchemaVersion != entity.ExportSchemaVersion {
+		return nil, fmt.Errorf("%w %d, expected %d", ErrorUnsupportedSchemaVersion, accountExport.Manifest.SchemaVersion, entity.ExportSchemaVersion)
+	}
+	if len(accountExport.Data) == 0 {
+		return nil, fmt.Errorf("%w: data is missing", ErrorInvalidExport)
+	}
+	checksum, err := entity.ExportChecksum(accountExport.Data)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrorInvalidExport, err)
+	}
+	if checksum != accountExport.Manifest.Checksum {
+		return nil, ErrorChecksumMismatch
+	}
+	var data entity.AccountExportData
+	if err = json.Unmarshal(accountExport.Data, &data); err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrorInvalidExport, err)
+	}
+	counts := data.Counts()
+	for name, count := range accountExport.Manifest.Counts {
+		if count != counts[name] {
+			return nil, fmt.Errorf("%w: manifest counts %d %s, data has %d", ErrorInvalidExport, count, name, counts[name])
+		}
+	}
+	if err = restoredSettings(data.Account).Validate(); err != nil {
+		return nil, fmt.Errorf("%w: account: %s", ErrorInvalidExport, err)
+	}
+	seen := make(map[uuid.UUID]bool, len(data.OCDLogs)+len(data.TrashedOCDLogs))
+	for i, ocdLog := range data.OCDLogs {
+		if err = verifyLog(ocdLog, seen); err != nil {
+			return nil, fmt.Errorf("%w: ocdlog %d: %s", ErrorInvalidExport, i, err)
+		}
+		if ocdLog.DeletedAt != nil {
+			return nil, fmt.Errorf("%w: ocdlog %d: deleted_at is only allowed in the trash", ErrorInvalidExport, i)
+		}
+	}
+	for i, ocdLog := range data.TrashedOCDLogs {
+		if err = verifyLog(ocdLog, seen); err != nil {
+			return nil, fmt.Errorf("%w: trashed ocdlog %d: %s", ErrorInvalidExport, i, err)
+		}
+		if ocdLog.DeletedAt == nil {
+			return nil, fmt.Errorf("%w: trashed ocdlog %d: deleted_at is required", ErrorInvalidExport, i)
+		}
+	}
+	revisions := make(map[uuid.UUID]int, len(data.OCDLogRevisions))
+	for i, revision := range data.OCDLogRevisions {
+		if !seen[revision.OCDLogID] {
+			return nil, fmt.Errorf("%w: ocdlog revision %d: log %s is not part of the export", ErrorInvalidExport, i, revision.OCDLogID)
+		}
+		if revision.Revision != revisions[revision.OCDLogID]+1 {
+			return nil, fmt.Errorf("%w: ocdlog revision %d: revision %d of log %s does not follow revision %d", ErrorInvalidExport, i,
+				revision.Revision, revision.OCDLogID, revisions[revision.OCDLogID])
+		}
+		revisions[revision.OCDLogID] = revision.Revision
+	}
+	tags := make(map[string]bool, len(data.Tags))
+	for i, tag := range data.Tags {
+		if tag.ID == uuid.Nil {
+			return nil, fmt.Errorf("%w: tag %d: id is required", ErrorInvalidExport, i)
+		}
+		if err = tag.Validate(); err != nil {
+			return nil, fmt.Errorf("%w: tag %d: %s", ErrorInvalidExport, i, err)
+		}
+		name := entity.NormalizeTagName(*tag.Name)
+		if tags[name] {
+			return nil, fmt.Errorf("%w: tag %d: duplicate name %q", ErrorInvalidExport, i, name)
+		}
+		tags[name] = true
+	}
+	items := make(map[uuid.UUID]bool, len(data.ExposureItems))
+	for i, item := range data.ExposureItems {
+		if item.ID == uuid.Nil || items[item.ID] {
+			return nil, fmt.Errorf("%w: exposure item %d: missing or duplicate id", ErrorInvalidExport, i)
+		}
+		items[item.ID] = true
+		if err = validateRecord(item); err != nil {
+			return nil, fmt.Errorf("%w: exposure item %d: %s", ErrorInvalidExport, i, err)
+		}
+	}
+	sessions := make(map[uuid.UUID]bool, len(data.ExposureSessions))
+	for i, session := range data.ExposureSessions {
+		if session.ID == uuid.Nil || sessions[session.ID] {
+			return nil, fmt.Errorf("%w: exposure session %d: missing or duplicate id", ErrorInvalidExport, i)
+		}
+		sessions[session.ID] = true
+		if err = validateRecord(session); err != nil {
+			return nil, fmt.Errorf("%w: exposure session %d: %s", ErrorInvalidExport, i, err)
+		}
+		if !items[*session.ItemID] {
+			return nil, fmt.Errorf("%w: exposure session %d: item %s is not part of the export", ErrorInvalidExport, i, *session.ItemID)
+		}
+	}
+	responses := make(map[uuid.UUID]bool, len(data.QuestionnaireResponses))
+	for i, response := range data.QuestionnaireResponses {
+		if response.ID == uuid.Nil || responses[response.ID] {
+			return nil, fmt.Errorf("%w: questionnaire response %d: missing or duplicate id", ErrorInvalidExport, i)
+		}
+		responses[response.ID] = true
+		if response.QuestionnaireID == "" || response.QuestionnaireVersion < 1 || len(response.Answers) == 0 {
+			return nil, fmt.Errorf("%w: questionnaire response %d: questionnaire_id, questionnaire_version and answers are required", ErrorInvalidExport, i)
+		}
+	}
+	return &data, nil
+}
+
+// verifyLog checks that an archived log can be recreated as it was and that its id is not used by another log
+func verifyLog(ocdLog entity.OCDLog, seen map[uuid.UUID]bool) error {
+	if ocdLog.ID == uuid.Nil || ocdLog.CreatedAt == nil {
+		return errors.New("id and created_at are required")
+	}
+	if seen[ocdLog.ID] {
+		return fmt.Errorf("duplicate id %s", ocdLog.ID)
+	}
+	seen[ocdLog.ID] = true
+	return ocdLog.Validate()
+}
+
+// validateRecord checks the fields that every record requires besides the constraints on their values
+func validateRecord(record interface {
+	Validate() error
+	ValidateCreate() error
+}) error {
+	if err := record.ValidateCreate(); err != nil {
+		return err
+	}
+	return record.Validate()
+}
+
+// Restore recreates the tags, the logs, including the trash and the revision history, the exposure hierarchy and
+// sessions and the questionnaire responses of an archive with their ids and timestamps and applies the account settings
+// in one transaction; a dry run performs all of it and rolls it back. Devices and grants are not restored since the
+// archive does not hold their tokens and invitation codes, and grants concern the accounts of clinicians as well
+func Restore(ctx context.Context, store db.Store, accountID string, data *entity.AccountExportData, dryRun bool) (*entity.AccountRestoreReport, error) {
+	settings := restoredSettings(data.Account)
+	err := store.WithTx(ctx, func(tx db.Tx) error {
+		// tags go first so that the logs find them by name instead of creating them with new ids
+		if len(data.Tags) > 0 {
+			if err := tx.Tags.ImportTags(ctx, accountID, data.Tags); err != nil {
+				return err
+			}
+		}
+		for _, ocdLogs := range [][]entity.OCDLog{data.OCDLogs, data.TrashedOCDLogs} {
+			if len(ocdLogs) == 0 {
+				continue
+			}
+			if err := tx.OCDLogs.ImportLogs(ctx, accountID, ocdLogs); err != nil {
+				return err
+			}
+		}
+		if len(data.OCDLogRevisions) > 0 {
+			if err := tx.OCDLogs.ImportLogRevisions(ctx, accountID, data.OCDLogRevisions); err != nil {
+				return err
+			}
+		}
+		if len(data.ExposureItems) > 0 {
+			if err := tx.ExposureItems.ImportItems(ctx, accountID, data.ExposureItems); err != nil {
+				return err
+			}
+		}
+		if len(data.ExposureSessions) > 0 {
+			if err := tx.ExposureSessions.ImportSessions(ctx, accountID, data.ExposureSessions); err != nil {
+				return err
+			}
+		}
+		if len(data.QuestionnaireResponses) > 0 {
+			if err := tx.QuestionnaireResponses.ImportResponses(ctx, accountID, data.QuestionnaireResponses); err != nil {
+				return err
+			}
+		}
+		if err := tx.Accounts.UpdateAccount(ctx, accountID, settings); err != nil {
+			return err
+		}
+		if dryRun {
+			return errorDryRun
+		}
+		return nil
+	})
+	if err != nil && !errors.Is(err, errorDryRun) {
+		return nil, err
+	}
+	settings.ID = accountID
+	counts := data.Counts()
+	delete(counts, "devices")
+	delete(counts, "grants")
+	return &entity.AccountRestoreReport{
+		DryRun:   dryRun,
+		Settings: *settings,
+		Counts:   counts,
+	}, nil
+}
+
+// restoredSettings picks the fields of an archived account that are restored; identity fields such as the email are
+// owned by firebase and stay as they are
+func restoredSettings(account entity.Account) *entity.Account {
+	return &entity.Account{
+		WakeTime:             account.WakeTime,
+		SleepTime:            account.SleepTime,
+		NotificationInterval: account.NotificationInterval,
+		TimeZone:             account.TimeZone,
+	}
+}
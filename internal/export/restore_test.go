@@ -0,0 +1,310 @@
+package export
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/cecobask/ocdtracker-api/internal/db"
+	"github.com/cecobask/ocdtracker-api/internal/db/memory"
+	"github.com/cecobask/ocdtracker-api/pkg/entity"
+	"github.com/cecobask/ocdtracker-api/pkg/log"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"testing"
+)
+
+type testStore struct {
+	store    *memory.Store
+	repos    Repositories
+	accounts *memory.AccountRepository
+}
+
+func newTestStore(t *testing.T, ctx context.Context, accountID string) testStore {
+	t.Helper()
+	memoryDB := memory.NewDB()
+	accountRepo := memory.NewAccountRepository(memoryDB)
+	email := accountID + "@example.com"
+	if err := accountRepo.CreateAccount(ctx, &entity.Account{ID: accountID, Email: &email}); err != nil {
+		t.Fatalf("failed to create account: %v", err)
+	}
+	return testStore{
+		store: memory.NewStore(memoryDB),
+		repos: Repositories{
+			Accounts:               accountRepo,
+			OCDLogs:                memory.NewOCDLogRepository(memoryDB),
+			Tags:                   memory.NewTagRepository(memoryDB),
+			ExposureItems:          memory.NewExposureItemRepository(memoryDB),
+			ExposureSessions:       memory.NewExposureSessionRepository(memoryDB),
+			QuestionnaireResponses: memory.NewQuestionnaireResponseRepository(memoryDB),
+			Devices:                memory.NewDeviceRepository(memoryDB),
+			Grants:                 memory.NewGrantRepository(memoryDB),
+		},
+		accounts: accountRepo,
+	}
+}
+
+func buildTestExport(t *testing.T, ctx context.Context, s testStore, accountID string) *entity.AccountExport {
+	t.Helper()
+	wakeTime, anxietyLevel := "06:45", 7
+	if err := s.accounts.UpdateAccount(ctx, accountID, &entity.Account{WakeTime: &wakeTime}); err != nil {
+		t.Fatalf("failed to update account: %v", err)
+	}
+	for i := 0; i < 2; i++ {
+		if err := s.repos.OCDLogs.CreateLog(ctx, accountID, &entity.OCDLog{AnxietyLevel: &anxietyLevel, Tags: entity.TagNames{"work"}}); err != nil {
+			t.Fatalf("failed to create log: %v", err)
+		}
+	}
+	unused := "unused"
+	if err := s.repos.Tags.CreateTag(ctx, accountID, &entity.Tag{Name: &unused}); err != nil {
+		t.Fatalf("failed to create tag: %v", err)
+	}
+	description, predictedSUDS, durationMinutes, sudsStart, sudsPeak, sudsEnd := "touch a doorknob", 60, 20, 50, 70, 30
+	item := &entity.ExposureItem{Description: &description, PredictedSUDS: &predictedSUDS}
+	if err := s.repos.ExposureItems.CreateItem(ctx, accountID, item); err != nil {
+		t.Fatalf("failed to create exposure item: %v", err)
+	}
+	session := &entity.ExposureSession{ItemID: &item.ID, DurationMinutes: &durationMinutes, SUDSStart: &sudsStart, SUDSPeak: &sudsPeak, SUDSEnd: &sudsEnd}
+	if err := s.repos.ExposureSessions.CreateSession(ctx, accountID, session); err != nil {
+		t.Fatalf("failed to create exposure session: %v", err)
+	}
+	severity := "mild"
+	response := &entity.QuestionnaireResponse{QuestionnaireID: "ybocs", QuestionnaireVersion: 1, Answers: entity.QuestionnaireAnswers{{Item: 1, Value: 2}},
+		TotalScore: 9, SubscaleScores: entity.SubscaleScores{"obsessions": 9}, Severity: &severity}
+	if err := s.repos.QuestionnaireResponses.CreateResponse(ctx, accountID, response); err != nil {
+		t.Fatalf("failed to create questionnaire response: %v", err)
+	}
+	token := "push-token"
+	if err := s.repos.Devices.RegisterDevice(ctx, accountID, &entity.Device{Token: &token}); err != nil {
+		t.Fatalf("failed to register device: %v", err)
+	}
+	ocdLogList, err := s.repos.OCDLogs.GetAllLogs(ctx, accountID, entity.OCDLogFilter{}, entity.DefaultOCDLogSort, 10, 0)
+	if err != nil {
+		t.Fatalf("failed to get logs: %v", err)
+	}
+	anxietyLevel++
+	for _, ocdLog := range ocdLogList.Logs {
+		if err = s.repos.OCDLogs.UpdateLog(ctx, accountID, ocdLog.ID, &entity.OCDLog{AnxietyLevel: &anxietyLevel}); err != nil {
+			t.Fatalf("failed to update log: %v", err)
+		}
+	}
+	if err = s.repos.OCDLogs.DeleteLog(ctx, accountID, ocdLogList.Logs[0].ID); err != nil {
+		t.Fatalf("failed to delete log: %v", err)
+	}
+	accountExport, err := Build(ctx, s.repos, accountID)
+	if err != nil {
+		t.Fatalf("failed to build export: %v", err)
+	}
+	return accountExport
+}
+
+// withData replaces the data of an archive and updates the checksum to match
+func withData(t *testing.T, accountExport entity.AccountExport, data entity.AccountExportData) entity.AccountExport {
+	t.Helper()
+	dataJSON, err := json.Marshal(data)
+	if err != nil {
+		t.Fatalf("failed to encode export data: %v", err)
+	}
+	accountExport.Data = dataJSON
+	if accountExport.Manifest.Checksum, err = entity.ExportChecksum(dataJSON); err != nil {
+		t.Fatalf("failed to compute checksum: %v", err)
+	}
+	return accountExport
+}
+
+func TestVerify(t *testing.T) {
+	ctx := log.ContextWithLogger(context.Background(), zap.NewNop())
+	s := newTestStore(t, ctx, "patient")
+	accountExport := buildTestExport(t, ctx, s, "patient")
+	data, err := Verify(accountExport)
+	if err != nil {
+		t.Fatalf("expected the export to verify, got %v", err)
+	}
+	if len(data.OCDLogs) != 1 || len(data.TrashedOCDLogs) != 1 || len(data.OCDLogRevisions) != 2 || len(data.Tags) != 2 || len(data.ExposureItems) != 1 || len(data.ExposureSessions) != 1 || len(data.QuestionnaireResponses) != 1 || len(data.Devices) != 1 || data.Devices[0].Token != nil || data.TrashedOCDLogs[0].DeletedAt == nil || *data.Account.WakeTime != "06:45" {
+		t.Fatalf("unexpected export data %+v", data)
+	}
+
+	tests := []struct {
+		name   string
+		modify func(accountExport entity.AccountExport) entity.AccountExport
+		err    error
+	}{
+		{
+			name: "unsupported schema version",
+			modify: func(accountExport entity.AccountExport) entity.AccountExport {
+				accountExport.Manifest.SchemaVersion++
+				return accountExport
+			},
+			err: ErrorUnsupportedSchemaVersion,
+		},
+		{
+			name: "tampered data",
+			modify: func(accountExport entity.AccountExport) entity.AccountExport {
+				accountExport.Data = []byte(`{"account":{},"ocdlogs":[]}`)
+				return accountExport
+			},
+			err: ErrorChecksumMismatch,
+		},
+		{
+			name: "counts that do not match the data",
+			modify: func(accountExport entity.AccountExport) entity.AccountExport {
+				accountExport.Manifest.Counts = map[string]int{"ocdlogs": 2}
+				return accountExport
+			},
+			err: ErrorInvalidExport,
+		},
+		{
+			name: "trashed log without deleted_at",
+			modify: func(accountExport entity.AccountExport) entity.AccountExport {
+				var data entity.AccountExportData
+				if err := json.Unmarshal(accountExport.Data, &data); err != nil {
+					t.Fatalf("failed to decode export data: %v", err)
+				}
+				data.TrashedOCDLogs[0].DeletedAt = nil
+				return withData(t, accountExport, data)
+			},
+			err: ErrorInvalidExport,
+		},
+		{
+			name: "revision of a log outside the export",
+			modify: func(accountExport entity.AccountExport) entity.AccountExport {
+				var data entity.AccountExportData
+				if err := json.Unmarshal(accountExport.Data, &data); err != nil {
+					t.Fatalf("failed to decode export data: %v", err)
+				}
+				data.OCDLogRevisions[0].OCDLogID = uuid.New()
+				return withData(t, accountExport, data)
+			},
+			err: ErrorInvalidExport,
+		},
+		{
+			name: "duplicate tag name",
+			modify: func(accountExport entity.AccountExport) entity.AccountExport {
+				var data entity.AccountExportData
+				if err := json.Unmarshal(accountExport.Data, &data); err != nil {
+					t.Fatalf("failed to decode export data: %v", err)
+				}
+				duplicate := " WORK "
+				data.Tags = append(data.Tags, entity.Tag{ID: uuid.New(), Name: &duplicate})
+				return withData(t, accountExport, data)
+			},
+			err: ErrorInvalidExport,
+		},
+		{
+			name: "exposure session of an item outside the export",
+			modify: func(accountExport entity.AccountExport) entity.AccountExport {
+				var data entity.AccountExportData
+				if err := json.Unmarshal(accountExport.Data, &data); err != nil {
+					t.Fatalf("failed to decode export data: %v", err)
+				}
+				itemID := uuid.New()
+				data.ExposureSessions[0].ItemID = &itemID
+				return withData(t, accountExport, data)
+			},
+			err: ErrorInvalidExport,
+		},
+		{
+			name: "questionnaire response without answers",
+			modify: func(accountExport entity.AccountExport) entity.AccountExport {
+				var data entity.AccountExportData
+				if err := json.Unmarshal(accountExport.Data, &data); err != nil {
+					t.Fatalf("failed to decode export data: %v", err)
+				}
+				data.QuestionnaireResponses[0].Answers = nil
+				return withData(t, accountExport, data)
+			},
+			err: ErrorInvalidExport,
+		},
+		{
+			name: "missing data",
+			modify: func(accountExport entity.AccountExport) entity.AccountExport {
+				accountExport.Data = nil
+				return accountExport
+			},
+			err: ErrorInvalidExport,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			modified := tt.modify(*accountExport)
+			if _, err := Verify(&modified); !errors.Is(err, tt.err) {
+				t.Fatalf("expected %v, got %v", tt.err, err)
+			}
+		})
+	}
+}
+
+func TestRestore(t *testing.T) {
+	ctx := log.ContextWithLogger(context.Background(), zap.NewNop())
+	s := newTestStore(t, ctx, "patient")
+	data, err := Verify(buildTestExport(t, ctx, s, "patient"))
+	if err != nil {
+		t.Fatalf("failed to verify export: %v", err)
+	}
+	otherWakeTime := "10:00"
+	if err = s.accounts.UpdateAccount(ctx, "patient", &entity.Account{WakeTime: &otherWakeTime}); err != nil {
+		t.Fatalf("failed to update account: %v", err)
+	}
+	expectState := func(logCount, trashedCount int, wakeTime string) {
+		t.Helper()
+		ocdLogList, err := s.repos.OCDLogs.GetAllLogs(ctx, "patient", entity.OCDLogFilter{}, entity.DefaultOCDLogSort, 10, 0)
+		if err != nil {
+			t.Fatalf("failed to get logs: %v", err)
+		}
+		trashedList, err := s.repos.OCDLogs.GetTrashedLogs(ctx, "patient", 10, 0)
+		if err != nil {
+			t.Fatalf("failed to get trashed logs: %v", err)
+		}
+		account, err := s.accounts.GetAccount(ctx, "patient")
+		if err != nil {
+			t.Fatalf("failed to get account: %v", err)
+		}
+		if len(ocdLogList.Logs) != logCount || len(trashedList.Logs) != trashedCount || *account.WakeTime != wakeTime {
+			t.Fatalf("expected %d logs, %d trashed and wake time %s, got %d, %d and %s", logCount, trashedCount, wakeTime,
+				len(ocdLogList.Logs), len(trashedList.Logs), *account.WakeTime)
+		}
+	}
+
+	// the logs still exist with the same ids, so the settings must be rolled back together with them
+	if _, err = Restore(ctx, s.store, "patient", data, false); !errors.Is(err, db.ErrorDuplicate) {
+		t.Fatalf("expected a duplicate error, got %v", err)
+	}
+	expectState(1, 1, otherWakeTime)
+
+	// re-creating the account removes everything but the settings are restored on top of the new account
+	if err = s.accounts.DeleteAccount(ctx, "patient"); err != nil {
+		t.Fatalf("failed to delete account: %v", err)
+	}
+	email := "patient@example.com"
+	if err = s.accounts.CreateAccount(ctx, &entity.Account{ID: "patient", Email: &email, WakeTime: &otherWakeTime}); err != nil {
+		t.Fatalf("failed to create account: %v", err)
+	}
+	report, err := Restore(ctx, s.store, "patient", data, true)
+	if err != nil || !report.DryRun || report.Counts["ocdlogs"] != 1 || report.Counts["trashed_ocdlogs"] != 1 || report.Counts["ocdlog_revisions"] != 2 || report.Counts["tags"] != 2 ||
+		report.Counts["exposure_items"] != 1 || report.Counts["exposure_sessions"] != 1 ||
+		report.Counts["questionnaire_responses"] != 1 || report.Counts["devices"] != 0 || *report.Settings.WakeTime != "06:45" {
+		t.Fatalf("unexpected dry run result %+v (%v)", report, err)
+	}
+	expectState(0, 0, otherWakeTime)
+
+	report, err = Restore(ctx, s.store, "patient", data, false)
+	if err != nil || report.DryRun {
+		t.Fatalf("unexpected restore result %+v (%v)", report, err)
+	}
+	expectState(1, 1, "06:45")
+	revisionList, err := s.repos.OCDLogs.GetAccountLogRevisions(ctx, "patient")
+	if err != nil || len(revisionList.Revisions) != 2 || revisionList.Revisions[0].Old.AnxietyLevel != 7 || revisionList.Revisions[0].New.AnxietyLevel != 8 {
+		t.Fatalf("expected the revisions to be restored, got %+v (%v)", revisionList, err)
+	}
+	tagList, err := s.repos.Tags.GetAllTags(ctx, "patient")
+	if err != nil || len(tagList.Tags) != 2 || tagList.Tags[0].ID != data.Tags[0].ID || tagList.Tags[1].ID != data.Tags[1].ID {
+		t.Fatalf("expected the tags to be restored with their ids, got %+v (%v)", tagList, err)
+	}
+	sessionList, err := s.repos.ExposureSessions.GetAllSessions(ctx, "patient", entity.ExposureSessionFilter{ItemID: &data.ExposureItems[0].ID}, 10, 0)
+	if err != nil || len(sessionList.Sessions) != 1 || sessionList.Sessions[0].ID != data.ExposureSessions[0].ID || *sessionList.Sessions[0].SUDSPeak != 70 {
+		t.Fatalf("expected the exposure sessions to be restored with their item, got %+v (%v)", sessionList, err)
+	}
+	responses, err := s.repos.QuestionnaireResponses.GetAccountResponses(ctx, "patient")
+	if err != nil || len(responses) != 1 || responses[0].ID != data.QuestionnaireResponses[0].ID || responses[0].TotalScore != 9 || *responses[0].Severity != "mild" {
+		t.Fatalf("expected the questionnaire responses to be restored with their scores, got %+v (%v)", responses, err)
+	}
+}
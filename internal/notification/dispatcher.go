@@ -0,0 +1,59 @@
+package notification
+
+import (
+	"context"
+	"github.com/cecobask/ocdtracker-api/internal/db"
+	"github.com/cecobask/ocdtracker-api/pkg/entity"
+	"github.com/cecobask/ocdtracker-api/pkg/log"
+	"go.uber.org/zap"
+	"time"
+)
+
+// Dispatcher delivers notifications to every registered device of an account and prunes the tokens the sender rejects
+type Dispatcher struct {
+	deviceRepo db.DeviceRepository
+	sender     Sender
+}
+
+func NewDispatcher(deviceRepo db.DeviceRepository, sender Sender) *Dispatcher {
+	return &Dispatcher{
+		deviceRepo: deviceRepo,
+		sender:     sender,
+	}
+}
+
+func (d *Dispatcher) Notify(ctx context.Context, accountID string, notification entity.Notification) error {
+	deviceList, err := d.deviceRepo.GetAllDevices(ctx, accountID)
+	if err != nil {
+		return err
+	}
+	if len(deviceList.Devices) == 0 {
+		return nil
+	}
+	tokens := make([]string, 0, len(deviceList.Devices))
+	for _, device := range deviceList.Devices {
+		tokens = append(tokens, *device.Token)
+	}
+	invalidTokens, sendErr := d.sender.Send(ctx, tokens, notification)
+	if len(invalidTokens) > 0 {
+		pruned, err := d.deviceRepo.DeleteDeviceTokens(ctx, invalidTokens)
+		if err != nil {
+			log.LoggerFromContext(ctx).Error("failed to prune invalid device tokens", zap.String("account_id", accountID), zap.Error(err))
+		} else {
+			log.LoggerFromContext(ctx).Info("pruned invalid device tokens", zap.String("account_id", accountID), zap.Int("count", pruned))
+		}
+	}
+	return sendErr
+}
+
+// Send delivers a check-in reminder, which makes the dispatcher a reminder sender
+func (d *Dispatcher) Send(ctx context.Context, reminder entity.Reminder) error {
+	return d.Notify(ctx, reminder.AccountID, entity.Notification{
+		Title: "Time to check in",
+		Body:  "How are you feeling? Take a moment to log your thoughts.",
+		Data: map[string]string{
+			"type": "reminder",
+			"at":   reminder.At.Format(time.RFC3339),
+		},
+	})
+}
@@ -0,0 +1,91 @@
+package notification
+
+import (
+	"context"
+	"errors"
+	"github.com/cecobask/ocdtracker-api/internal/db/memory"
+	"github.com/cecobask/ocdtracker-api/pkg/entity"
+	"github.com/cecobask/ocdtracker-api/pkg/log"
+	"go.uber.org/zap"
+	"testing"
+	"time"
+)
+
+// failingSender rejects some tokens and fails to deliver to the rest
+type failingSender struct {
+	invalid []string
+}
+
+func (s failingSender) Send(context.Context, []string, entity.Notification) ([]string, error) {
+	return s.invalid, errors.New("unavailable")
+}
+
+func newTestDevices(t *testing.T, ctx context.Context) *memory.DeviceRepository {
+	t.Helper()
+	memoryDB := memory.NewDB()
+	accountRepo := memory.NewAccountRepository(memoryDB)
+	deviceRepo := memory.NewDeviceRepository(memoryDB)
+	for accountID, tokens := range map[string][]string{"patient": {"phone", "tablet"}, "partner": {"laptop"}} {
+		email := accountID + "@example.com"
+		if err := accountRepo.CreateAccount(ctx, &entity.Account{ID: accountID, Email: &email}); err != nil {
+			t.Fatalf("failed to create account: %v", err)
+		}
+		for i := range tokens {
+			if err := deviceRepo.RegisterDevice(ctx, accountID, &entity.Device{Token: &tokens[i]}); err != nil {
+				t.Fatalf("failed to register device: %v", err)
+			}
+		}
+	}
+	return deviceRepo
+}
+
+func tokensOf(t *testing.T, ctx context.Context, deviceRepo *memory.DeviceRepository, accountID string) map[string]bool {
+	t.Helper()
+	deviceList, err := deviceRepo.GetAllDevices(ctx, accountID)
+	if err != nil {
+		t.Fatalf("failed to get devices: %v", err)
+	}
+	tokens := make(map[string]bool, len(deviceList.Devices))
+	for _, device := range deviceList.Devices {
+		tokens[*device.Token] = true
+	}
+	return tokens
+}
+
+func TestDispatcherNotifiesEveryDeviceAndPrunesInvalidTokens(t *testing.T) {
+	ctx := log.ContextWithLogger(context.Background(), zap.NewNop())
+	deviceRepo := newTestDevices(t, ctx)
+	sender := NewLogSender()
+	sender.Reject("tablet")
+	dispatcher := NewDispatcher(deviceRepo, sender)
+
+	at := time.Date(2024, time.March, 10, 12, 0, 0, 0, time.UTC)
+	if err := dispatcher.Send(ctx, entity.Reminder{AccountID: "patient", At: at}); err != nil {
+		t.Fatalf("failed to send reminder: %v", err)
+	}
+	sent := sender.Sent()
+	if len(sent) != 1 || sent[0].Token != "phone" || sent[0].Notification.Data["type"] != "reminder" || sent[0].Notification.Data["at"] != "2024-03-10T12:00:00Z" {
+		t.Fatalf("expected the reminder to reach only the valid device of the account, got %+v", sent)
+	}
+	if tokens := tokensOf(t, ctx, deviceRepo, "patient"); len(tokens) != 1 || !tokens["phone"] {
+		t.Fatalf("expected the rejected token to be pruned, got %v", tokens)
+	}
+	if tokens := tokensOf(t, ctx, deviceRepo, "partner"); !tokens["laptop"] {
+		t.Fatalf("expected the devices of other accounts to be kept, got %v", tokens)
+	}
+	if err := dispatcher.Notify(ctx, "nobody", entity.Notification{Title: "hello"}); err != nil || len(sender.Sent()) != 1 {
+		t.Fatalf("expected nothing to be sent to an account without devices, got %v", err)
+	}
+}
+
+func TestDispatcherPrunesInvalidTokensWhenDeliveryFails(t *testing.T) {
+	ctx := log.ContextWithLogger(context.Background(), zap.NewNop())
+	deviceRepo := newTestDevices(t, ctx)
+	dispatcher := NewDispatcher(deviceRepo, failingSender{invalid: []string{"phone"}})
+	if err := dispatcher.Notify(ctx, "patient", entity.Notification{Title: "hello"}); err == nil {
+		t.Fatalf("expected the delivery error to be returned")
+	}
+	if tokens := tokensOf(t, ctx, deviceRepo, "patient"); len(tokens) != 1 || !tokens["tablet"] {
+		t.Fatalf("expected only the rejected token to be pruned, got %v", tokens)
+	}
+}
@@ -0,0 +1,76 @@
+package notification
+
+import (
+	"context"
+	"firebase.google.com/go/v4/messaging"
+	"fmt"
+	"github.com/cecobask/ocdtracker-api/pkg/entity"
+)
+
+const (
+	maxMulticastTokens = 500
+)
+
+// MessagingClient is the subset of the firebase cloud messaging api used to deliver notifications
+type MessagingClient interface {
+	SendMulticast(ctx context.Context, message *messaging.MulticastMessage) (*messaging.BatchResponse, error)
+}
+
+var _ MessagingClient = (*messaging.Client)(nil)
+
+// FCMSender delivers notifications through firebase cloud messaging
+type FCMSender struct {
+	client MessagingClient
+}
+
+var _ Sender = (*FCMSender)(nil)
+
+func NewFCMSender(client MessagingClient) *FCMSender {
+	return &FCMSender{
+		client: client,
+	}
+}
+
+// Send delivers the notification in batches of up to 500 tokens
+func (s *FCMSender) Send(ctx context.Context, tokens []string, notification entity.Notification) ([]string, error) {
+	invalidTokens := make([]string, 0)
+	failed := 0
+	for start := 0; start < len(tokens); start += maxMulticastTokens {
+		end := start + maxMulticastTokens
+		if end > len(tokens) {
+			end = len(tokens)
+		}
+		batch := tokens[start:end]
+		response, err := s.client.SendMulticast(ctx, &messaging.MulticastMessage{
+			Tokens: batch,
+			Data:   notification.Data,
+			Notification: &messaging.Notification{
+				Title: notification.Title,
+				Body:  notification.Body,
+			},
+		})
+		if err != nil {
+			return invalidTokens, err
+		}
+		for i, result := range response.Responses {
+			if result.Success {
+				continue
+			}
+			if isInvalidToken(result.Error) {
+				invalidTokens = append(invalidTokens, batch[i])
+				continue
+			}
+			failed++
+		}
+	}
+	if failed > 0 {
+		return invalidTokens, fmt.Errorf("failed to deliver notification to %d of %d devices", failed, len(tokens))
+	}
+	return invalidTokens, nil
+}
+
+// isInvalidToken reports whether the token will never be deliverable again; invalid argument errors are not included
+// because they are also returned for problems with the message itself, which would prune valid tokens
+func isInvalidToken(err error) bool {
+	return messaging.IsUnregistered(err) || messaging.IsSenderIDMismatch(err)
+}
@@ -0,0 +1,62 @@
+package notification
+
+import (
+	"context"
+	"errors"
+	"firebase.google.com/go/v4/messaging"
+	"fmt"
+	"github.com/cecobask/ocdtracker-api/pkg/entity"
+	"testing"
+)
+
+// testMessagingClient records the batches it is given and fails delivery to the tokens in failing
+type testMessagingClient struct {
+	batches [][]string
+	failing map[string]bool
+	err     error
+}
+
+func (c *testMessagingClient) SendMulticast(_ context.Context, message *messaging.MulticastMessage) (*messaging.BatchResponse, error) {
+	if c.err != nil {
+		return nil, c.err
+	}
+	c.batches = append(c.batches, message.Tokens)
+	response := messaging.BatchResponse{}
+	for _, token := range message.Tokens {
+		if c.failing[token] {
+			response.FailureCount++
+			response.Responses = append(response.Responses, &messaging.SendResponse{Error: errors.New("internal error")})
+			continue
+		}
+		response.SuccessCount++
+		response.Responses = append(response.Responses, &messaging.SendResponse{Success: true})
+	}
+	return &response, nil
+}
+
+func TestFCMSenderSendsInBatches(t *testing.T) {
+	tokens := make([]string, 0, 1001)
+	for i := 0; i < 1001; i++ {
+		tokens = append(tokens, fmt.Sprintf("token-%d", i))
+	}
+	client := &testMessagingClient{}
+	invalidTokens, err := NewFCMSender(client).Send(context.Background(), tokens, entity.Notification{Title: "hello"})
+	if err != nil || len(invalidTokens) != 0 {
+		t.Fatalf("expected every token to be delivered, got %v (%v)", invalidTokens, err)
+	}
+	if len(client.batches) != 3 || len(client.batches[0]) != 500 || len(client.batches[2]) != 1 || client.batches[2][0] != "token-1000" {
+		t.Fatalf("expected batches of up to 500 tokens, got %d batches", len(client.batches))
+	}
+}
+
+func TestFCMSenderReportsFailures(t *testing.T) {
+	client := &testMessagingClient{failing: map[string]bool{"tablet": true}}
+	invalidTokens, err := NewFCMSender(client).Send(context.Background(), []string{"phone", "tablet"}, entity.Notification{Title: "hello"})
+	if err == nil || len(invalidTokens) != 0 {
+		t.Fatalf("expected a delivery error that does not prune the token, got %v (%v)", invalidTokens, err)
+	}
+	client = &testMessagingClient{err: errors.New("unauthenticated")}
+	if _, err = NewFCMSender(client).Send(context.Background(), []string{"phone"}, entity.Notification{Title: "hello"}); err == nil {
+		t.Fatalf("expected the client error to be returned")
+	}
+}
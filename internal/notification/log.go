@@ -0,0 +1,66 @@
+package notification
+
+import (
+	"context"
+	"github.com/cecobask/ocdtracker-api/pkg/entity"
+	"github.com/cecobask/ocdtracker-api/pkg/log"
+	"go.uber.org/zap"
+	"sync"
+)
+
+// Delivery is a notification recorded by the LogSender
+type Delivery struct {
+	Token        string
+	Notification entity.Notification
+}
+
+// LogSender logs notifications and keeps them in memory instead of delivering them; it stands in for firebase cloud
+// messaging during development and tests
+type LogSender struct {
+	mu       sync.Mutex
+	sent     []Delivery
+	rejected map[string]bool
+}
+
+var _ Sender = (*LogSender)(nil)
+
+func NewLogSender() *LogSender {
+	return &LogSender{
+		rejected: make(map[string]bool),
+	}
+}
+
+func (s *LogSender) Send(ctx context.Context, tokens []string, notification entity.Notification) ([]string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	invalidTokens := make([]string, 0)
+	for _, token := range tokens {
+		if s.rejected[token] {
+			invalidTokens = append(invalidTokens, token)
+			continue
+		}
+		s.sent = append(s.sent, Delivery{Token: token, Notification: notification})
+	}
+	log.LoggerFromContext(ctx).Info("notification sent",
+		zap.String("title", notification.Title),
+		zap.Int("devices", len(tokens)-len(invalidTokens)),
+		zap.Int("invalid_tokens", len(invalidTokens)),
+	)
+	return invalidTokens, nil
+}
+
+// Reject makes the sender report the tokens as invalid from now on
+func (s *LogSender) Reject(tokens ...string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, token := range tokens {
+		s.rejected[token] = true
+	}
+}
+
+// Sent returns the notifications recorded so far
+func (s *LogSender) Sent() []Delivery {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Delivery(nil), s.sent...)
+}
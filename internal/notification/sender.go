@@ -0,0 +1,17 @@
+package notification
+
+import (
+	"context"
+	"github.com/cecobask/ocdtracker-api/pkg/entity"
+)
+
+const (
+	SenderFCM = "fcm"
+	SenderLog = "log"
+)
+
+// Sender delivers a notification to device tokens; it returns the tokens the messaging service rejected as invalid or
+// no longer registered, which should not be used again
+type Sender interface {
+	Send(ctx context.Context, tokens []string, notification entity.Notification) ([]string, error)
+}
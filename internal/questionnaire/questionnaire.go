@@ -0,0 +1,127 @@
+package questionnaire
+
+import (
+	"embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/cecobask/ocdtracker-api/pkg/entity"
+	"io/fs"
+	"sort"
+)
+
+//go:embed definitions/*.json
+var definitions embed.FS
+
+var (
+	ErrorInvalidAnswers = errors.New("invalid answers")
+)
+
+// Registry holds the questionnaire definitions the api offers
+type Registry struct {
+	questionnaires map[string]entity.Questionnaire
+}
+
+// Load reads and validates the bundled questionnaire definitions
+func Load() (*Registry, error) {
+	files, err := fs.Glob(definitions, "definitions/*.json")
+	if err != nil {
+		return nil, err
+	}
+	registry := Registry{
+		questionnaires: make(map[string]entity.Questionnaire, len(files)),
+	}
+	for _, file := range files {
+		data, err := definitions.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+		var questionnaire entity.Questionnaire
+		if err = json.Unmarshal(data, &questionnaire); err != nil {
+			return nil, fmt.Errorf("error parsing questionnaire %s: %w", file, err)
+		}
+		if err = questionnaire.Validate(); err != nil {
+			return nil, fmt.Errorf("invalid questionnaire %s: %w", file, err)
+		}
+		if _, ok := registry.questionnaires[questionnaire.ID]; ok {
+			return nil, fmt.Errorf("duplicate questionnaire id %s in %s", questionnaire.ID, file)
+		}
+		registry.questionnaires[questionnaire.ID] = questionnaire
+	}
+	return &registry, nil
+}
+
+func (registry *Registry) Get(id string) (*entity.Questionnaire, bool) {
+	questionnaire, ok := registry.questionnaires[id]
+	if !ok {
+		return nil, false
+	}
+	return &questionnaire, true
+}
+
+// GetAll returns every questionnaire ordered by id
+func (registry *Registry) GetAll() *entity.QuestionnaireList {
+	questionnaireList := entity.QuestionnaireList{
+		Questionnaires: make([]entity.Questionnaire, 0, len(registry.questionnaires)),
+	}
+	for _, questionnaire := range registry.questionnaires {
+		questionnaireList.Questionnaires = append(questionnaireList.Questionnaires, questionnaire)
+	}
+	sort.Slice(questionnaireList.Questionnaires, func(i, j int) bool {
+		return questionnaireList.Questionnaires[i].ID < questionnaireList.Questionnaires[j].ID
+	})
+	return &questionnaireList
+}
+
+// Score checks that every item of the questionnaire is answered exactly once with a value of its scale and returns a
+// response with the total score, the subscale scores and the severity of the total
+func Score(questionnaire *entity.Questionnaire, answers entity.QuestionnaireAnswers) (*entity.QuestionnaireResponse, error) {
+	valuesByItem := make(map[int]int, len(answers))
+	for _, answer := range answers {
+		item, ok := questionnaire.Item(answer.Item)
+		if !ok {
+			return nil, fmt.Errorf("%w: unknown item %d", ErrorInvalidAnswers, answer.Item)
+		}
+		if _, ok = valuesByItem[answer.Item]; ok {
+			return nil, fmt.Errorf("%w: item %d is answered more than once", ErrorInvalidAnswers, answer.Item)
+		}
+		scale, _ := questionnaire.Scale(item.Scale)
+		if !scaleHasValue(scale, answer.Value) {
+			return nil, fmt.Errorf("%w: %d is not a valid answer to item %d", ErrorInvalidAnswers, answer.Value, answer.Item)
+		}
+		valuesByItem[answer.Item] = answer.Value
+	}
+	for _, item := range questionnaire.Items {
+		if _, ok := valuesByItem[item.ID]; !ok {
+			return nil, fmt.Errorf("%w: item %d is not answered", ErrorInvalidAnswers, item.ID)
+		}
+	}
+	response := entity.QuestionnaireResponse{
+		QuestionnaireID:      questionnaire.ID,
+		QuestionnaireVersion: questionnaire.Version,
+		Answers:              make(entity.QuestionnaireAnswers, 0, len(questionnaire.Items)),
+		SubscaleScores:       make(entity.SubscaleScores, len(questionnaire.Subscales)),
+	}
+	for _, item := range questionnaire.Items {
+		response.Answers = append(response.Answers, entity.QuestionnaireAnswer{Item: item.ID, Value: valuesByItem[item.ID]})
+		response.TotalScore += valuesByItem[item.ID]
+	}
+	for _, subscale := range questionnaire.Subscales {
+		score := 0
+		for _, id := range subscale.Items {
+			score += valuesByItem[id]
+		}
+		response.SubscaleScores[subscale.ID] = score
+	}
+	response.Severity = questionnaire.SeverityOf(response.TotalScore)
+	return &response, nil
+}
+
+func scaleHasValue(scale entity.AnswerScale, value int) bool {
+	for _, option := range scale.Options {
+		if option.Value == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,129 @@
+package questionnaire
+
+import (
+	"errors"
+	"github.com/cecobask/ocdtracker-api/pkg/entity"
+	"testing"
+)
+
+func loadQuestionnaire(t *testing.T, id string) *entity.Questionnaire {
+	t.Helper()
+	registry, err := Load()
+	if err != nil {
+		t.Fatalf("failed to load questionnaires: %v", err)
+	}
+	questionnaire, ok := registry.Get(id)
+	if !ok {
+		t.Fatalf("expected questionnaire %s to be bundled", id)
+	}
+	return questionnaire
+}
+
+// answerAll answers every item of a questionnaire with the value returned for it
+func answerAll(questionnaire *entity.Questionnaire, value func(item int) int) entity.QuestionnaireAnswers {
+	answers := make(entity.QuestionnaireAnswers, 0, len(questionnaire.Items))
+	for i := len(questionnaire.Items) - 1; i >= 0; i-- {
+		item := questionnaire.Items[i].ID
+		answers = append(answers, entity.QuestionnaireAnswer{Item: item, Value: value(item)})
+	}
+	return answers
+}
+
+func TestLoad(t *testing.T) {
+	registry, err := Load()
+	if err != nil {
+		t.Fatalf("failed to load questionnaires: %v", err)
+	}
+	questionnaireList := registry.GetAll()
+	if len(questionnaireList.Questionnaires) != 2 || questionnaireList.Questionnaires[0].ID != "ocir" || questionnaireList.Questionnaires[1].ID != "ybocs" {
+		t.Fatalf("expected the bundled questionnaires ordered by id, got %+v", questionnaireList.Questionnaires)
+	}
+	if _, ok := registry.Get("unknown"); ok {
+		t.Fatalf("expected an unknown questionnaire not to be found")
+	}
+}
+
+func TestScoreYBOCS(t *testing.T) {
+	ybocs := loadQuestionnaire(t, "ybocs")
+	tests := []struct {
+		name        string
+		value       func(item int) int
+		total       int
+		obsessions  int
+		compulsions int
+		severity    string
+	}{
+		{name: "lowest", value: func(int) int { return 0 }, total: 0, severity: "subclinical"},
+		{name: "band lower bound", value: func(item int) int {
+			if item <= 4 {
+				return 2
+			}
+			return 0
+		}, total: 8, obsessions: 8, severity: "mild"},
+		{name: "band upper bound", value: func(item int) int {
+			if item <= 5 {
+				return 3
+			}
+			return 1
+		}, total: 20, obsessions: 15, compulsions: 5, severity: "moderate"},
+		{name: "highest", value: func(int) int { return 4 }, total: 40, obsessions: 20, compulsions: 20, severity: "extreme"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			response, err := Score(ybocs, answerAll(ybocs, tt.value))
+			if err != nil {
+				t.Fatalf("failed to score: %v", err)
+			}
+			if response.TotalScore != tt.total || response.SubscaleScores["obsessions"] != tt.obsessions || response.SubscaleScores["compulsions"] != tt.compulsions {
+				t.Fatalf("expected total %d, obsessions %d and compulsions %d, got %+v", tt.total, tt.obsessions, tt.compulsions, response)
+			}
+			if response.Severity == nil || *response.Severity != tt.severity {
+				t.Fatalf("expected severity %s, got %v", tt.severity, response.Severity)
+			}
+			if response.QuestionnaireID != "ybocs" || response.QuestionnaireVersion != ybocs.Version || response.Answers[0].Item != 1 {
+				t.Fatalf("expected the answers in item order with the questionnaire version, got %+v", response)
+			}
+		})
+	}
+}
+
+func TestScoreOCIRSubscales(t *testing.T) {
+	ocir := loadQuestionnaire(t, "ocir")
+	response, err := Score(ocir, answerAll(ocir, func(item int) int {
+		if item%6 == 2 { // checking: 2, 8, 14
+			return 4
+		}
+		return 1
+	}))
+	if err != nil {
+		t.Fatalf("failed to score: %v", err)
+	}
+	if response.TotalScore != 27 || response.SubscaleScores["checking"] != 12 || response.SubscaleScores["washing"] != 3 || len(response.SubscaleScores) != 6 {
+		t.Fatalf("unexpected scores %+v", response)
+	}
+	if *response.Severity != "clinical range" {
+		t.Fatalf("expected the clinical range, got %s", *response.Severity)
+	}
+}
+
+func TestScoreRejectsInvalidAnswers(t *testing.T) {
+	ybocs := loadQuestionnaire(t, "ybocs")
+	valid := answerAll(ybocs, func(int) int { return 1 })
+	tests := []struct {
+		name    string
+		answers entity.QuestionnaireAnswers
+	}{
+		{name: "missing item", answers: valid[1:]},
+		{name: "unknown item", answers: append(valid[:len(valid):len(valid)], entity.QuestionnaireAnswer{Item: 11, Value: 1})},
+		{name: "item answered twice", answers: append(valid[:len(valid):len(valid)], entity.QuestionnaireAnswer{Item: 1, Value: 1})},
+		{name: "value outside the scale", answers: append(entity.QuestionnaireAnswers{{Item: 1, Value: 5}}, valid[:len(valid)-1]...)},
+		{name: "no answers"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := Score(ybocs, tt.answers); !errors.Is(err, ErrorInvalidAnswers) {
+				t.Fatalf("expected %v, got %v", ErrorInvalidAnswers, err)
+			}
+		})
+	}
+}
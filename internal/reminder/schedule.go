@@ -0,0 +1,69 @@
+package reminder
+
+import (
+	"fmt"
+	"github.com/cecobask/ocdtracker-api/pkg/entity"
+	"time"
+)
+
+const (
+	dateLayout  = "2006-01-02"
+	clockLayout = "15:04"
+)
+
+// Schedule computes the check-in reminders of an account for the awake period starting on the calendar day of date in
+// the time zone of the account: one every notification interval after the wake time, up to and including the sleep
+// time. A sleep time before the wake time means the period ends on the next day, an equal one that the account is awake
+// around the clock, and an interval of 0 that reminders are off
+func Schedule(account entity.Account, date time.Time) (*entity.ReminderSchedule, error) {
+	year, month, day := date.Date()
+	date = time.Date(year, month, day, 0, 0, 0, 0, account.Location())
+	schedule := entity.ReminderSchedule{
+		Date:                 date.Format(dateLayout),
+		WakeTime:             valueOrDefault(account.WakeTime, entity.DefaultWakeTime),
+		SleepTime:            valueOrDefault(account.SleepTime, entity.DefaultSleepTime),
+		NotificationInterval: account.ReminderInterval(),
+		Reminders:            make([]time.Time, 0),
+	}
+	wake, err := clockOn(date, schedule.WakeTime)
+	if err != nil {
+		return nil, fmt.Errorf("invalid wake time: %w", err)
+	}
+	sleep, err := clockOn(date, schedule.SleepTime)
+	if err != nil {
+		return nil, fmt.Errorf("invalid sleep time: %w", err)
+	}
+	schedule.WakeTime, schedule.SleepTime = wake.Format(clockLayout), sleep.Format(clockLayout)
+	if schedule.NotificationInterval <= 0 {
+		return &schedule, nil
+	}
+	if !sleep.After(wake) {
+		sleep = sleep.AddDate(0, 0, 1)
+	}
+	// reminders step by wall clock so that they keep their local times across daylight saving changes
+	for hours := schedule.NotificationInterval; ; hours += schedule.NotificationInterval {
+		at := time.Date(wake.Year(), wake.Month(), wake.Day(), wake.Hour()+hours, wake.Minute(), 0, 0, wake.Location())
+		if at.After(sleep) {
+			break
+		}
+		schedule.Reminders = append(schedule.Reminders, at)
+	}
+	return &schedule, nil
+}
+
+// clockOn returns the time of day clock (15:04) on the date of t
+func clockOn(t time.Time, clock string) (time.Time, error) {
+	parsed, err := time.Parse("15:4", clock)
+	if err != nil {
+		return time.Time{}, err
+	}
+	year, month, day := t.Date()
+	return time.Date(year, month, day, parsed.Hour(), parsed.Minute(), 0, 0, t.Location()), nil
+}
+
+func valueOrDefault[T any](value *T, defaultValue T) T {
+	if value == nil {
+		return defaultValue
+	}
+	return *value
+}
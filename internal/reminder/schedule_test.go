@@ -0,0 +1,125 @@
+package reminder
+
+import (
+	"github.com/cecobask/ocdtracker-api/pkg/entity"
+	"testing"
+	"time"
+)
+
+func settings(wakeTime, sleepTime string, interval int) entity.Account {
+	return entity.Account{ID: "patient", WakeTime: &wakeTime, SleepTime: &sleepTime, NotificationInterval: &interval}
+}
+
+func clocks(times []time.Time) []string {
+	formatted := make([]string, 0, len(times))
+	for _, t := range times {
+		formatted = append(formatted, t.Format("01-02 15:04"))
+	}
+	return formatted
+}
+
+func equalStrings(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestSchedule(t *testing.T) {
+	date := time.Date(2024, time.March, 10, 15, 30, 0, 0, time.UTC)
+	tests := []struct {
+		name      string
+		account   entity.Account
+		reminders []string
+	}{
+		{name: "same day", account: settings("08:00", "20:00", 4), reminders: []string{"03-10 12:00", "03-10 16:00", "03-10 20:00"}},
+		{name: "sleep time between reminders", account: settings("8:30", "19:00", 5), reminders: []string{"03-10 13:30", "03-10 18:30"}},
+		{name: "awake past midnight", account: settings("18:00", "02:00", 3), reminders: []string{"03-10 21:00", "03-11 00:00"}},
+		{name: "awake around the clock", account: settings("06:00", "06:00", 8), reminders: []string{"03-10 14:00", "03-10 22:00", "03-11 06:00"}},
+		{name: "reminders off", account: settings("08:00", "20:00", 0), reminders: []string{}},
+		{name: "defaults", account: entity.Account{ID: "patient"}, reminders: []string{"03-10 12:00", "03-10 15:00", "03-10 18:00", "03-10 21:00"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			schedule, err := Schedule(tt.account, date)
+			if err != nil {
+				t.Fatalf("failed to compute the schedule: %v", err)
+			}
+			if got := clocks(schedule.Reminders); !equalStrings(got, tt.reminders) {
+				t.Fatalf("expected reminders %v, got %v", tt.reminders, got)
+			}
+			if schedule.Date != "2024-03-10" {
+				t.Fatalf("expected the schedule of the date, got %s", schedule.Date)
+			}
+		})
+	}
+
+	schedule, err := Schedule(settings("8:5", "23:00", 3), date)
+	if err != nil || schedule.WakeTime != "08:05" || schedule.NotificationInterval != 3 {
+		t.Fatalf("expected the clock times to be normalised, got %+v (%v)", schedule, err)
+	}
+	if _, err = Schedule(settings("25:00", "23:00", 3), date); err == nil {
+		t.Fatalf("expected an invalid wake time to be rejected")
+	}
+}
+
+func TestDueReminders(t *testing.T) {
+	account := settings("18:00", "02:00", 3)
+	at := func(day, hour int) time.Time {
+		return time.Date(2024, time.March, day, hour, 0, 0, 0, time.UTC)
+	}
+	// the reminder at midnight belongs to the awake period of the previous day
+	due := dueReminders(account, at(10, 22), at(11, 1))
+	if got := clocks(due); !equalStrings(got, []string{"03-11 00:00"}) {
+		t.Fatalf("expected the reminder after midnight, got %v", got)
+	}
+	// since is exclusive and until inclusive, so consecutive checks never send a reminder twice
+	due = dueReminders(account, at(10, 21), at(10, 21))
+	if len(due) != 0 {
+		t.Fatalf("expected no reminders in an empty period, got %v", clocks(due))
+	}
+	due = dueReminders(account, at(10, 20), at(10, 21))
+	if got := clocks(due); !equalStrings(got, []string{"03-10 21:00"}) {
+		t.Fatalf("expected the reminder at the end of the period, got %v", got)
+	}
+}
+
+func TestScheduleInTimeZone(t *testing.T) {
+	london, err := time.LoadLocation("Europe/London")
+	if err != nil {
+		t.Skipf("time zone database unavailable: %v", err)
+	}
+	account := settings("00:00", "08:00", 2)
+	timeZone := london.String()
+	account.TimeZone = &timeZone
+	// clocks go forward from 01:00 GMT to 02:00 BST on 2022-03-27 and back from 02:00 BST to 01:00 GMT on 2022-10-30
+	for _, date := range []time.Time{
+		time.Date(2022, time.March, 27, 12, 0, 0, 0, time.UTC),
+		time.Date(2022, time.October, 30, 12, 0, 0, 0, time.UTC),
+	} {
+		schedule, err := Schedule(account, date)
+		if err != nil {
+			t.Fatalf("failed to compute the schedule: %v", err)
+		}
+		want := []string{"02:00", "04:00", "06:00", "08:00"}
+		got := make([]string, 0, len(schedule.Reminders))
+		for _, reminder := range schedule.Reminders {
+			if reminder.Location().String() != timeZone {
+				t.Fatalf("expected reminders in the time zone of the account, got %s", reminder)
+			}
+			got = append(got, reminder.Format(clockLayout))
+		}
+		if !equalStrings(got, want) {
+			t.Fatalf("expected reminders to keep their local times on %s, got %v", schedule.Date, got)
+		}
+	}
+	schedule, err := Schedule(account, time.Date(2022, time.March, 27, 23, 30, 0, 0, time.UTC))
+	if err != nil || schedule.Date != "2022-03-27" {
+		t.Fatalf("expected the schedule of the calendar day of the date, got %+v (%v)", schedule, err)
+	}
+}
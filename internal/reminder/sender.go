@@ -0,0 +1,11 @@
+package reminder
+
+import (
+	"context"
+	"github.com/cecobask/ocdtracker-api/pkg/entity"
+)
+
+// Sender delivers due reminders to the account holders
+type Sender interface {
+	Send(ctx context.Context, reminder entity.Reminder) error
+}
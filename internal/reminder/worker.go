@@ -0,0 +1,113 @@
+package reminder
+
+import (
+	"context"
+	"github.com/cecobask/ocdtracker-api/internal/db"
+	"github.com/cecobask/ocdtracker-api/pkg/entity"
+	"github.com/cecobask/ocdtracker-api/pkg/log"
+	"go.uber.org/zap"
+	"time"
+)
+
+// retryWindow is how long after falling due a reminder that could not be sent is retried on every check; a reminder
+// that arrives later than that is no longer useful
+const retryWindow = 30 * time.Minute
+
+// Worker periodically hands the reminders that fell due since its previous check to a sender
+type Worker struct {
+	accountRepo db.AccountRepository
+	sender      Sender
+	interval    time.Duration
+	failed      []entity.Reminder // reminders to retry on the next check; only used by Run
+}
+
+func NewWorker(accountRepo db.AccountRepository, sender Sender, interval time.Duration) *Worker {
+	return &Worker{
+		accountRepo: accountRepo,
+		sender:      sender,
+		interval:    interval,
+	}
+}
+
+func (w *Worker) Name() string {
+	return "reminder-worker"
+}
+
+// Run checks for due reminders on every interval until ctx is cancelled. Reminders that fell due while the worker was
+// not running are not sent; if the accounts cannot be loaded the same period is checked again on the next tick
+func (w *Worker) Run(ctx context.Context) error {
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+	since := time.Now()
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+		until := time.Now()
+		if w.sendDue(ctx, since, until) {
+			since = until
+		}
+	}
+}
+
+// sendDue sends the reminders in (since, until] along with the ones that failed on earlier checks and reports whether
+// the period was fully checked. A reminder whose delivery fails is retried until it is older than the retry window, so
+// devices of the account that did receive it may get it more than once
+func (w *Worker) sendDue(ctx context.Context, since, until time.Time) bool {
+	logger := log.LoggerFromContext(ctx)
+	accounts, err := w.accountRepo.GetReminderAccounts(ctx)
+	if err != nil {
+		logger.Error("failed to get reminder accounts", zap.Error(err))
+		return false
+	}
+	due := w.failed
+	w.failed = nil
+	for _, account := range accounts {
+		for _, at := range dueReminders(account, since, until) {
+			due = append(due, entity.Reminder{AccountID: account.ID, At: at})
+		}
+	}
+	sent := 0
+	for _, reminder := range due {
+		if err = w.sender.Send(ctx, reminder); err != nil {
+			if until.Sub(reminder.At) < retryWindow {
+				logger.Warn("failed to send reminder, retrying on the next check", zap.String("account_id", reminder.AccountID), zap.Error(err))
+				w.failed = append(w.failed, reminder)
+				continue
+			}
+			logger.Error("failed to send reminder", zap.String("account_id", reminder.AccountID), zap.Error(err))
+			continue
+		}
+		sent++
+	}
+	logger.Info("sent due reminders", zap.Int("count", sent), zap.Int("accounts", len(accounts)))
+	return true
+}
+
+// dueReminders returns the reminders of an account in (since, until]; the dates are those of the time zone of the
+// account and the day before since is included because its awake period may cross midnight
+func dueReminders(account entity.Account, since, until time.Time) []time.Time {
+	location := account.Location()
+	since, until = since.In(location), until.In(location)
+	due := make([]time.Time, 0)
+	lastDate := startOfDay(until)
+	for date := startOfDay(since).AddDate(0, 0, -1); !date.After(lastDate); date = date.AddDate(0, 0, 1) {
+		schedule, err := Schedule(account, date)
+		if err != nil {
+			continue
+		}
+		for _, at := range schedule.Reminders {
+			if at.After(since) && !at.After(until) {
+				due = append(due, at)
+			}
+		}
+	}
+	return due
+}
+
+func startOfDay(t time.Time) time.Time {
+	year, month, day := t.Date()
+	return time.Date(year, month, day, 0, 0, 0, 0, t.Location())
+}
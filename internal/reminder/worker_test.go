@@ -0,0 +1,85 @@
+package reminder
+
+import (
+	"context"
+	"errors"
+	"github.com/cecobask/ocdtracker-api/internal/db/memory"
+	"github.com/cecobask/ocdtracker-api/pkg/entity"
+	"github.com/cecobask/ocdtracker-api/pkg/log"
+	"go.uber.org/zap"
+	"testing"
+	"time"
+)
+
+// testSender records the reminders it is given and fails while err is set
+type testSender struct {
+	sent []entity.Reminder
+	err  error
+}
+
+func (s *testSender) Send(_ context.Context, reminder entity.Reminder) error {
+	if s.err != nil {
+		return s.err
+	}
+	s.sent = append(s.sent, reminder)
+	return nil
+}
+
+func TestWorkerRetriesFailedReminders(t *testing.T) {
+	ctx := log.ContextWithLogger(context.Background(), zap.NewNop())
+	memoryDB := memory.NewDB()
+	accountRepo := memory.NewAccountRepository(memoryDB)
+	wakeTime, sleepTime, interval, off := "08:00", "20:00", 4, 0
+	for id, notificationInterval := range map[string]*int{"patient": &interval, "muted": &off} {
+		email := id + "@example.com"
+		account := &entity.Account{ID: id, Email: &email, WakeTime: &wakeTime, SleepTime: &sleepTime, NotificationInterval: notificationInterval}
+		if err := accountRepo.CreateAccount(ctx, account); err != nil {
+			t.Fatalf("failed to create account: %v", err)
+		}
+	}
+	sender := &testSender{err: errors.New("unavailable")}
+	worker := NewWorker(accountRepo, sender, time.Minute)
+	at := func(hour, minute int) time.Time {
+		return time.Date(2024, time.March, 10, hour, minute, 0, 0, time.UTC)
+	}
+
+	if !worker.sendDue(ctx, at(11, 59), at(12, 0)) || len(worker.failed) != 1 {
+		t.Fatalf("expected the failed reminder to be kept for a retry, got %+v", worker.failed)
+	}
+	sender.err = nil
+	worker.sendDue(ctx, at(12, 0), at(12, 1))
+	if len(sender.sent) != 1 || sender.sent[0].AccountID != "patient" || !sender.sent[0].At.Equal(at(12, 0)) || len(worker.failed) != 0 {
+		t.Fatalf("expected the reminder to be sent on the next check, got %+v", sender.sent)
+	}
+
+	sender.err = errors.New("unavailable")
+	worker.sendDue(ctx, at(15, 59), at(16, 0))
+	worker.sendDue(ctx, at(16, 0), at(16, 30))
+	if len(worker.failed) != 0 {
+		t.Fatalf("expected the reminder to be dropped once it is older than the retry window, got %+v", worker.failed)
+	}
+	sender.err = nil
+	worker.sendDue(ctx, at(16, 30), at(16, 31))
+	if len(sender.sent) != 1 {
+		t.Fatalf("expected the dropped reminder not to be sent, got %+v", sender.sent)
+	}
+}
+
+func TestWorkerStopsWhenCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(log.ContextWithLogger(context.Background(), zap.NewNop()))
+	worker := NewWorker(memory.NewAccountRepository(memory.NewDB()), &testSender{}, time.Millisecond)
+	done := make(chan error, 1)
+	go func() {
+		done <- worker.Run(ctx)
+	}()
+	time.Sleep(10 * time.Millisecond)
+	cancel()
+	select {
+	case err := <-done:
+		if !errors.Is(err, context.Canceled) {
+			t.Fatalf("expected the worker to stop with the context error, got %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("expected the worker to stop when the context is cancelled")
+	}
+}
@@ -0,0 +1,88 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/cecobask/ocdtracker-api/pkg/log"
+	"go.uber.org/zap"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Worker is a background process that runs until its context is cancelled
+type Worker interface {
+	Name() string
+	Run(ctx context.Context) error
+}
+
+// Timeouts bound how long a client may take to send a request and keep an idle connection open, and how long
+// in-flight requests are given to finish on shutdown
+type Timeouts struct {
+	ReadHeader time.Duration
+	Read       time.Duration
+	Idle       time.Duration
+	Shutdown   time.Duration
+}
+
+type Server struct {
+	httpServer      *http.Server
+	shutdownTimeout time.Duration
+	workers         []Worker
+}
+
+func New(addr string, handler http.Handler, timeouts Timeouts, workers ...Worker) *Server {
+	return &Server{
+		httpServer: &http.Server{
+			Addr:              addr,
+			Handler:           handler,
+			ReadHeaderTimeout: timeouts.ReadHeader,
+			ReadTimeout:       timeouts.Read,
+			IdleTimeout:       timeouts.Idle,
+		},
+		shutdownTimeout: timeouts.Shutdown,
+		workers:         workers,
+	}
+}
+
+// Run serves http requests and runs the workers until ctx is cancelled, then drains in-flight requests
+// within the shutdown timeout and waits for the workers to stop
+func (s *Server) Run(ctx context.Context) error {
+	logger := log.LoggerFromContext(ctx)
+	workerCtx, stopWorkers := context.WithCancel(ctx)
+	defer stopWorkers()
+	var wg sync.WaitGroup
+	for _, worker := range s.workers {
+		wg.Add(1)
+		go func(worker Worker) {
+			defer wg.Done()
+			logger.Info("starting worker", zap.String("worker", worker.Name()))
+			if err := worker.Run(workerCtx); err != nil && !errors.Is(err, context.Canceled) {
+				logger.Error("worker stopped unexpectedly", zap.String("worker", worker.Name()), zap.Error(err))
+				return
+			}
+			logger.Info("stopped worker", zap.String("worker", worker.Name()))
+		}(worker)
+	}
+	serveErr := make(chan error, 1)
+	go func() {
+		logger.Info("starting http server", zap.String("url", s.httpServer.Addr))
+		serveErr <- s.httpServer.ListenAndServe()
+	}()
+	var err error
+	select {
+	case err = <-serveErr:
+		err = fmt.Errorf("failed to start http server: %w", err)
+	case <-ctx.Done():
+		logger.Info("shutting down http server", zap.Duration("timeout", s.shutdownTimeout))
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), s.shutdownTimeout)
+		defer cancel()
+		if shutdownErr := s.httpServer.Shutdown(shutdownCtx); shutdownErr != nil {
+			err = fmt.Errorf("failed to drain http server: %w", shutdownErr)
+		}
+	}
+	stopWorkers()
+	wg.Wait()
+	return err
+}
@@ -0,0 +1,102 @@
+package server
+
+import (
+	"context"
+	"github.com/cecobask/ocdtracker-api/pkg/log"
+	"go.uber.org/zap"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+)
+
+type testWorker struct {
+	stopped chan struct{}
+}
+
+func (w *testWorker) Name() string {
+	return "test"
+}
+
+func (w *testWorker) Run(ctx context.Context) error {
+	<-ctx.Done()
+	close(w.stopped)
+	return ctx.Err()
+}
+
+func freeAddr(t *testing.T) string {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to find a free port: %v", err)
+	}
+	addr := listener.Addr().String()
+	if err := listener.Close(); err != nil {
+		t.Fatalf("failed to release port: %v", err)
+	}
+	return addr
+}
+
+func TestRunDrainsInFlightRequestsAndStopsWorkers(t *testing.T) {
+	ctx, cancel := context.WithCancel(log.ContextWithLogger(context.Background(), zap.NewNop()))
+	defer cancel()
+	addr := freeAddr(t)
+	started := make(chan struct{})
+	release := make(chan struct{})
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		<-release
+		w.WriteHeader(http.StatusNoContent)
+	})
+	worker := &testWorker{stopped: make(chan struct{})}
+	srv := New(addr, handler, Timeouts{ReadHeader: time.Second, Shutdown: 5 * time.Second}, worker)
+	if srv.httpServer.ReadHeaderTimeout != time.Second {
+		t.Fatalf("expected the read header timeout to be set, got %s", srv.httpServer.ReadHeaderTimeout)
+	}
+	runErr := make(chan error, 1)
+	go func() {
+		runErr <- srv.Run(ctx)
+	}()
+
+	response := make(chan *http.Response, 1)
+	go func() {
+		for {
+			resp, err := http.Get("http://" + addr)
+			if err == nil {
+				response <- resp
+				return
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+	}()
+	<-started
+	cancel()
+	select {
+	case <-worker.stopped:
+	case <-time.After(5 * time.Second):
+		t.Fatalf("expected the worker to be stopped")
+	}
+	close(release)
+
+	resp := <-response
+	_ = resp.Body.Close()
+	if resp.StatusCode != http.StatusNoContent {
+		t.Fatalf("expected the in-flight request to complete, got %d", resp.StatusCode)
+	}
+	if err := <-runErr; err != nil {
+		t.Fatalf("expected a clean shutdown, got %v", err)
+	}
+}
+
+func TestRunReturnsListenErrors(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	defer listener.Close()
+	ctx := log.ContextWithLogger(context.Background(), zap.NewNop())
+	srv := New(listener.Addr().String(), http.NotFoundHandler(), Timeouts{Shutdown: time.Second})
+	if err := srv.Run(ctx); err == nil {
+		t.Fatalf("expected an error when the address is in use")
+	}
+}
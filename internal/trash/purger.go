@@ -0,0 +1,52 @@
+package trash
+
+import (
+	"context"
+	"github.com/cecobask/ocdtracker-api/internal/db"
+	"github.com/cecobask/ocdtracker-api/pkg/log"
+	"go.uber.org/zap"
+	"time"
+)
+
+// Purger permanently removes logs that have been in the trash for longer than the retention period
+type Purger struct {
+	ocdLogRepo db.OCDLogRepository
+	retention  time.Duration
+	interval   time.Duration
+}
+
+func NewPurger(ocdLogRepo db.OCDLogRepository, retention, interval time.Duration) *Purger {
+	return &Purger{
+		ocdLogRepo: ocdLogRepo,
+		retention:  retention,
+		interval:   interval,
+	}
+}
+
+func (p *Purger) Name() string {
+	return "trash-purger"
+}
+
+// Run purges once on start and then on every interval until ctx is cancelled; failed purges are retried on the next tick
+func (p *Purger) Run(ctx context.Context) error {
+	ticker := time.NewTicker(p.interval)
+	defer ticker.Stop()
+	for {
+		p.purge(ctx)
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+func (p *Purger) purge(ctx context.Context) {
+	logger := log.LoggerFromContext(ctx)
+	purged, err := p.ocdLogRepo.PurgeTrashedLogs(ctx, time.Now().Add(-p.retention))
+	if err != nil {
+		logger.Error("failed to purge trashed logs", zap.Error(err))
+		return
+	}
+	logger.Info("purged trashed logs", zap.Int("count", purged), zap.Duration("retention", p.retention))
+}
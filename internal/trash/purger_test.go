@@ -0,0 +1,69 @@
+package trash
+
+import (
+	"context"
+	"github.com/cecobask/ocdtracker-api/internal/db/memory"
+	"github.com/cecobask/ocdtracker-api/pkg/entity"
+	"github.com/cecobask/ocdtracker-api/pkg/log"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"testing"
+	"time"
+)
+
+func TestPurgerRemovesLogsPastRetention(t *testing.T) {
+	ctx, cancel := context.WithCancel(log.ContextWithLogger(context.Background(), zap.NewNop()))
+	defer cancel()
+	memoryDB := memory.NewDB()
+	email := "patient@example.com"
+	if err := memory.NewAccountRepository(memoryDB).CreateAccount(ctx, &entity.Account{ID: "patient", Email: &email}); err != nil {
+		t.Fatalf("failed to create account: %v", err)
+	}
+	ocdLogRepo := memory.NewOCDLogRepository(memoryDB)
+	deletedAt := func(age time.Duration) *time.Time {
+		value := time.Now().Add(-age)
+		return &value
+	}
+	expired, recent, kept := uuid.New(), uuid.New(), uuid.New()
+	err := ocdLogRepo.ImportLogs(ctx, "patient", []entity.OCDLog{
+		{ID: expired, DeletedAt: deletedAt(2 * time.Hour)},
+		{ID: recent, DeletedAt: deletedAt(time.Minute)},
+		{ID: kept},
+	})
+	if err != nil {
+		t.Fatalf("failed to import logs: %v", err)
+	}
+
+	purger := NewPurger(ocdLogRepo, time.Hour, time.Hour)
+	done := make(chan error, 1)
+	go func() {
+		done <- purger.Run(ctx)
+	}()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		trash, err := ocdLogRepo.GetTrashedLogs(ctx, "patient", 10, 0)
+		if err != nil {
+			t.Fatalf("failed to get trashed logs: %v", err)
+		}
+		if len(trash.Logs) == 1 {
+			if trash.Logs[0].ID != recent {
+				t.Fatalf("expected only the recently deleted log to stay in the trash, got %+v", trash.Logs)
+			}
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("expected the purger to remove the expired log, got %+v", trash.Logs)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if _, err = ocdLogRepo.GetLog(ctx, "patient", kept); err != nil {
+		t.Fatalf("expected logs outside the trash to be kept, got %v", err)
+	}
+
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatalf("expected the purger to stop when the context is cancelled")
+	}
+}
@@ -6,72 +6,184 @@ import (
 	"fmt"
 	"github.com/aws/aws-sdk-go/aws/session"
 	"github.com/cecobask/ocdtracker-api/internal/api/account"
+	"github.com/cecobask/ocdtracker-api/internal/api/erp"
+	"github.com/cecobask/ocdtracker-api/internal/api/health"
 	"github.com/cecobask/ocdtracker-api/internal/api/middleware"
 	"github.com/cecobask/ocdtracker-api/internal/api/ocdlog"
+	"github.com/cecobask/ocdtracker-api/internal/api/patient"
+	questionnaireAPI "github.com/cecobask/ocdtracker-api/internal/api/questionnaire"
+	"github.com/cecobask/ocdtracker-api/internal/api/tag"
+	"github.com/cecobask/ocdtracker-api/internal/auth"
 	"github.com/cecobask/ocdtracker-api/internal/aws"
+	"github.com/cecobask/ocdtracker-api/internal/config"
+	"github.com/cecobask/ocdtracker-api/internal/db"
+	"github.com/cecobask/ocdtracker-api/internal/db/memory"
 	"github.com/cecobask/ocdtracker-api/internal/db/postgres"
+	"github.com/cecobask/ocdtracker-api/internal/notification"
+	"github.com/cecobask/ocdtracker-api/internal/questionnaire"
+	"github.com/cecobask/ocdtracker-api/internal/reminder"
+	"github.com/cecobask/ocdtracker-api/internal/server"
+	"github.com/cecobask/ocdtracker-api/internal/trash"
 	"github.com/cecobask/ocdtracker-api/pkg/log"
 	"github.com/go-chi/chi/v5"
 	chiMiddleware "github.com/go-chi/chi/v5/middleware"
 	"go.uber.org/zap"
 	"google.golang.org/api/option"
-	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
 )
 
 func main() {
 	logger := log.NewLogger()
-	ctx := log.ContextWithLogger(context.Background(), logger)
-	sess := session.Must(session.NewSession())
-	postgresCreds, err := aws.NewSecretsManager(sess).GetPostgresCreds()
-	if err != nil {
-		logger.Fatal("failed to get postgres credentials", zap.Error(err))
+	ctx, stop := signal.NotifyContext(log.ContextWithLogger(context.Background(), logger), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	if err := run(ctx); err != nil {
+		logger.Fatal("server exited with error", zap.Error(err))
 	}
-	db, err := postgres.Connect(ctx, postgresCreds)
+	logger.Info("server stopped")
+}
+
+// run wires up all dependencies and blocks until ctx is cancelled and the server has shut down
+func run(ctx context.Context) error {
+	logger := log.LoggerFromContext(ctx)
+	cfg, err := config.Load()
 	if err != nil {
-		logger.Fatal("failed to connect to database", zap.Error(err))
+		return fmt.Errorf("failed to load configuration: %w", err)
 	}
-	defer func() {
-		if err := db.Close(); err != nil {
-			logger.Fatal("failed to close database connection", zap.Error(err))
+	var (
+		accountRepo               db.AccountRepository
+		ocdLogRepo                db.OCDLogRepository
+		tagRepo                   db.TagRepository
+		exposureItemRepo          db.ExposureItemRepository
+		exposureSessionRepo       db.ExposureSessionRepository
+		questionnaireResponseRepo db.QuestionnaireResponseRepository
+		deviceRepo                db.DeviceRepository
+		grantRepo                 db.GrantRepository
+		store                     db.Store
+		authClient                auth.Client
+		notificationSender        notification.Sender = notification.NewLogSender()
+		healthChecks              []health.Check
+	)
+	switch cfg.Storage {
+	case db.StorageMemory:
+		logger.Warn("using in-memory storage, all data is lost when the server stops")
+		memoryDB := memory.NewDB()
+		accountRepo = memory.NewAccountRepository(memoryDB)
+		ocdLogRepo = memory.NewOCDLogRepository(memoryDB)
+		tagRepo = memory.NewTagRepository(memoryDB)
+		exposureItemRepo = memory.NewExposureItemRepository(memoryDB)
+		exposureSessionRepo = memory.NewExposureSessionRepository(memoryDB)
+		questionnaireResponseRepo = memory.NewQuestionnaireResponseRepository(memoryDB)
+		deviceRepo = memory.NewDeviceRepository(memoryDB)
+		grantRepo = memory.NewGrantRepository(memoryDB)
+		store = memory.NewStore(memoryDB)
+		authClient = auth.NewStubClient()
+	default:
+		sess := session.Must(session.NewSession())
+		postgresCreds, err := aws.NewSecretsManager(sess, cfg.AWS.Region).GetPostgresCreds(cfg.AWS.PostgresCredsSecretName)
+		if err != nil {
+			return fmt.Errorf("failed to get postgres credentials: %w", err)
 		}
-	}()
-	err = postgres.Migrate(db)
-	if err != nil {
-		logger.Fatal("failed to migrate database", zap.Error(err))
-	}
-	googleAppCreds, err := aws.NewS3(sess).GetGoogleAppCreds(ctx)
-	if err != nil {
-		logger.Fatal("failed to get google application credentials", zap.Error(err))
-	}
-	config := firebase.Config{ProjectID: googleAppCreds.ProjectID}
-	firebaseApp, err := firebase.NewApp(ctx, &config, option.WithCredentials(googleAppCreds))
-	if err != nil {
-		logger.Fatal("error initialising firebase app", zap.Error(err))
+		postgresDB, err := postgres.Connect(ctx, postgresCreds, cfg.Postgres)
+		if err != nil {
+			return fmt.Errorf("failed to connect to database: %w", err)
+		}
+		defer func() {
+			if err := postgresDB.Close(); err != nil {
+				logger.Error("failed to close database connection", zap.Error(err))
+				return
+			}
+			logger.Info("closed database connection")
+		}()
+		err = postgres.Migrate(postgresDB, cfg.Postgres.MigrationPath)
+		if err != nil {
+			return fmt.Errorf("failed to migrate database: %w", err)
+		}
+		migrationVersion, err := postgres.LatestMigrationVersion(cfg.Postgres.MigrationPath)
+		if err != nil {
+			return fmt.Errorf("failed to get latest migration version: %w", err)
+		}
+		accountRepo = postgres.NewAccountRepository(postgresDB)
+		ocdLogRepo = postgres.NewOCDLogRepository(postgresDB)
+		tagRepo = postgres.NewTagRepository(postgresDB)
+		exposureItemRepo = postgres.NewExposureItemRepository(postgresDB)
+		exposureSessionRepo = postgres.NewExposureSessionRepository(postgresDB)
+		questionnaireResponseRepo = postgres.NewQuestionnaireResponseRepository(postgresDB)
+		deviceRepo = postgres.NewDeviceRepository(postgresDB)
+		grantRepo = postgres.NewGrantRepository(postgresDB)
+		store = postgres.NewStore(postgresDB)
+		googleAppCreds, err := aws.NewS3(sess, cfg.AWS.Region).GetGoogleAppCreds(ctx, cfg.AWS.Bucket, cfg.AWS.GoogleAppCredsObjectKey)
+		if err != nil {
+			return fmt.Errorf("failed to get google application credentials: %w", err)
+		}
+		firebaseConfig := firebase.Config{ProjectID: googleAppCreds.ProjectID}
+		firebaseApp, err := firebase.NewApp(ctx, &firebaseConfig, option.WithCredentials(googleAppCreds))
+		if err != nil {
+			return fmt.Errorf("error initialising firebase app: %w", err)
+		}
+		authClient, err = firebaseApp.Auth(ctx)
+		if err != nil {
+			return fmt.Errorf("unable to create firebase auth client: %w", err)
+		}
+		if cfg.Notification.Sender == notification.SenderFCM {
+			messagingClient, err := firebaseApp.Messaging(ctx)
+			if err != nil {
+				return fmt.Errorf("unable to create firebase messaging client: %w", err)
+			}
+			notificationSender = notification.NewFCMSender(messagingClient)
+		}
+		healthChecks = append(healthChecks,
+			health.Check{Name: "postgres", Probe: postgresDB.PingContext},
+			health.Check{Name: "migration", Probe: func(ctx context.Context) error {
+				return postgres.CheckMigrationVersion(ctx, postgresDB, migrationVersion)
+			}},
+			health.Check{Name: "firebase", Probe: func(ctx context.Context) error {
+				return auth.Ping(ctx, authClient)
+			}},
+		)
 	}
-	authClient, err := firebaseApp.Auth(ctx)
+	questionnaireRegistry, err := questionnaire.Load()
 	if err != nil {
-		logger.Fatal("unable to create firebase auth client", zap.Error(err))
+		return fmt.Errorf("failed to load questionnaires: %w", err)
 	}
-	accountRepo := postgres.NewAccountRepository(db)
-	ocdLogRepo := postgres.NewOCDLogRepository(db)
-	accountHandler := account.NewHandler(ctx, accountRepo, authClient)
+	accountHandler := account.NewHandler(ctx, accountRepo, ocdLogRepo, tagRepo, exposureItemRepo, exposureSessionRepo, questionnaireResponseRepo, deviceRepo, grantRepo, store, authClient)
 	ocdLogHandler := ocdlog.NewHandler(ctx, ocdLogRepo)
+	tagHandler := tag.NewHandler(ctx, tagRepo)
+	erpHandler := erp.NewHandler(ctx, exposureItemRepo, exposureSessionRepo)
+	questionnaireHandler := questionnaireAPI.NewHandler(ctx, questionnaireRegistry, questionnaireResponseRepo)
+	patientHandler := patient.NewHandler(ctx, grantRepo, accountRepo)
+	healthHandler := health.NewHandler(ctx, time.Duration(cfg.Server.HealthCheckTimeout), healthChecks...)
 	chiRouter := chi.NewRouter()
 	chiRouter.Use(
 		chiMiddleware.Recoverer,
 		middleware.NewRequestLoggerMiddleware(ctx).Handle,
-		middleware.NewAuthMiddleware(ctx, authClient, accountRepo).Handle,
-		middleware.NewPaginationMiddleware(ctx).Handle,
 	)
-	chiRouter.Mount("/ocdlog", ocdlog.NewRouter(ocdLogHandler))
-	chiRouter.Mount("/account", account.NewRouter(accountHandler))
-	server := http.Server{
-		Addr:    fmt.Sprintf(":%s", "8080"),
-		Handler: chiRouter,
-	}
-	logger.Info("starting http server", zap.String("url", server.Addr))
-	err = server.ListenAndServe()
-	if err != nil {
-		logger.Fatal("failed to start http server", zap.Error(err))
-	}
+	health.RegisterRoutes(chiRouter, healthHandler)
+	chiRouter.Group(func(r chi.Router) {
+		r.Use(
+			middleware.NewAuthMiddleware(ctx, authClient, accountRepo).Handle,
+			middleware.NewPaginationMiddleware(ctx).Handle,
+		)
+		r.Mount("/ocdlog", ocdlog.NewRouter(ocdLogHandler))
+		r.Mount("/account", account.NewRouter(accountHandler))
+		r.Mount("/tag", tag.NewRouter(tagHandler))
+		r.Mount("/erp", erp.NewRouter(erpHandler))
+		r.Mount("/questionnaire", questionnaireAPI.NewRouter(questionnaireHandler))
+		r.Mount("/patients", patient.NewRouter(patientHandler, ocdLogHandler, questionnaireHandler))
+	})
+	srv := server.New(
+		fmt.Sprintf(":%s", cfg.Server.Port),
+		chiRouter,
+		server.Timeouts{
+			ReadHeader: time.Duration(cfg.Server.ReadHeaderTimeout),
+			Read:       time.Duration(cfg.Server.ReadTimeout),
+			Idle:       time.Duration(cfg.Server.IdleTimeout),
+			Shutdown:   time.Duration(cfg.Server.ShutdownTimeout),
+		},
+		trash.NewPurger(ocdLogRepo, time.Duration(cfg.Trash.Retention), time.Duration(cfg.Trash.PurgeInterval)),
+		reminder.NewWorker(accountRepo, notification.NewDispatcher(deviceRepo, notificationSender), time.Duration(cfg.Reminder.CheckInterval)),
+	)
+	return srv.Run(ctx)
 }
@@ -7,6 +7,14 @@ import (
 	"time"
 )
 
+// account settings that are not set fall back to the column defaults
+const (
+	DefaultWakeTime             = "09:00"
+	DefaultSleepTime            = "23:00"
+	DefaultNotificationInterval = 3
+	DefaultTimeZone             = "UTC"
+)
+
 type Account struct {
 	ID                   string     `json:"id"`
 	Email                *string    `json:"email,omitempty"`
@@ -18,6 +26,7 @@ type Account struct {
 	NotificationInterval *int       `json:"notification_interval,omitempty"`
 	Password             *string    `json:"password,omitempty"` // not stored; param for firebase user updates
 	PhotoURL             *string    `json:"photo_url,omitempty"`
+	TimeZone             *string    `json:"time_zone,omitempty"` // IANA time zone name (Europe/Dublin)
 }
 
 func (account Account) Validate() error {
@@ -27,5 +36,41 @@ func (account Account) Validate() error {
 		validation.Field(&account.SleepTime, validation.Match(regexp.MustCompile(`^(2[0-3]|[01]?[0-9]):([0-5]?[0-9])$`))),
 		validation.Field(&account.NotificationInterval, validation.Min(0), validation.Max(24)),
 		validation.Field(&account.PhotoURL, is.URL),
+		validation.Field(&account.TimeZone, validation.By(validateTimeZone)),
 	)
 }
+
+// ReminderInterval returns the hours between check-in reminders, falling back to the default when it is unset; 0 turns them off
+func (account Account) ReminderInterval() int {
+	if account.NotificationInterval == nil {
+		return DefaultNotificationInterval
+	}
+	return *account.NotificationInterval
+}
+
+// Location returns the time zone of the account, falling back to utc when it is unset or unknown
+func (account Account) Location() *time.Location {
+	if account.TimeZone == nil {
+		return time.UTC
+	}
+	location, err := time.LoadLocation(*account.TimeZone)
+	if err != nil {
+		return time.UTC
+	}
+	return location
+}
+
+func validateTimeZone(value interface{}) error {
+	timeZone, _ := value.(*string)
+	if timeZone == nil {
+		return nil
+	}
+	// LoadLocation accepts "" and "Local" too, which would make the zone depend on the server
+	if *timeZone == "" || *timeZone == "Local" {
+		return validation.NewError("validation_invalid_time_zone", "must be an IANA time zone name")
+	}
+	if _, err := time.LoadLocation(*timeZone); err != nil {
+		return validation.NewError("validation_invalid_time_zone", "must be an IANA time zone name")
+	}
+	return nil
+}
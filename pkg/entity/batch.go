@@ -0,0 +1,55 @@
+package entity
+
+import (
+	validation "github.com/go-ozzo/ozzo-validation/v4"
+	"github.com/google/uuid"
+)
+
+const (
+	BatchOpCreate = "create"
+	BatchOpUpdate = "update"
+	BatchOpDelete = "delete"
+)
+
+const (
+	MaxBatchOperations = 500
+)
+
+const (
+	BatchResultApplied    = "applied"
+	BatchResultFailed     = "failed"
+	BatchResultRolledBack = "rolled_back"
+	BatchResultSkipped    = "skipped"
+)
+
+// OCDLogBatchOperation is a single change of a batch; creates may carry a client generated id
+type OCDLogBatchOperation struct {
+	Op  string     `json:"op"`
+	ID  *uuid.UUID `json:"id,omitempty"`
+	Log *OCDLog    `json:"log,omitempty"`
+}
+
+type OCDLogBatch struct {
+	Operations []OCDLogBatchOperation `json:"operations"`
+}
+
+type OCDLogBatchResult struct {
+	Index  int        `json:"index"`
+	Op     string     `json:"op"`
+	ID     *uuid.UUID `json:"id,omitempty"`
+	Status string     `json:"status"`
+	Error  string     `json:"error,omitempty"`
+}
+
+type OCDLogBatchResponse struct {
+	Applied bool                `json:"applied"`
+	Results []OCDLogBatchResult `json:"results"`
+}
+
+func (operation OCDLogBatchOperation) Validate() error {
+	return validation.ValidateStruct(&operation,
+		validation.Field(&operation.Op, validation.Required, validation.In(BatchOpCreate, BatchOpUpdate, BatchOpDelete)),
+		validation.Field(&operation.ID, validation.When(operation.Op != BatchOpCreate, validation.Required)),
+		validation.Field(&operation.Log, validation.When(operation.Op != BatchOpDelete, validation.Required)),
+	)
+}
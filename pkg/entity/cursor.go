@@ -0,0 +1,39 @@
+package entity
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"github.com/google/uuid"
+	"time"
+)
+
+var (
+	ErrorInvalidCursor = errors.New("invalid cursor")
+)
+
+// Cursor is a position in a listing ordered by (created_at, id)
+type Cursor struct {
+	CreatedAt  time.Time `json:"t"`
+	ID         uuid.UUID `json:"id"`
+	Descending bool      `json:"desc,omitempty"` // order of the listing the cursor was issued for
+	Backward   bool      `json:"back,omitempty"` // page towards the start of the listing
+}
+
+// Encode returns the opaque token handed out to clients
+func (cursor Cursor) Encode() string {
+	data, _ := json.Marshal(cursor)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func DecodeCursor(token string) (*Cursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return nil, ErrorInvalidCursor
+	}
+	var cursor Cursor
+	if err = json.Unmarshal(data, &cursor); err != nil || cursor.CreatedAt.IsZero() || cursor.ID == uuid.Nil {
+		return nil, ErrorInvalidCursor
+	}
+	return &cursor, nil
+}
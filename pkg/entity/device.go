@@ -0,0 +1,41 @@
+package entity
+
+import (
+	validation "github.com/go-ozzo/ozzo-validation/v4"
+	"github.com/google/uuid"
+	"time"
+)
+
+const (
+	DevicePlatformAndroid = "android"
+	DevicePlatformIOS     = "ios"
+	DevicePlatformWeb     = "web"
+)
+
+// Device is an app installation that receives push notifications through its firebase cloud messaging token
+type Device struct {
+	ID        uuid.UUID  `json:"id"`
+	AccountID string     `json:"account_id"`
+	Token     *string    `json:"token,omitempty"`
+	Platform  *string    `json:"platform,omitempty"`
+	CreatedAt *time.Time `json:"created_at,omitempty"`
+	UpdatedAt *time.Time `json:"updated_at,omitempty"`
+}
+
+type DeviceList struct {
+	Devices []Device `json:"devices"`
+}
+
+// Notification is the content of a push notification
+type Notification struct {
+	Title string            `json:"title"`
+	Body  string            `json:"body"`
+	Data  map[string]string `json:"data,omitempty"`
+}
+
+func (device Device) Validate() error {
+	return validation.ValidateStruct(&device,
+		validation.Field(&device.Token, validation.Required, validation.Length(1, 4096)),
+		validation.Field(&device.Platform, validation.NilOrNotEmpty, validation.In(DevicePlatformAndroid, DevicePlatformIOS, DevicePlatformWeb)),
+	)
+}
@@ -0,0 +1,90 @@
+package entity
+
+import (
+	validation "github.com/go-ozzo/ozzo-validation/v4"
+	"github.com/google/uuid"
+	"time"
+)
+
+const (
+	MaxSUDS = 100 // subjective units of distress
+)
+
+// ExposureItem is a step of an exposure hierarchy, ranked by the distress the user predicts it will cause
+type ExposureItem struct {
+	ID            uuid.UUID  `json:"id"`
+	AccountID     string     `json:"account_id"`
+	CreatedAt     *time.Time `json:"created_at,omitempty"`
+	UpdatedAt     *time.Time `json:"updated_at,omitempty"`
+	Description   *string    `json:"description,omitempty"`
+	PredictedSUDS *int       `json:"predicted_suds,omitempty"`
+}
+
+// ExposureItemList is the hierarchy, from the least to the most distressing item
+type ExposureItemList struct {
+	Items []ExposureItem `json:"items"`
+}
+
+// ExposureSession is a single exposure to a hierarchy item with the distress felt over its course
+type ExposureSession struct {
+	ID              uuid.UUID  `json:"id"`
+	AccountID       string     `json:"account_id"`
+	ItemID          *uuid.UUID `json:"item_id,omitempty"`
+	CreatedAt       *time.Time `json:"created_at,omitempty"`
+	UpdatedAt       *time.Time `json:"updated_at,omitempty"`
+	StartedAt       *time.Time `json:"started_at,omitempty"`
+	DurationMinutes *int       `json:"duration_minutes,omitempty"`
+	SUDSStart       *int       `json:"suds_start,omitempty"`
+	SUDSPeak        *int       `json:"suds_peak,omitempty"`
+	SUDSEnd         *int       `json:"suds_end,omitempty"`
+	Notes           *string    `json:"notes,omitempty"`
+}
+
+type ExposureSessionList struct {
+	Sessions   []ExposureSession `json:"sessions"`
+	Pagination PaginationDetails `json:"pagination"`
+}
+
+// ExposureSessionFilter narrows down the sessions returned by a listing; nil fields are not applied
+type ExposureSessionFilter struct {
+	ItemID *uuid.UUID `json:"item_id,omitempty"`
+}
+
+func (item ExposureItem) Validate() error {
+	return validation.ValidateStruct(&item,
+		validation.Field(&item.Description, validation.NilOrNotEmpty, validation.Length(1, 500)),
+		validation.Field(&item.PredictedSUDS, validation.Min(0), validation.Max(MaxSUDS)),
+	)
+}
+
+// ValidateCreate additionally requires the fields every new item must have
+func (item ExposureItem) ValidateCreate() error {
+	return validation.ValidateStruct(&item,
+		validation.Field(&item.Description, validation.Required),
+		validation.Field(&item.PredictedSUDS, validation.NotNil),
+	)
+}
+
+func (session ExposureSession) Validate() error {
+	return validation.ValidateStruct(&session,
+		validation.Field(&session.StartedAt, validation.By(notInFuture)),
+		validation.Field(&session.DurationMinutes, validation.Min(0)),
+		validation.Field(&session.SUDSStart, validation.Min(0), validation.Max(MaxSUDS)),
+		validation.Field(&session.SUDSPeak, validation.Min(0), validation.Max(MaxSUDS),
+			validation.When(session.SUDSStart != nil, validation.Min(derefInt(session.SUDSStart))),
+			validation.When(session.SUDSEnd != nil, validation.Min(derefInt(session.SUDSEnd))),
+		),
+		validation.Field(&session.SUDSEnd, validation.Min(0), validation.Max(MaxSUDS)),
+	)
+}
+
+// ValidateCreate additionally requires the fields every new session must have
+func (session ExposureSession) ValidateCreate() error {
+	return validation.ValidateStruct(&session,
+		validation.Field(&session.ItemID, validation.Required),
+		validation.Field(&session.DurationMinutes, validation.NotNil),
+		validation.Field(&session.SUDSStart, validation.NotNil),
+		validation.Field(&session.SUDSPeak, validation.NotNil),
+		validation.Field(&session.SUDSEnd, validation.NotNil),
+	)
+}
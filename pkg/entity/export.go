@@ -0,0 +1,72 @@
+package entity
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"time"
+)
+
+const (
+	ExportSchemaVersion = 1
+)
+
+// AccountExport is a versioned archive of all data held about an account
+type AccountExport struct {
+	Manifest ExportManifest  `json:"manifest"`
+	Data     json.RawMessage `json:"data"` // encoded AccountExportData; kept raw so the checksum can be verified
+}
+
+type ExportManifest struct {
+	SchemaVersion int            `json:"schema_version"`
+	GeneratedAt   time.Time      `json:"generated_at"`
+	AccountID     string         `json:"account_id"`
+	Checksum      string         `json:"checksum"` // sha256 of the compacted data
+	Counts        map[string]int `json:"counts"`
+}
+
+type AccountExportData struct {
+	Account                Account                 `json:"account"`
+	OCDLogs                []OCDLog                `json:"ocdlogs"`
+	TrashedOCDLogs         []OCDLog                `json:"trashed_ocdlogs"`
+	OCDLogRevisions        []OCDLogRevision        `json:"ocdlog_revisions"`
+	Tags                   []Tag                   `json:"tags"`
+	ExposureItems          []ExposureItem          `json:"exposure_items"`
+	ExposureSessions       []ExposureSession       `json:"exposure_sessions"`
+	QuestionnaireResponses []QuestionnaireResponse `json:"questionnaire_responses"`
+	Devices                []Device                `json:"devices"` // without their tokens; devices register again on login
+	Grants                 []Grant                 `json:"grants"`  // without their invitation codes
+}
+
+// Counts returns the number of records of each type, as listed in the manifest
+func (data AccountExportData) Counts() map[string]int {
+	return map[string]int{
+		"ocdlogs":                 len(data.OCDLogs),
+		"trashed_ocdlogs":         len(data.TrashedOCDLogs),
+		"ocdlog_revisions":        len(data.OCDLogRevisions),
+		"tags":                    len(data.Tags),
+		"exposure_items":          len(data.ExposureItems),
+		"exposure_sessions":       len(data.ExposureSessions),
+		"questionnaire_responses": len(data.QuestionnaireResponses),
+		"devices":                 len(data.Devices),
+		"grants":                  len(data.Grants),
+	}
+}
+
+// AccountRestoreReport describes what a restore applied, or would apply in a dry run
+type AccountRestoreReport struct {
+	DryRun   bool           `json:"dry_run"`
+	Settings Account        `json:"settings"`
+	Counts   map[string]int `json:"counts"`
+}
+
+// ExportChecksum returns the hex encoded sha256 of data after removing insignificant whitespace
+func ExportChecksum(data []byte) (string, error) {
+	var compacted bytes.Buffer
+	if err := json.Compact(&compacted, data); err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(compacted.Bytes())
+	return hex.EncodeToString(sum[:]), nil
+}
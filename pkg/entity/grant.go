@@ -0,0 +1,111 @@
+package entity
+
+import (
+	"database/sql/driver"
+	validation "github.com/go-ozzo/ozzo-validation/v4"
+	"github.com/go-ozzo/ozzo-validation/v4/is"
+	"github.com/google/uuid"
+	"sort"
+	"time"
+)
+
+const (
+	GrantScopeLogs           = "logs"           // the ocd logs, including the csv export
+	GrantScopeStats          = "stats"          // the stats and series of the ocd logs
+	GrantScopeQuestionnaires = "questionnaires" // the questionnaire responses
+)
+
+// Grant gives a clinician read-only access to parts of the data of an account. It starts as an invitation, which is
+// either addressed to an email or redeemed with its code, and becomes active once a clinician accepts it
+type Grant struct {
+	ID          uuid.UUID   `json:"id"`
+	AccountID   string      `json:"account_id"`
+	ClinicianID *string     `json:"clinician_id,omitempty"` // null while the invitation is pending
+	Email       *string     `json:"email,omitempty"`        // only the account with this email may accept the invitation
+	Code        *string     `json:"code,omitempty"`         // cleared once the invitation is accepted
+	Scopes      GrantScopes `json:"scopes"`
+	CreatedAt   *time.Time  `json:"created_at,omitempty"`
+	AcceptedAt  *time.Time  `json:"accepted_at,omitempty"`
+	ExpiresAt   *time.Time  `json:"expires_at,omitempty"` // the grant never expires when null
+}
+
+type GrantList struct {
+	Grants []Grant `json:"grants"`
+}
+
+// Patient is an account that shares its data with a clinician, or invited them to, together with the grant
+type Patient struct {
+	AccountID   string      `json:"account_id"`
+	Email       *string     `json:"email,omitempty"`
+	DisplayName *string     `json:"display_name,omitempty"`
+	GrantID     uuid.UUID   `json:"grant_id"`
+	Scopes      GrantScopes `json:"scopes"`
+	AcceptedAt  *time.Time  `json:"accepted_at,omitempty"`
+	ExpiresAt   *time.Time  `json:"expires_at,omitempty"`
+}
+
+type PatientList struct {
+	Patients []Patient `json:"patients"`
+}
+
+// GrantScopes is stored as a json array
+type GrantScopes []string
+
+func (grant Grant) Validate() error {
+	return validation.ValidateStruct(&grant,
+		validation.Field(&grant.Email, is.Email),
+		// validation.Required would check the json of the scopes, since they implement driver.Valuer, and "[]" is not empty
+		validation.Field(&grant.Scopes, validation.By(func(_ interface{}) error {
+			if len(grant.Scopes) == 0 {
+				return validation.ErrRequired
+			}
+			return nil
+		}), validation.Each(validation.In(GrantScopeLogs, GrantScopeStats, GrantScopeQuestionnaires))),
+		validation.Field(&grant.ExpiresAt, validation.By(func(_ interface{}) error {
+			if grant.ExpiresAt != nil && !grant.ExpiresAt.After(time.Now()) {
+				return validation.NewError("validation_not_in_future", "must be in the future")
+			}
+			return nil
+		})),
+	)
+}
+
+// Pending reports whether no clinician has accepted the invitation yet
+func (grant Grant) Pending() bool {
+	return grant.ClinicianID == nil
+}
+
+func (grant Grant) Expired(now time.Time) bool {
+	return grant.ExpiresAt != nil && !grant.ExpiresAt.After(now)
+}
+
+// Normalized returns the scopes de-duplicated and sorted
+func (scopes GrantScopes) Normalized() GrantScopes {
+	seen := make(map[string]bool, len(scopes))
+	normalized := make(GrantScopes, 0, len(scopes))
+	for _, scope := range scopes {
+		if !seen[scope] {
+			seen[scope] = true
+			normalized = append(normalized, scope)
+		}
+	}
+	sort.Strings(normalized)
+	return normalized
+}
+
+func (scopes GrantScopes) Contains(scope string) bool {
+	for _, existing := range scopes {
+		if existing == scope {
+			return true
+		}
+	}
+	return false
+}
+
+func (scopes *GrantScopes) Scan(src interface{}) error {
+	return scanJSON(src, scopes, "grant scopes")
+}
+
+func (scopes GrantScopes) Value() (driver.Value, error) {
+	return valueJSON(scopes)
+}
@@ -0,0 +1,24 @@
+package entity
+
+import (
+	"github.com/google/uuid"
+)
+
+const (
+	ImportRowStatusAccepted = "accepted"
+	ImportRowStatusRejected = "rejected"
+)
+
+// OCDLogImportReport lists the outcome of every row of an import
+type OCDLogImportReport struct {
+	Accepted int               `json:"accepted"`
+	Rejected int               `json:"rejected"`
+	Rows     []OCDLogImportRow `json:"rows"`
+}
+
+type OCDLogImportRow struct {
+	Row    int        `json:"row"` // 1-based, not counting the csv header
+	Status string     `json:"status"`
+	ID     *uuid.UUID `json:"id,omitempty"`
+	Error  string     `json:"error,omitempty"`
+}
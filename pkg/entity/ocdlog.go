@@ -14,6 +14,13 @@ type OCDLog struct {
 	RuminateMinutes *int       `json:"ruminate_minutes,omitempty"`
 	AnxietyLevel    *int       `json:"anxiety_level,omitempty"`
 	Notes           *string    `json:"notes,omitempty"`
+	CompulsionType  *string    `json:"compulsion_type,omitempty"`
+	UrgeIntensity   *int       `json:"urge_intensity,omitempty"`
+	Resisted        *bool      `json:"resisted,omitempty"`
+	ResistedMinutes *int       `json:"resisted_minutes,omitempty"` // how long the urge was resisted, also when giving in eventually
+	Tags            TagNames   `json:"tags,omitempty"`
+	DeletedAt       *time.Time `json:"deleted_at,omitempty"` // set while the log is in the trash
+	Cleared         []string   `json:"-"`                    // optional fields an update sets back to null
 }
 
 type OCDLogList struct {
@@ -21,16 +28,174 @@ type OCDLogList struct {
 	Pagination PaginationDetails `json:"pagination"`
 }
 
+// OCDLogFilter narrows down the logs returned by a listing; nil fields are not applied
+type OCDLogFilter struct {
+	From           *time.Time `json:"from,omitempty"`
+	To             *time.Time `json:"to,omitempty"`
+	AnxietyMin     *int       `json:"anxiety_min,omitempty"`
+	AnxietyMax     *int       `json:"anxiety_max,omitempty"`
+	RuminateMin    *int       `json:"ruminate_min,omitempty"`
+	RuminateMax    *int       `json:"ruminate_max,omitempty"`
+	HasNotes       *bool      `json:"has_notes,omitempty"`
+	CompulsionType *string    `json:"compulsion_type,omitempty"`
+	Resisted       *bool      `json:"resisted,omitempty"`
+	Tags           TagNames   `json:"tags,omitempty"` // logs must carry all of them
+}
+
+// OCDLogSortField is a single sort key of a listing
+type OCDLogSortField struct {
+	Field      string `json:"field"`
+	Descending bool   `json:"descending"`
+}
+
+// OCDLogSort orders a listing by multiple keys; ties are always broken by id
+type OCDLogSort []OCDLogSortField
+
+// compulsion types follow the common OCD symptom dimensions; anything else is recorded as other
+const (
+	CompulsionTypeChecking           = "checking"
+	CompulsionTypeWashing            = "washing"
+	CompulsionTypeCounting           = "counting"
+	CompulsionTypeOrdering           = "ordering"
+	CompulsionTypeRepeating          = "repeating"
+	CompulsionTypeReassuranceSeeking = "reassurance_seeking"
+	CompulsionTypeMentalRituals      = "mental_rituals"
+	CompulsionTypeAvoidance          = "avoidance"
+	CompulsionTypeHoarding           = "hoarding"
+	CompulsionTypeOther              = "other"
+)
+
+const (
+	MaxUrgeIntensity = 10
+)
+
+// optional fields of a log that an update can clear
+const (
+	OCDLogFieldCompulsionType  = "compulsion_type"
+	OCDLogFieldUrgeIntensity   = "urge_intensity"
+	OCDLogFieldResisted        = "resisted"
+	OCDLogFieldResistedMinutes = "resisted_minutes"
+)
+
+var (
+	CompulsionTypes = []interface{}{
+		CompulsionTypeChecking, CompulsionTypeWashing, CompulsionTypeCounting, CompulsionTypeOrdering, CompulsionTypeRepeating,
+		CompulsionTypeReassuranceSeeking, CompulsionTypeMentalRituals, CompulsionTypeAvoidance, CompulsionTypeHoarding, CompulsionTypeOther,
+	}
+)
+
+const (
+	SortFieldCreatedAt       = "created_at"
+	SortFieldUpdatedAt       = "updated_at"
+	SortFieldAnxietyLevel    = "anxiety_level"
+	SortFieldRuminateMinutes = "ruminate_minutes"
+)
+
+var (
+	DefaultOCDLogSort = OCDLogSort{{Field: SortFieldCreatedAt}}
+)
+
+// CursorCompatible reports whether the sort is the (created_at, id) order cursors are based on
+func (sort OCDLogSort) CursorCompatible() bool {
+	return len(sort) == 0 || (len(sort) == 1 && sort[0].Field == SortFieldCreatedAt)
+}
+
+// Descending reports whether the primary sort key is descending
+func (sort OCDLogSort) Descending() bool {
+	return len(sort) > 0 && sort[0].Descending
+}
+
+// PaginationDetails describes either an offset page (offset and total) or a cursor page (next and prev cursors)
 type PaginationDetails struct {
-	Limit  int `json:"limit"`
-	Offset int `json:"offset"`
-	Count  int `json:"count"`
-	Total  int `json:"total"`
+	Limit      int     `json:"limit"`
+	Offset     *int    `json:"offset,omitempty"`
+	Count      int     `json:"count"`
+	Total      *int    `json:"total,omitempty"`
+	NextCursor string  `json:"next_cursor,omitempty"`
+	PrevCursor string  `json:"prev_cursor,omitempty"`
+	CursorMode bool    `json:"-"`
+	Cursor     *Cursor `json:"-"` // cursor of the request; nil for the first page
 }
 
 func (ocdLog OCDLog) Validate() error {
 	return validation.ValidateStruct(&ocdLog,
+		validation.Field(&ocdLog.CreatedAt, validation.By(notInFuture)),
+		validation.Field(&ocdLog.UpdatedAt, validation.By(notInFuture), validation.When(ocdLog.CreatedAt != nil, validation.By(notBefore(ocdLog.CreatedAt)))),
 		validation.Field(&ocdLog.RuminateMinutes, validation.Min(0)),
-		validation.Field(&ocdLog.AnxietyLevel, validation.Min(0), validation.Max(10)),
+		validation.Field(&ocdLog.AnxietyLevel, validation.Min(0), validation.Max(MaxAnxietyLevel)),
+		validation.Field(&ocdLog.CompulsionType, validation.In(CompulsionTypes...)),
+		validation.Field(&ocdLog.UrgeIntensity, validation.Min(0), validation.Max(MaxUrgeIntensity)),
+		validation.Field(&ocdLog.ResistedMinutes, validation.Min(0)),
+		validation.Field(&ocdLog.Tags),
+	)
+}
+
+func (filter OCDLogFilter) Validate() error {
+	return validation.ValidateStruct(&filter,
+		validation.Field(&filter.To, validation.When(filter.From != nil && filter.To != nil, validation.By(notBefore(filter.From)))),
+		validation.Field(&filter.AnxietyMin, validation.Min(0), validation.Max(MaxAnxietyLevel)),
+		validation.Field(&filter.AnxietyMax, validation.Min(0), validation.Max(MaxAnxietyLevel), validation.When(filter.AnxietyMin != nil, validation.Min(derefInt(filter.AnxietyMin)))),
+		validation.Field(&filter.RuminateMin, validation.Min(0)),
+		validation.Field(&filter.RuminateMax, validation.Min(0), validation.When(filter.RuminateMin != nil, validation.Min(derefInt(filter.RuminateMin)))),
+		validation.Field(&filter.CompulsionType, validation.In(CompulsionTypes...)),
+		validation.Field(&filter.Tags),
+	)
+}
+
+func (sortField OCDLogSortField) Validate() error {
+	return validation.ValidateStruct(&sortField,
+		validation.Field(&sortField.Field, validation.Required, validation.In(SortFieldCreatedAt, SortFieldUpdatedAt, SortFieldAnxietyLevel, SortFieldRuminateMinutes)),
 	)
 }
+
+func (sort OCDLogSort) Validate() error {
+	seen := make(map[string]bool, len(sort))
+	for _, sortField := range sort {
+		if seen[sortField.Field] {
+			return validation.NewError("validation_duplicate_sort_field", "must not repeat field "+sortField.Field)
+		}
+		seen[sortField.Field] = true
+	}
+	return validation.Validate([]OCDLogSortField(sort))
+}
+
+func notBefore(from *time.Time) validation.RuleFunc {
+	return func(value interface{}) error {
+		to, _ := value.(*time.Time)
+		if to != nil && to.Before(*from) {
+			return validation.NewError("validation_not_before", "must not be before "+from.Format(time.RFC3339))
+		}
+		return nil
+	}
+}
+
+func notInFuture(value interface{}) error {
+	t, _ := value.(*time.Time)
+	if t != nil && t.After(time.Now()) {
+		return validation.NewError("validation_not_in_future", "must not be in the future")
+	}
+	return nil
+}
+
+// Clears reports whether an update sets the field back to null
+func (ocdLog OCDLog) Clears(field string) bool {
+	for _, cleared := range ocdLog.Cleared {
+		if cleared == field {
+			return true
+		}
+	}
+	return false
+}
+
+func (ocdLog *OCDLog) clearIfNil(isNil bool, field string) {
+	if isNil {
+		ocdLog.Cleared = append(ocdLog.Cleared, field)
+	}
+}
+
+func derefInt(value *int) int {
+	if value == nil {
+		return 0
+	}
+	return *value
+}
@@ -0,0 +1,204 @@
+package entity
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	validation "github.com/go-ozzo/ozzo-validation/v4"
+	"github.com/google/uuid"
+	"time"
+)
+
+const (
+	QuestionnaireScoringSum = "sum" // the score is the sum of the answer values
+)
+
+// Questionnaire is the definition of a standardised instrument: its items, the scales they are answered on, how it is
+// scored and how a total score is interpreted
+type Questionnaire struct {
+	ID            string                  `json:"id"`
+	Version       int                     `json:"version"`
+	Name          string                  `json:"name"`
+	Instructions  string                  `json:"instructions"`
+	Scales        []AnswerScale           `json:"scales"`
+	Items         []QuestionnaireItem     `json:"items"`
+	Scoring       string                  `json:"scoring"`
+	Subscales     []QuestionnaireSubscale `json:"subscales"`
+	SeverityBands []SeverityBand          `json:"severity_bands"`
+}
+
+type AnswerScale struct {
+	ID      string         `json:"id"`
+	Options []AnswerOption `json:"options"`
+}
+
+type AnswerOption struct {
+	Value int    `json:"value"`
+	Label string `json:"label"`
+}
+
+type QuestionnaireItem struct {
+	ID    int    `json:"id"`
+	Text  string `json:"text"`
+	Scale string `json:"scale"`
+}
+
+type QuestionnaireSubscale struct {
+	ID    string `json:"id"`
+	Name  string `json:"name"`
+	Items []int  `json:"items"`
+}
+
+// SeverityBand labels the total scores from Min to Max, both inclusive
+type SeverityBand struct {
+	Min   int    `json:"min"`
+	Max   int    `json:"max"`
+	Label string `json:"label"`
+}
+
+type QuestionnaireList struct {
+	Questionnaires []Questionnaire `json:"questionnaires"`
+}
+
+type QuestionnaireAnswer struct {
+	Item  int `json:"item"`
+	Value int `json:"value"`
+}
+
+// QuestionnaireAnswers is stored as a json array
+type QuestionnaireAnswers []QuestionnaireAnswer
+
+// SubscaleScores maps subscale ids to their scores and is stored as a json object
+type SubscaleScores map[string]int
+
+// QuestionnaireResponse is a completed questionnaire with the scores computed when it was submitted
+type QuestionnaireResponse struct {
+	ID                   uuid.UUID            `json:"id"`
+	AccountID            string               `json:"account_id"`
+	QuestionnaireID      string               `json:"questionnaire_id"`
+	QuestionnaireVersion int                  `json:"questionnaire_version"`
+	CreatedAt            *time.Time           `json:"created_at,omitempty"`
+	Answers              QuestionnaireAnswers `json:"answers"`
+	TotalScore           int                  `json:"total_score"`
+	SubscaleScores       SubscaleScores       `json:"subscale_scores"`
+	Severity             *string              `json:"severity,omitempty"`
+}
+
+type QuestionnaireResponseList struct {
+	Responses  []QuestionnaireResponse `json:"responses"`
+	Pagination PaginationDetails       `json:"pagination"`
+}
+
+// Scale returns the answer scale with the given id
+func (questionnaire Questionnaire) Scale(id string) (AnswerScale, bool) {
+	for _, scale := range questionnaire.Scales {
+		if scale.ID == id {
+			return scale, true
+		}
+	}
+	return AnswerScale{}, false
+}
+
+// Item returns the item with the given id
+func (questionnaire Questionnaire) Item(id int) (QuestionnaireItem, bool) {
+	for _, item := range questionnaire.Items {
+		if item.ID == id {
+			return item, true
+		}
+	}
+	return QuestionnaireItem{}, false
+}
+
+// SeverityOf returns the label of the band the total score falls into
+func (questionnaire Questionnaire) SeverityOf(totalScore int) *string {
+	for _, band := range questionnaire.SeverityBands {
+		if totalScore >= band.Min && totalScore <= band.Max {
+			label := band.Label
+			return &label
+		}
+	}
+	return nil
+}
+
+// Validate checks that a definition is consistent: every item uses a known scale and every subscale a known item
+func (questionnaire Questionnaire) Validate() error {
+	return validation.ValidateStruct(&questionnaire,
+		validation.Field(&questionnaire.ID, validation.Required),
+		validation.Field(&questionnaire.Version, validation.Required, validation.Min(1)),
+		validation.Field(&questionnaire.Name, validation.Required),
+		validation.Field(&questionnaire.Scales, validation.Required, validation.Each(validation.By(validateScale))),
+		validation.Field(&questionnaire.Items, validation.Required, validation.Each(validation.By(func(value interface{}) error {
+			item := value.(QuestionnaireItem)
+			if _, ok := questionnaire.Scale(item.Scale); !ok {
+				return fmt.Errorf("item %d uses unknown scale %q", item.ID, item.Scale)
+			}
+			return nil
+		}))),
+		validation.Field(&questionnaire.Scoring, validation.Required, validation.In(QuestionnaireScoringSum)),
+		validation.Field(&questionnaire.Subscales, validation.Each(validation.By(func(value interface{}) error {
+			subscale := value.(QuestionnaireSubscale)
+			if subscale.ID == "" || len(subscale.Items) == 0 {
+				return fmt.Errorf("subscale needs an id and items")
+			}
+			for _, id := range subscale.Items {
+				if _, ok := questionnaire.Item(id); !ok {
+					return fmt.Errorf("subscale %s uses unknown item %d", subscale.ID, id)
+				}
+			}
+			return nil
+		}))),
+		validation.Field(&questionnaire.SeverityBands, validation.Each(validation.By(func(value interface{}) error {
+			band := value.(SeverityBand)
+			if band.Label == "" || band.Min > band.Max {
+				return fmt.Errorf("severity band %d-%d needs a label and min <= max", band.Min, band.Max)
+			}
+			return nil
+		}))),
+	)
+}
+
+func validateScale(value interface{}) error {
+	scale := value.(AnswerScale)
+	if scale.ID == "" || len(scale.Options) == 0 {
+		return fmt.Errorf("scale needs an id and options")
+	}
+	return nil
+}
+
+func (answers *QuestionnaireAnswers) Scan(src interface{}) error {
+	return scanJSON(src, answers, "questionnaire answers")
+}
+
+func (answers QuestionnaireAnswers) Value() (driver.Value, error) {
+	return valueJSON(answers)
+}
+
+func (scores *SubscaleScores) Scan(src interface{}) error {
+	return scanJSON(src, scores, "subscale scores")
+}
+
+func (scores SubscaleScores) Value() (driver.Value, error) {
+	return valueJSON(scores)
+}
+
+// valueJSON encodes a value as a json string, since byte slices would be sent to postgres as bytea
+func valueJSON(value interface{}) (driver.Value, error) {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+	return string(data), nil
+}
+
+func scanJSON(src interface{}, dst interface{}, name string) error {
+	switch value := src.(type) {
+	case nil:
+		return nil
+	case []byte:
+		return json.Unmarshal(value, dst)
+	case string:
+		return json.Unmarshal([]byte(value), dst)
+	default:
+		return fmt.Errorf("cannot scan %T into %s", src, name)
+	}
+}
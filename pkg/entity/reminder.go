@@ -0,0 +1,21 @@
+package entity
+
+import (
+	"time"
+)
+
+// ReminderSchedule lists the check-in reminders of an account for the awake period that starts on Date; when the
+// sleep time is before the wake time the period crosses midnight and the last reminders fall on the next day
+type ReminderSchedule struct {
+	Date                 string      `json:"date"` // 2006-01-02
+	WakeTime             string      `json:"wake_time"`
+	SleepTime            string      `json:"sleep_time"`
+	NotificationInterval int         `json:"notification_interval"` // hours between reminders; 0 turns them off
+	Reminders            []time.Time `json:"reminders"`
+}
+
+// Reminder is a single check-in reminder that is due for an account
+type Reminder struct {
+	AccountID string    `json:"account_id"`
+	At        time.Time `json:"at"`
+}
@@ -0,0 +1,136 @@
+package entity
+
+import (
+	"github.com/google/uuid"
+	"time"
+)
+
+// OCDLogRevision records a single change of a log with the editable fields before and after it
+type OCDLogRevision struct {
+	Revision  int                  `json:"revision"`
+	OCDLogID  uuid.UUID            `json:"ocdlog_id"`
+	ChangedBy string               `json:"changed_by"`
+	ChangedAt time.Time            `json:"changed_at"`
+	Old       OCDLogRevisionValues `json:"old"`
+	New       OCDLogRevisionValues `json:"new"`
+}
+
+type OCDLogRevisionValues struct {
+	RuminateMinutes int      `json:"ruminate_minutes"`
+	AnxietyLevel    int      `json:"anxiety_level"`
+	Notes           *string  `json:"notes,omitempty"`
+	CompulsionType  *string  `json:"compulsion_type,omitempty"`
+	UrgeIntensity   *int     `json:"urge_intensity,omitempty"`
+	Resisted        *bool    `json:"resisted,omitempty"`
+	ResistedMinutes *int     `json:"resisted_minutes,omitempty"`
+	Tags            TagNames `json:"tags,omitempty"` // null for revisions recorded before tags were tracked
+}
+
+type OCDLogRevisionList struct {
+	Revisions []OCDLogRevision `json:"revisions"`
+}
+
+// NewOCDLogRevisionValues snapshots the editable fields of a log
+func NewOCDLogRevisionValues(ocdLog OCDLog) OCDLogRevisionValues {
+	return OCDLogRevisionValues{
+		RuminateMinutes: derefInt(ocdLog.RuminateMinutes),
+		AnxietyLevel:    derefInt(ocdLog.AnxietyLevel),
+		Notes:           copyPointer(ocdLog.Notes),
+		CompulsionType:  copyPointer(ocdLog.CompulsionType),
+		UrgeIntensity:   copyPointer(ocdLog.UrgeIntensity),
+		Resisted:        copyPointer(ocdLog.Resisted),
+		ResistedMinutes: copyPointer(ocdLog.ResistedMinutes),
+		Tags:            append(TagNames{}, ocdLog.Tags...),
+	}
+}
+
+// Equal reports whether both snapshots hold the same values; missing and empty notes or tags are equal
+func (values OCDLogRevisionValues) Equal(other OCDLogRevisionValues) bool {
+	return values.RuminateMinutes == other.RuminateMinutes &&
+		values.AnxietyLevel == other.AnxietyLevel &&
+		derefString(values.Notes) == derefString(other.Notes) &&
+		equalPointers(values.CompulsionType, other.CompulsionType) &&
+		equalPointers(values.UrgeIntensity, other.UrgeIntensity) &&
+		equalPointers(values.Resisted, other.Resisted) &&
+		equalPointers(values.ResistedMinutes, other.ResistedMinutes) &&
+		equalTags(values.Tags, other.Tags)
+}
+
+// Reverted returns the update that undoes the revision, setting the fields it changed back to their old values and
+// clearing the compulsion fields it set for the first time; it returns nil if the revision did not change anything
+func (revision OCDLogRevision) Reverted() *OCDLog {
+	var (
+		update  OCDLog
+		changed bool
+	)
+	if revision.Old.RuminateMinutes != revision.New.RuminateMinutes {
+		ruminateMinutes := revision.Old.RuminateMinutes
+		update.RuminateMinutes, changed = &ruminateMinutes, true
+	}
+	if revision.Old.AnxietyLevel != revision.New.AnxietyLevel {
+		anxietyLevel := revision.Old.AnxietyLevel
+		update.AnxietyLevel, changed = &anxietyLevel, true
+	}
+	if derefString(revision.Old.Notes) != derefString(revision.New.Notes) {
+		notes := derefString(revision.Old.Notes)
+		update.Notes, changed = &notes, true
+	}
+	if !equalPointers(revision.Old.CompulsionType, revision.New.CompulsionType) {
+		update.CompulsionType, changed = copyPointer(revision.Old.CompulsionType), true
+		update.clearIfNil(revision.Old.CompulsionType == nil, OCDLogFieldCompulsionType)
+	}
+	if !equalPointers(revision.Old.UrgeIntensity, revision.New.UrgeIntensity) {
+		update.UrgeIntensity, changed = copyPointer(revision.Old.UrgeIntensity), true
+		update.clearIfNil(revision.Old.UrgeIntensity == nil, OCDLogFieldUrgeIntensity)
+	}
+	if !equalPointers(revision.Old.Resisted, revision.New.Resisted) {
+		update.Resisted, changed = copyPointer(revision.Old.Resisted), true
+		update.clearIfNil(revision.Old.Resisted == nil, OCDLogFieldResisted)
+	}
+	if !equalPointers(revision.Old.ResistedMinutes, revision.New.ResistedMinutes) {
+		update.ResistedMinutes, changed = copyPointer(revision.Old.ResistedMinutes), true
+		update.clearIfNil(revision.Old.ResistedMinutes == nil, OCDLogFieldResistedMinutes)
+	}
+	if !equalTags(revision.Old.Tags, revision.New.Tags) {
+		update.Tags, changed = append(TagNames{}, revision.Old.Tags...), true
+	}
+	if !changed {
+		return nil
+	}
+	return &update
+}
+
+func derefString(value *string) string {
+	if value == nil {
+		return ""
+	}
+	return *value
+}
+
+func equalTags(a, b TagNames) bool {
+	a, b = a.Normalized(), b.Normalized()
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func copyPointer[T any](value *T) *T {
+	if value == nil {
+		return nil
+	}
+	v := *value
+	return &v
+}
+
+func equalPointers[T comparable](a, b *T) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
@@ -0,0 +1,179 @@
+package entity
+
+import (
+	"fmt"
+	validation "github.com/go-ozzo/ozzo-validation/v4"
+	"time"
+)
+
+type SeriesBucket string
+
+const (
+	SeriesBucketHour  SeriesBucket = "hour"
+	SeriesBucketDay   SeriesBucket = "day"
+	SeriesBucketWeek  SeriesBucket = "week"
+	SeriesBucketMonth SeriesBucket = "month"
+)
+
+const (
+	MaxSeriesPoints = 5000
+)
+
+// OCDLogSeriesQuery describes the buckets of a series; From and To are truncated to bucket boundaries
+type OCDLogSeriesQuery struct {
+	Bucket      SeriesBucket
+	From        time.Time
+	To          time.Time
+	AwakeWindow *DailyWindow   // when set, only logs created between wake and sleep time are counted
+	Location    *time.Location // the buckets and the awake window follow this zone; utc when nil
+}
+
+type OCDLogSeries struct {
+	Bucket SeriesBucket        `json:"bucket"`
+	From   time.Time           `json:"from"`
+	To     time.Time           `json:"to"`
+	Points []OCDLogSeriesPoint `json:"points"`
+}
+
+type OCDLogSeriesPoint struct {
+	BucketStart          time.Time `json:"bucket_start"`
+	Count                int       `json:"count"`
+	AvgAnxietyLevel      *float64  `json:"avg_anxiety_level"` // null for empty buckets
+	TotalRuminateMinutes int       `json:"total_ruminate_minutes"`
+	ResistanceRate       *float64  `json:"resistance_rate"` // null for buckets without any outcome recorded
+}
+
+// DailyWindow is a time of day range in 24-hour clock (15:04) which may cross midnight
+type DailyWindow struct {
+	Start string
+	End   string
+}
+
+func (query OCDLogSeriesQuery) Validate() error {
+	return validation.ValidateStruct(&query,
+		validation.Field(&query.Bucket, validation.Required, validation.In(SeriesBucketHour, SeriesBucketDay, SeriesBucketWeek, SeriesBucketMonth)),
+		validation.Field(&query.To, validation.By(func(_ interface{}) error {
+			if query.To.Before(query.From) {
+				return validation.NewError("validation_not_before", "must not be before from")
+			}
+			if query.Bucket.PointCount(query.From.In(query.Zone()), query.To.In(query.Zone())) > MaxSeriesPoints {
+				return validation.NewError("validation_too_many_points", fmt.Sprintf("range must not span more than %d buckets", MaxSeriesPoints))
+			}
+			return nil
+		})),
+	)
+}
+
+// Zone returns the location of the query, defaulting to utc
+func (query OCDLogSeriesQuery) Zone() *time.Location {
+	if query.Location == nil {
+		return time.UTC
+	}
+	return query.Location
+}
+
+// Truncate returns the start of the bucket containing t in the location of t, like postgres date_trunc (weeks start
+// on monday). Hours are truncated by elapsed time rather than by wall clock, so that the hour repeated when daylight
+// saving time ends is a bucket of its own
+func (bucket SeriesBucket) Truncate(t time.Time) time.Time {
+	location := t.Location()
+	switch bucket {
+	case SeriesBucketHour:
+		return t.Add(-time.Duration(t.Minute())*time.Minute - time.Duration(t.Second())*time.Second - time.Duration(t.Nanosecond()))
+	case SeriesBucketWeek:
+		day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, location)
+		return day.AddDate(0, 0, -((int(day.Weekday()) + 6) % 7))
+	case SeriesBucketMonth:
+		return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, location)
+	default:
+		return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, location)
+	}
+}
+
+// Next returns the start of the bucket following the one starting at t
+func (bucket SeriesBucket) Next(t time.Time) time.Time {
+	switch bucket {
+	case SeriesBucketHour:
+		return t.Add(time.Hour)
+	case SeriesBucketWeek:
+		return t.AddDate(0, 0, 7)
+	case SeriesBucketMonth:
+		return t.AddDate(0, 1, 0)
+	default:
+		return t.AddDate(0, 0, 1)
+	}
+}
+
+// PointCount returns the number of buckets between from and to, both inclusive
+func (bucket SeriesBucket) PointCount(from, to time.Time) int {
+	from, to = bucket.Truncate(from), bucket.Truncate(to)
+	if to.Before(from) {
+		return 0
+	}
+	switch bucket {
+	case SeriesBucketHour:
+		return int(to.Sub(from)/time.Hour) + 1
+	case SeriesBucketDay:
+		return daysBetween(from, to) + 1
+	case SeriesBucketWeek:
+		return daysBetween(from, to)/7 + 1
+	default:
+		return (to.Year()-from.Year())*12 + int(to.Month()-from.Month()) + 1
+	}
+}
+
+// daysBetween counts calendar days rather than 24 hour periods, which differ across daylight saving changes
+func daysBetween(from, to time.Time) int {
+	fromDay := time.Date(from.Year(), from.Month(), from.Day(), 0, 0, 0, 0, time.UTC)
+	toDay := time.Date(to.Year(), to.Month(), to.Day(), 0, 0, 0, 0, time.UTC)
+	return int(toDay.Sub(fromDay) / (24 * time.Hour))
+}
+
+// DefaultSpan returns how far back a series reaches when no start is given
+func (bucket SeriesBucket) DefaultSpan(to time.Time) time.Time {
+	switch bucket {
+	case SeriesBucketHour:
+		return to.Add(-24 * time.Hour)
+	case SeriesBucketWeek:
+		return to.AddDate(0, 0, -7*12)
+	case SeriesBucketMonth:
+		return to.AddDate(-1, 0, 0)
+	default:
+		return to.AddDate(0, 0, -30)
+	}
+}
+
+// Contains reports whether the time of day of t, in the location of t, falls within the window; an empty window covers
+// the whole day
+func (window DailyWindow) Contains(t time.Time) bool {
+	clock := t.Format("15:04")
+	start, end := window.Bounds()
+	switch {
+	case start == end:
+		return true
+	case start < end:
+		return clock >= start && clock < end
+	default:
+		return clock >= start || clock < end
+	}
+}
+
+// OverlapsHour reports whether any minute of the hour starting at t falls within the window
+func (window DailyWindow) OverlapsHour(t time.Time) bool {
+	start, _ := window.Bounds()
+	hourStart := SeriesBucketHour.Truncate(t)
+	return window.Contains(hourStart) || start[:2] == hourStart.Format("15")
+}
+
+// Bounds returns both ends padded to 15:04 so that they compare lexically
+func (window DailyWindow) Bounds() (string, string) {
+	return normaliseClock(window.Start), normaliseClock(window.End)
+}
+
+func normaliseClock(clock string) string {
+	parsed, err := time.Parse("15:4", clock)
+	if err != nil {
+		return "00:00"
+	}
+	return parsed.Format("15:04")
+}
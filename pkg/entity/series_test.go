@@ -0,0 +1,142 @@
+package entity
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSeriesBucketTruncateAndNext(t *testing.T) {
+	// wednesday 2022-03-16 14:35 in UTC+2
+	eet := time.FixedZone("EET", 2*60*60)
+	at := time.Date(2022, 3, 16, 14, 35, 10, 0, eet)
+	tests := []struct {
+		bucket SeriesBucket
+		start  time.Time
+		next   time.Time
+	}{
+		{bucket: SeriesBucketHour, start: time.Date(2022, 3, 16, 14, 0, 0, 0, eet), next: time.Date(2022, 3, 16, 15, 0, 0, 0, eet)},
+		{bucket: SeriesBucketDay, start: time.Date(2022, 3, 16, 0, 0, 0, 0, eet), next: time.Date(2022, 3, 17, 0, 0, 0, 0, eet)},
+		{bucket: SeriesBucketWeek, start: time.Date(2022, 3, 14, 0, 0, 0, 0, eet), next: time.Date(2022, 3, 21, 0, 0, 0, 0, eet)},
+		{bucket: SeriesBucketMonth, start: time.Date(2022, 3, 1, 0, 0, 0, 0, eet), next: time.Date(2022, 4, 1, 0, 0, 0, 0, eet)},
+	}
+	for _, test := range tests {
+		t.Run(string(test.bucket), func(t *testing.T) {
+			start := test.bucket.Truncate(at)
+			if !start.Equal(test.start) || start.Location() != eet {
+				t.Fatalf("expected the bucket to start at %s, got %s", test.start, start)
+			}
+			if next := test.bucket.Next(start); !next.Equal(test.next) {
+				t.Fatalf("expected the next bucket to start at %s, got %s", test.next, next)
+			}
+		})
+	}
+	sunday := time.Date(2022, 3, 20, 23, 0, 0, 0, time.UTC)
+	if start := SeriesBucketWeek.Truncate(sunday); !start.Equal(time.Date(2022, 3, 14, 0, 0, 0, 0, time.UTC)) {
+		t.Fatalf("expected weeks to start on monday, got %s", start)
+	}
+}
+
+func TestSeriesBucketAcrossDaylightSavingTime(t *testing.T) {
+	london, err := time.LoadLocation("Europe/London")
+	if err != nil {
+		t.Skipf("time zone database unavailable: %v", err)
+	}
+	// clocks go back from 02:00 BST to 01:00 GMT on 2022-10-30, so 01:00-02:00 happens twice
+	first := time.Date(2022, 10, 30, 0, 30, 0, 0, time.UTC).In(london)
+	second := time.Date(2022, 10, 30, 1, 30, 0, 0, time.UTC).In(london)
+	if first.Format("15:04") != second.Format("15:04") {
+		t.Fatalf("expected both times to read the same on the wall clock, got %s and %s", first, second)
+	}
+	firstHour, secondHour := SeriesBucketHour.Truncate(first), SeriesBucketHour.Truncate(second)
+	if firstHour.Equal(secondHour) || secondHour.Sub(firstHour) != time.Hour {
+		t.Fatalf("expected the repeated hour to be a bucket of its own, got %s and %s", firstHour, secondHour)
+	}
+	if next := SeriesBucketHour.Next(firstHour); !next.Equal(secondHour) {
+		t.Fatalf("expected the next hour to be the repeated one, got %s", next)
+	}
+
+	day := SeriesBucketDay.Truncate(second)
+	if next := SeriesBucketDay.Next(day); next.Sub(day) != 25*time.Hour || next.Hour() != 0 {
+		t.Fatalf("expected the day the clocks go back to last 25 hours, got %s", next)
+	}
+	from := time.Date(2022, 3, 26, 12, 0, 0, 0, london)
+	to := time.Date(2022, 3, 28, 12, 0, 0, 0, london)
+	if got := SeriesBucketDay.PointCount(from, to); got != 3 {
+		t.Fatalf("expected calendar days to be counted across the change, got %d", got)
+	}
+}
+
+func TestSeriesBucketPointCount(t *testing.T) {
+	from := time.Date(2021, 12, 31, 23, 30, 0, 0, time.UTC)
+	to := time.Date(2022, 2, 1, 0, 30, 0, 0, time.UTC)
+	tests := []struct {
+		bucket SeriesBucket
+		want   int
+	}{
+		{bucket: SeriesBucketHour, want: 31*24 + 2},
+		{bucket: SeriesBucketDay, want: 33},
+		{bucket: SeriesBucketWeek, want: 6},
+		{bucket: SeriesBucketMonth, want: 3},
+	}
+	for _, test := range tests {
+		t.Run(string(test.bucket), func(t *testing.T) {
+			if got := test.bucket.PointCount(from, to); got != test.want {
+				t.Fatalf("expected %d points, got %d", test.want, got)
+			}
+		})
+	}
+	if got := SeriesBucketDay.PointCount(to, from); got != 0 {
+		t.Fatalf("expected no points for an inverted range, got %d", got)
+	}
+}
+
+func TestOCDLogSeriesQueryValidate(t *testing.T) {
+	to := time.Date(2022, 3, 16, 0, 0, 0, 0, time.UTC)
+	valid := OCDLogSeriesQuery{Bucket: SeriesBucketDay, From: to.AddDate(0, 0, -30), To: to}
+	if err := valid.Validate(); err != nil {
+		t.Fatalf("expected a valid query: %v", err)
+	}
+	for name, query := range map[string]OCDLogSeriesQuery{
+		"unknown bucket":  {Bucket: "year", From: valid.From, To: to},
+		"inverted range":  {Bucket: SeriesBucketDay, From: to, To: valid.From},
+		"too many points": {Bucket: SeriesBucketHour, From: to.Add(-MaxSeriesPoints * time.Hour), To: to},
+	} {
+		t.Run(name, func(t *testing.T) {
+			if err := query.Validate(); err == nil {
+				t.Fatalf("expected an error")
+			}
+		})
+	}
+}
+
+func TestDailyWindow(t *testing.T) {
+	at := func(clock string) time.Time {
+		parsed, _ := time.Parse("15:04", clock)
+		return parsed
+	}
+	day := DailyWindow{Start: "7:00", End: "22:30"}
+	night := DailyWindow{Start: "22:00", End: "06:00"}
+	tests := []struct {
+		window DailyWindow
+		clock  string
+		want   bool
+	}{
+		{window: day, clock: "07:00", want: true},
+		{window: day, clock: "06:59", want: false},
+		{window: day, clock: "22:29", want: true},
+		{window: day, clock: "22:30", want: false},
+		{window: night, clock: "23:15", want: true},
+		{window: night, clock: "05:59", want: true},
+		{window: night, clock: "12:00", want: false},
+		{window: DailyWindow{}, clock: "03:00", want: true},
+	}
+	for _, test := range tests {
+		if got := test.window.Contains(at(test.clock)); got != test.want {
+			t.Errorf("expected %+v to contain %s: %t, got %t", test.window, test.clock, test.want, got)
+		}
+	}
+	partial := DailyWindow{Start: "07:30", End: "22:00"}
+	if !partial.OverlapsHour(at("07:00")) || partial.OverlapsHour(at("06:00")) || partial.OverlapsHour(at("22:00")) {
+		t.Fatalf("unexpected hour overlap for %+v", partial)
+	}
+}
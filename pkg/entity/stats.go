@@ -0,0 +1,44 @@
+package entity
+
+const (
+	MaxAnxietyLevel = 10
+)
+
+// OCDLogStats summarises the logs matching a filter
+type OCDLogStats struct {
+	Count                int                      `json:"count"`
+	AvgAnxietyLevel      float64                  `json:"avg_anxiety_level"`
+	MedianAnxietyLevel   float64                  `json:"median_anxiety_level"`
+	MaxAnxietyLevel      int                      `json:"max_anxiety_level"`
+	TotalRuminateMinutes int                      `json:"total_ruminate_minutes"`
+	AvgRuminateMinutes   float64                  `json:"avg_ruminate_minutes"`
+	AnxietyHistogram     []AnxietyHistogramBucket `json:"anxiety_histogram"`
+	AvgUrgeIntensity     *float64                 `json:"avg_urge_intensity"` // null without any urge intensity recorded
+	ResistedCount        int                      `json:"resisted_count"`
+	ResistanceRate       *float64                 `json:"resistance_rate"` // share of resisted urges; null without any outcome recorded
+	TotalResistedMinutes int                      `json:"total_resisted_minutes"`
+	Compulsions          []CompulsionStats        `json:"compulsions"` // most frequent first
+	Tags                 []TagStats               `json:"tags"`        // most used first
+}
+
+// CompulsionStats aggregates the logs of a compulsion type
+type CompulsionStats struct {
+	CompulsionType   string   `json:"compulsion_type"`
+	Count            int      `json:"count"`
+	AvgUrgeIntensity *float64 `json:"avg_urge_intensity"`
+	ResistanceRate   *float64 `json:"resistance_rate"`
+}
+
+type AnxietyHistogramBucket struct {
+	AnxietyLevel int `json:"anxiety_level"`
+	Count        int `json:"count"`
+}
+
+// NewAnxietyHistogram returns a bucket for every anxiety level, all with a count of zero
+func NewAnxietyHistogram() []AnxietyHistogramBucket {
+	histogram := make([]AnxietyHistogramBucket, MaxAnxietyLevel+1)
+	for level := range histogram {
+		histogram[level].AnxietyLevel = level
+	}
+	return histogram
+}
@@ -0,0 +1,136 @@
+package entity
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	validation "github.com/go-ozzo/ozzo-validation/v4"
+	"github.com/google/uuid"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	MaxTagNameLength = 64
+	MaxTagsPerLog    = 20
+	TagNameSeparator = ";" // joins the tags of a log in a single csv cell, so names cannot contain it
+)
+
+// Tag is a free-form label, such as a trigger, that logs of the same account can share
+type Tag struct {
+	ID        uuid.UUID  `json:"id"`
+	AccountID string     `json:"account_id"`
+	Name      *string    `json:"name,omitempty"`
+	CreatedAt *time.Time `json:"created_at,omitempty"`
+	UpdatedAt *time.Time `json:"updated_at,omitempty"`
+}
+
+type TagList struct {
+	Tags []Tag `json:"tags"`
+}
+
+// TagStats aggregates the logs carrying a tag
+type TagStats struct {
+	Tag                  string  `json:"tag"`
+	Count                int     `json:"count"`
+	AvgAnxietyLevel      float64 `json:"avg_anxiety_level"`
+	TotalRuminateMinutes int     `json:"total_ruminate_minutes"`
+}
+
+// TagNames are the names of the tags of a log; postgres returns them as a json array
+type TagNames []string
+
+func (names *TagNames) Scan(src interface{}) error {
+	switch value := src.(type) {
+	case nil:
+		*names = nil
+		return nil
+	case []byte:
+		return json.Unmarshal(value, names)
+	case string:
+		return json.Unmarshal([]byte(value), names)
+	default:
+		return fmt.Errorf("cannot scan %T into tag names", src)
+	}
+}
+
+// Value encodes the names as a json string
+func (names TagNames) Value() (driver.Value, error) {
+	if names == nil {
+		return nil, nil
+	}
+	return valueJSON([]string(names))
+}
+
+// Normalized returns the names trimmed, lower-cased, de-duplicated and sorted; nil stays nil so that updates can tell
+// "leave the tags alone" apart from "remove all tags"
+func (names TagNames) Normalized() TagNames {
+	if names == nil {
+		return nil
+	}
+	seen := make(map[string]bool, len(names))
+	normalized := make(TagNames, 0, len(names))
+	for _, name := range names {
+		name = NormalizeTagName(name)
+		if !seen[name] {
+			seen[name] = true
+			normalized = append(normalized, name)
+		}
+	}
+	sort.Strings(normalized)
+	return normalized
+}
+
+// Contains reports whether the names include name, which must be normalized
+func (names TagNames) Contains(name string) bool {
+	for _, existing := range names {
+		if existing == name {
+			return true
+		}
+	}
+	return false
+}
+
+func NormalizeTagName(name string) string {
+	return strings.ToLower(strings.TrimSpace(name))
+}
+
+func (tag Tag) Validate() error {
+	return validation.ValidateStruct(&tag,
+		validation.Field(&tag.Name, validation.Required, validation.By(validTagName)),
+	)
+}
+
+func (names TagNames) Validate() error {
+	if len(names) > MaxTagsPerLog {
+		return validation.NewError("validation_too_many_tags", fmt.Sprintf("must not contain more than %d tags", MaxTagsPerLog))
+	}
+	for _, name := range names {
+		if err := validTagName(name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func validTagName(value interface{}) error {
+	var name string
+	switch v := value.(type) {
+	case string:
+		name = v
+	case *string:
+		if v == nil {
+			return nil
+		}
+		name = *v
+	}
+	name = NormalizeTagName(name)
+	if name == "" || len(name) > MaxTagNameLength {
+		return validation.NewError("validation_tag_name", fmt.Sprintf("tag names must be between 1 and %d characters long", MaxTagNameLength))
+	}
+	if strings.Contains(name, TagNameSeparator) {
+		return validation.NewError("validation_tag_name", fmt.Sprintf("tag names must not contain %q", TagNameSeparator))
+	}
+	return nil
+}